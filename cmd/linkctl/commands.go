@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/emacampolo/link-tracker/pkg/client"
+)
+
+func create(fs *flag.FlagSet) runFunc {
+	var nl client.NewLink
+	fs.StringVar(&nl.URL, "url", "", "destination of the link (required)")
+	fs.StringVar(&nl.Password, "password", "", "password to visit the link")
+	fs.StringVar(&nl.Owner, "owner", "", "owner of the link")
+	fs.StringVar(&nl.Title, "title", "", "title of the link")
+	fs.BoolVar(&nl.Interstitial, "interstitial", false, "show the destination before every visit")
+	fs.StringVar(&nl.FallbackURL, "fallback-url", "", "where visitors go while the destination is failing")
+	fs.IntVar(&nl.MaxVisits, "max-visits", 0, "make the link secret, visitable this many times; it requires a password")
+
+	return func(ctx context.Context, e *env, args []string) error {
+		if len(args) > 0 {
+			return usagef("create: unexpected argument %q", args[0])
+		}
+
+		if nl.URL == "" {
+			return usagef("create: -url is required")
+		}
+
+		id, err := e.client.Create(ctx, nl)
+		if err != nil {
+			return err
+		}
+
+		if e.json {
+			return writeJSON(e.stdout, map[string]int{"id": id})
+		}
+
+		t := newTable(e.stdout, "ID")
+		t.row(id)
+		return t.flush()
+	}
+}
+
+func list(fs *flag.FlagSet) runFunc {
+	owner := fs.String("owner", "", "only list the links of this owner")
+
+	return func(ctx context.Context, e *env, args []string) error {
+		if len(args) > 0 {
+			return usagef("list: unexpected argument %q", args[0])
+		}
+
+		links, err := e.client.List(ctx, *owner)
+		if err != nil {
+			return err
+		}
+
+		if e.json {
+			return writeJSON(e.stdout, links)
+		}
+
+		t := newTable(e.stdout, "ID", "URL", "OWNER", "TITLE", "COUNT", "INACTIVE")
+		for _, l := range links {
+			t.row(l.ID, orDash(l.URL), orDash(l.Owner), orDash(l.Title), l.Count, l.Inactive)
+		}
+
+		return t.flush()
+	}
+}
+
+func metrics(*flag.FlagSet) runFunc {
+	return func(ctx context.Context, e *env, args []string) error {
+		id, err := linkID("metrics", args)
+		if err != nil {
+			return err
+		}
+
+		m, err := e.client.Metrics(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		if e.json {
+			return writeJSON(e.stdout, m)
+		}
+
+		t := newTable(e.stdout)
+		t.row("ID", m.ID)
+		t.row("URL", orDash(m.URL))
+		t.row("OWNER", orDash(m.Owner))
+		t.row("TITLE", orDash(m.Title))
+		t.row("COUNT", m.Count)
+		t.row("PREVIEWS", m.PreviewCount)
+		t.row("BOTS", m.BotCount)
+		t.row("UNIQUE VISITORS", m.UniqueVisitors)
+		t.row("INACTIVE", m.Inactive)
+		t.row("PUBLIC", m.Public)
+		t.row("FAILING", m.Health.Failing)
+		if m.Schedule != nil {
+			t.row("OPEN", m.Schedule.Open)
+		}
+		if m.RemainingVisits != nil {
+			t.row("REMAINING VISITS", *m.RemainingVisits)
+		}
+		for _, c := range m.Codes {
+			t.row("CODE "+c.Name, c.Count)
+		}
+
+		return t.flush()
+	}
+}
+
+func inactivate(*flag.FlagSet) runFunc {
+	return func(ctx context.Context, e *env, args []string) error {
+		id, err := linkID("inactivate", args)
+		if err != nil {
+			return err
+		}
+
+		if err := e.client.Inactivate(ctx, id); err != nil {
+			return err
+		}
+
+		if e.json {
+			return writeJSON(e.stdout, map[string]interface{}{"id": id, "inactive": true})
+		}
+
+		t := newTable(e.stdout, "ID", "INACTIVE")
+		t.row(id, true)
+		return t.flush()
+	}
+}
+
+// export writes the links as the bodies that create them, one JSON object per line, whatever the
+// output format. What the API does not reveal cannot be exported: secret links are skipped, and so are
+// the links protected by a password or access codes, unless -include-unprotected exports them without
+// their protection.
+func export(fs *flag.FlagSet) runFunc {
+	owner := fs.String("owner", "", "only export the links of this owner")
+	includeUnprotected := fs.Bool("include-unprotected", false, "export the links protected by a password or access codes without them, so they are public once imported")
+
+	return func(ctx context.Context, e *env, args []string) error {
+		if len(args) > 0 {
+			return usagef("export: unexpected argument %q", args[0])
+		}
+
+		links, err := e.client.List(ctx, *owner)
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(e.stdout)
+		for _, l := range links {
+			if l.Inactive {
+				fmt.Fprintf(e.stderr, "linkctl: skipping link %d: it is inactive\n", l.ID)
+				continue
+			}
+
+			m, err := e.client.Metrics(ctx, l.ID)
+			if err != nil {
+				return err
+			}
+
+			if m.RemainingVisits != nil {
+				fmt.Fprintf(e.stderr, "linkctl: skipping link %d: the destination of secret links is not revealed\n", l.ID)
+				continue
+			}
+
+			if !m.Public {
+				if !*includeUnprotected {
+					fmt.Fprintf(e.stderr, "linkctl: skipping link %d: its password and access codes are not revealed; -include-unprotected exports it without them\n", l.ID)
+					continue
+				}
+
+				fmt.Fprintf(e.stderr, "linkctl: link %d is exported without its password and access codes\n", l.ID)
+			}
+
+			nl := client.NewLink{
+				URL:          m.URL,
+				Owner:        m.Owner,
+				Title:        m.Title,
+				Interstitial: m.Interstitial,
+				FallbackURL:  m.FallbackURL,
+			}
+			if m.Schedule != nil {
+				schedule := m.Schedule.Schedule
+				nl.Schedule = &schedule
+			}
+
+			if err := enc.Encode(nl); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// importLinks creates the links read from a file or the standard input, written as JSON lines like
+// export does, or as a JSON array. Links that fail to be created are reported and skipped.
+func importLinks(*flag.FlagSet) runFunc {
+	return func(ctx context.Context, e *env, args []string) error {
+		if len(args) > 1 {
+			return usagef("import: unexpected argument %q", args[1])
+		}
+
+		in := e.stdin
+		if len(args) == 1 && args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+
+		links, err := readLinks(in)
+		if err != nil {
+			return fmt.Errorf("import: %w", err)
+		}
+
+		type imported struct {
+			Record int `json:"record"`
+			ID     int `json:"id"`
+		}
+
+		var created []imported
+		for i, nl := range links {
+			id, err := e.client.Create(ctx, nl)
+			if err != nil {
+				fmt.Fprintf(e.stderr, "linkctl: record %d: %v\n", i+1, err)
+				continue
+			}
+
+			created = append(created, imported{Record: i + 1, ID: id})
+		}
+
+		if e.json {
+			if created == nil {
+				created = []imported{}
+			}
+			err = writeJSON(e.stdout, created)
+		} else {
+			t := newTable(e.stdout, "RECORD", "ID")
+			for _, c := range created {
+				t.row(c.Record, c.ID)
+			}
+			err = t.flush()
+		}
+		if err != nil {
+			return err
+		}
+
+		if failed := len(links) - len(created); failed > 0 {
+			return fmt.Errorf("import: %d of %d links were not created", failed, len(links))
+		}
+
+		return nil
+	}
+}
+
+// readLinks decodes a JSON array of links, or a sequence of them.
+func readLinks(r io.Reader) ([]client.NewLink, error) {
+	br := bufio.NewReader(r)
+	var links []client.NewLink
+
+	first, err := firstByte(br)
+	if errors.Is(err, io.EOF) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(br)
+	dec.DisallowUnknownFields()
+	if first == '[' {
+		if err := dec.Decode(&links); err != nil {
+			return nil, err
+		}
+		return links, nil
+	}
+
+	for {
+		var nl client.NewLink
+		err := dec.Decode(&nl)
+		if errors.Is(err, io.EOF) {
+			return links, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %w", len(links)+1, err)
+		}
+
+		links = append(links, nl)
+	}
+}
+
+// firstByte returns the first byte of r that is not white space, without consuming it.
+func firstByte(r *bufio.Reader) (byte, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+
+		if !bytes.ContainsRune([]byte(" \t\r\n"), rune(b)) {
+			return b, r.UnreadByte()
+		}
+	}
+}
+
+func completion(*flag.FlagSet) runFunc {
+	return func(ctx context.Context, e *env, args []string) error {
+		if len(args) != 1 {
+			return usagef("completion: expected the shell, bash or zsh")
+		}
+
+		switch args[0] {
+		case "bash":
+			return writeBashCompletion(e.stdout)
+		case "zsh":
+			return writeZshCompletion(e.stdout)
+		}
+
+		return usagef("completion: unknown shell %q", args[0])
+	}
+}
+
+// linkID parses the only argument of a command, the ID of a link.
+func linkID(name string, args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, usagef("%s: expected the ID of a link", name)
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil || id <= 0 {
+		return 0, usagef("%s: invalid link ID %q", name, args[0])
+	}
+
+	return id, nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// bashCompletion completes the commands, their flags and arguments, and the values of the global flags.
+var bashCompletion = template.Must(template.New("bash").Parse(`_linkctl() {
+	local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]} cmd="" i
+	case $prev in
+	-file) COMPREPLY=($(compgen -f -- "$cur")); return ;;
+	-o) COMPREPLY=($(compgen -W "table json" -- "$cur")); return ;;
+	-server) return ;;
+	esac
+	for ((i = 1; i < COMP_CWORD; i++)); do
+		case ${COMP_WORDS[i]} in
+		{{.GlobalFlags}}) ((i++)) ;;
+		-*) ;;
+		*) cmd=${COMP_WORDS[i]}; break ;;
+		esac
+	done
+	case $cmd in
+	"") COMPREPLY=($(compgen -W "{{.Commands}} {{.GlobalFlagList}}" -- "$cur")) ;;
+{{- range .Cmds}}
+	{{.Name}}) {{if .Files}}COMPREPLY=($(compgen -f -W "{{.Words}}" -- "$cur")){{else}}COMPREPLY=($(compgen -W "{{.Words}}" -- "$cur")){{end}} ;;
+{{- end}}
+	esac
+}
+complete -F _linkctl linkctl
+`))
+
+type completionCmd struct {
+	Name  string
+	Words string
+	Files bool
+}
+
+func writeBashCompletion(w io.Writer) error {
+	var globals []string
+	fs := flag.NewFlagSet("linkctl", flag.ContinueOnError)
+	defineGlobalFlags(fs)
+	fs.VisitAll(func(f *flag.Flag) {
+		globals = append(globals, "-"+f.Name)
+	})
+
+	var cmds []completionCmd
+	for _, name := range commandNames() {
+		c := completionCmd{Name: name}
+		var words []string
+		fs := flag.NewFlagSet(name, flag.ContinueOnError)
+		commands[name].define(fs)
+		fs.VisitAll(func(f *flag.Flag) {
+			words = append(words, "-"+f.Name)
+		})
+
+		for _, word := range commands[name].complete {
+			if word == "files" {
+				c.Files = true
+				continue
+			}
+			words = append(words, word)
+		}
+
+		c.Words = strings.Join(words, " ")
+		cmds = append(cmds, c)
+	}
+
+	return bashCompletion.Execute(w, map[string]interface{}{
+		"GlobalFlags":    strings.Join(globals, "|"),
+		"GlobalFlagList": strings.Join(globals, " "),
+		"Commands":       strings.Join(commandNames(), " "),
+		"Cmds":           cmds,
+	})
+}
+
+// writeZshCompletion writes the bash completion, loaded through the compatibility layer of zsh.
+func writeZshCompletion(w io.Writer) error {
+	if _, err := io.WriteString(w, "#compdef linkctl\nautoload -U +X bashcompinit && bashcompinit\n"); err != nil {
+		return err
+	}
+
+	return writeBashCompletion(w)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/emacampolo/link-tracker/internal/webhook"
+	"github.com/emacampolo/link-tracker/pkg/client"
+)
+
+// newLocalClient returns a client whose requests are served in process by the handlers of the server,
+// on the links stored in path. Offline mode behaves exactly like the server this way. The file is locked
+// until the returned io.Closer is closed, so it fails while a server uses it.
+func newLocalClient(path string) (*client.Client, io.Closer, error) {
+	repository, err := link.OpenFileRepository(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The same policy the server enforces by default.
+	linkService := link.NewService(repository, link.WithPasswordPolicy(link.PasswordPolicy{
+		MinLength:      8,
+		MinCharClasses: 2,
+		RejectCommon:   true,
+	}))
+	webhookService := webhook.NewService(webhook.NewInMemoryRepository(), webhook.NewDeliveryLog(1), webhook.NewInMemoryDeadLetterStore())
+
+	app := web.New(web.WithoutRequestLog())
+	handler.API{
+		Link:    handler.NewLink(linkService),
+		QR:      handler.NewQR(linkService, ""),
+		Click:   handler.NewClick(linkService, link.NewClickStream(1), time.Minute),
+		Webhook: handler.NewWebhook(webhookService),
+	}.Register(app)
+
+	c, err := client.New("http://linkctl.local",
+		client.WithHTTPClient(&http.Client{Transport: inProcess{app}}),
+		client.WithRetries(0, 0))
+	if err != nil {
+		repository.Close()
+		return nil, nil, err
+	}
+
+	return c, repository, nil
+}
+
+// inProcess is an http.RoundTripper that serves the requests with a handler.
+type inProcess struct {
+	h http.Handler
+}
+
+func (t inProcess) RoundTrip(r *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	t.h.ServeHTTP(rec, r)
+	return rec.Result(), nil
+}
@@ -0,0 +1,189 @@
+// Command linkctl manages the links of a link tracker from the command line. It calls the API of a
+// running server, or, in offline mode, opens the file where a stopped server stores its links.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/emacampolo/link-tracker/pkg/client"
+)
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "linkctl:", err)
+		var usage usageError
+		if errors.As(err, &usage) {
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+// usageError is returned when the command line is not valid.
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// env is what the commands need to run.
+type env struct {
+	client *client.Client
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+	// json prints JSON instead of tables.
+	json bool
+}
+
+// runFunc runs a command with the arguments left after its flags.
+type runFunc func(ctx context.Context, e *env, args []string) error
+
+// command is a subcommand of linkctl.
+type command struct {
+	// args describes the arguments of the command after its flags.
+	args    string
+	summary string
+	// define defines the flags of the command in fs and returns the function that runs it.
+	define func(fs *flag.FlagSet) runFunc
+	// complete are the words completed as arguments of the command; "files" completes file names.
+	complete []string
+}
+
+// commands is set in init, because the completion command refers to it.
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"create":     {summary: "create a link", define: create},
+		"list":       {summary: "list the links", define: list},
+		"metrics":    {args: "ID", summary: "show the metrics of a link", define: metrics},
+		"inactivate": {args: "ID", summary: "inactivate a link", define: inactivate},
+		"export":     {summary: "write the links as JSON lines that import reads", define: export},
+		"import":     {args: "[FILE]", summary: "create the links read from FILE or the standard input", define: importLinks, complete: []string{"files"}},
+		"completion": {args: "bash|zsh", summary: "print the shell completion script", define: completion, complete: []string{"bash", "zsh"}},
+	}
+}
+
+// commandNames returns the names of the commands, sorted.
+func commandNames() []string {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// globalFlags are the flags given before the command.
+type globalFlags struct {
+	server string
+	file   string
+	output string
+}
+
+func defineGlobalFlags(fs *flag.FlagSet) *globalFlags {
+	defaultServer := os.Getenv("LINKCTL_SERVER")
+	if defaultServer == "" {
+		defaultServer = "http://localhost:8080"
+	}
+
+	var g globalFlags
+	fs.StringVar(&g.server, "server", defaultServer, "address of the server; $LINKCTL_SERVER if set")
+	fs.StringVar(&g.file, "file", "", "work offline on the links stored in this file by the -links-file flag of a stopped server")
+	fs.StringVar(&g.output, "o", "table", "output format: table or json")
+	return &g
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	fs := flag.NewFlagSet("linkctl", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	g := defineGlobalFlags(fs)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: linkctl [flags] COMMAND [ARGS]\n\nCommands:\n")
+		w := tabwriter.NewWriter(stderr, 0, 0, 2, ' ', 0)
+		for _, name := range commandNames() {
+			fmt.Fprintf(w, "  %s\t%s\n", name, commands[name].summary)
+		}
+		w.Flush()
+		fmt.Fprintf(stderr, "\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return flagError(err)
+	}
+
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return usagef("missing command")
+	}
+
+	name := fs.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		return usagef("unknown command %q", name)
+	}
+
+	if g.output != "table" && g.output != "json" {
+		return usagef("unknown output format %q", g.output)
+	}
+
+	cmdFlags := flag.NewFlagSet(name, flag.ContinueOnError)
+	cmdFlags.SetOutput(stderr)
+	runCmd := cmd.define(cmdFlags)
+	cmdFlags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: linkctl %s [flags] %s\n", name, cmd.args)
+		cmdFlags.PrintDefaults()
+	}
+
+	if err := cmdFlags.Parse(fs.Args()[1:]); err != nil {
+		return flagError(err)
+	}
+
+	e := &env{stdin: stdin, stdout: stdout, stderr: stderr, json: g.output == "json"}
+
+	var err error
+	if g.file != "" {
+		var repository io.Closer
+		if e.client, repository, err = newLocalClient(g.file); err != nil {
+			return err
+		}
+		defer repository.Close()
+	} else {
+		e.client, err = client.New(g.server)
+	}
+	if err != nil {
+		return err
+	}
+
+	return runCmd(ctx, e, cmdFlags.Args())
+}
+
+// flagError makes the errors of parsing the flags, which are printed with the usage, usage errors.
+func flagError(err error) error {
+	if errors.Is(err, flag.ErrHelp) {
+		return err
+	}
+
+	return usageError{msg: err.Error()}
+}
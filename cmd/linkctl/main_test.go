@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/emacampolo/link-tracker/internal/webhook"
+	"github.com/emacampolo/link-tracker/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+// linkctl runs the command line with stdin and returns what it printed.
+func linkctl(t *testing.T, stdin string, args ...string) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+func newServer(t *testing.T) *httptest.Server {
+	linkService := link.NewService(link.NewInMemoryRepository())
+	webhookService := webhook.NewService(webhook.NewInMemoryRepository(), webhook.NewDeliveryLog(10), webhook.NewInMemoryDeadLetterStore())
+
+	app := web.New(web.WithoutRequestLog())
+	handler.API{
+		Link:    handler.NewLink(linkService),
+		QR:      handler.NewQR(linkService, "http://localhost:8080"),
+		Click:   handler.NewClick(linkService, link.NewClickStream(10), time.Second),
+		Webhook: handler.NewWebhook(webhookService),
+	}.Register(app)
+
+	srv := httptest.NewServer(app)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRun_Offline(t *testing.T) {
+	// Given
+	file := filepath.Join(t.TempDir(), "links.json")
+
+	// When
+	created, _, err := linkctl(t, "", "-file", file, "create", "-url", "https://www.google.com", "-owner", "alice", "-title", "Google")
+	require.NoError(t, err)
+
+	_, _, err = linkctl(t, "", "-file", file, "inactivate", "1")
+	require.NoError(t, err)
+
+	listed, _, err := linkctl(t, "", "-file", file, "list")
+	require.NoError(t, err)
+
+	metrics, _, err := linkctl(t, "", "-file", file, "-o", "json", "metrics", "1")
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, "ID \n1  \n", created)
+	require.Equal(t, ""+
+		"ID URL                    OWNER TITLE  COUNT INACTIVE \n"+
+		"1  https://www.google.com alice Google 0     true     \n", listed)
+
+	var m client.Metrics
+	require.NoError(t, json.Unmarshal([]byte(metrics), &m))
+	require.Equal(t, 1, m.ID)
+	require.Equal(t, "alice", m.Owner)
+	require.True(t, m.Inactive)
+}
+
+func TestRun_Offline_InUse(t *testing.T) {
+	// Given
+	file := filepath.Join(t.TempDir(), "links.json")
+	repository, err := link.OpenFileRepository(file)
+	require.NoError(t, err)
+	defer repository.Close()
+
+	// When
+	_, _, err = linkctl(t, "", "-file", file, "list")
+
+	// Then
+	require.ErrorIs(t, err, link.ErrFileInUse)
+}
+
+func TestRun_ExportImport(t *testing.T) {
+	// Given
+	srv := newServer(t)
+	file := filepath.Join(t.TempDir(), "links.json")
+	_, _, err := linkctl(t, "", "-server", srv.URL, "create", "-url", "https://www.google.com", "-owner", "alice", "-interstitial")
+	require.NoError(t, err)
+	_, _, err = linkctl(t, "", "-server", srv.URL, "create", "-url", "https://www.bing.com", "-password", "s3cret-pass", "-max-visits", "1")
+	require.NoError(t, err)
+
+	// When
+	exported, warnings, err := linkctl(t, "", "-server", srv.URL, "export")
+	require.NoError(t, err)
+
+	imported, _, err := linkctl(t, exported, "-file", file, "-o", "json", "import")
+	require.NoError(t, err)
+
+	listed, _, err := linkctl(t, "", "-file", file, "-o", "json", "list")
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, `{"link":"https://www.google.com","owner":"alice","interstitial":true}`+"\n", exported)
+	require.Contains(t, warnings, "skipping link 2")
+	require.JSONEq(t, `[{"record": 1, "id": 1}]`, imported)
+
+	var links []client.Link
+	require.NoError(t, json.Unmarshal([]byte(listed), &links))
+	require.Equal(t, []client.Link{{ID: 1, URL: "https://www.google.com", Owner: "alice"}}, links)
+}
+
+func TestRun_Export_Protected(t *testing.T) {
+	// Given
+	srv := newServer(t)
+	_, _, err := linkctl(t, "", "-server", srv.URL, "create", "-url", "https://www.google.com")
+	require.NoError(t, err)
+	_, _, err = linkctl(t, "", "-server", srv.URL, "create", "-url", "https://www.bing.com", "-password", "s3cret-pass")
+	require.NoError(t, err)
+
+	// When
+	exported, warnings, err := linkctl(t, "", "-server", srv.URL, "export")
+	require.NoError(t, err)
+
+	unprotected, _, err := linkctl(t, "", "-server", srv.URL, "export", "-include-unprotected")
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, `{"link":"https://www.google.com"}`+"\n", exported)
+	require.Contains(t, warnings, "skipping link 2")
+	require.Equal(t, `{"link":"https://www.google.com"}`+"\n"+`{"link":"https://www.bing.com"}`+"\n", unprotected)
+}
+
+func TestRun_Import_Failures(t *testing.T) {
+	// Given
+	srv := newServer(t)
+	in := `[{"link": "https://www.google.com"}, {"link": "google"}]`
+
+	// When
+	imported, warnings, err := linkctl(t, in, "-server", srv.URL, "import", "-")
+
+	// Then
+	require.EqualError(t, err, "import: 1 of 2 links were not created")
+	require.Equal(t, "RECORD ID \n1      1  \n", imported)
+	require.Contains(t, warnings, "record 2: invalid_request")
+}
+
+func TestRun_Errors(t *testing.T) {
+	srv := newServer(t)
+
+	tt := []struct {
+		name      string
+		args      []string
+		wantUsage bool
+		wantErr   error
+	}{
+		{name: "missing command", args: []string{}, wantUsage: true},
+		{name: "unknown command", args: []string{"delete"}, wantUsage: true},
+		{name: "unknown output", args: []string{"-o", "yaml", "list"}, wantUsage: true},
+		{name: "unknown flag", args: []string{"list", "-all"}, wantUsage: true},
+		{name: "missing url", args: []string{"create"}, wantUsage: true},
+		{name: "invalid id", args: []string{"metrics", "one"}, wantUsage: true},
+		{name: "help", args: []string{"list", "-h"}, wantErr: flag.ErrHelp},
+		{name: "not found", args: []string{"metrics", "42"}, wantErr: client.ErrNotFound},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// When
+			_, _, err := linkctl(t, "", append([]string{"-server", srv.URL}, tc.args...)...)
+
+			// Then
+			var usage usageError
+			require.Equal(t, tc.wantUsage, errors.As(err, &usage), err)
+			if tc.wantErr != nil {
+				require.True(t, errors.Is(err, tc.wantErr), err)
+			}
+		})
+	}
+}
+
+func TestRun_Completion(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh"} {
+		t.Run(shell, func(t *testing.T) {
+			// When
+			script, _, err := linkctl(t, "", "completion", shell)
+
+			// Then
+			require.NoError(t, err)
+			require.Contains(t, script, "complete -F _linkctl linkctl")
+			require.Contains(t, script, `"completion create export import inactivate list metrics -file -o -server"`)
+			require.Contains(t, script, `create) COMPREPLY=($(compgen -W "-fallback-url -interstitial -max-visits -owner -password -title -url" -- "$cur"))`)
+		})
+	}
+}
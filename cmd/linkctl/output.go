@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// table writes rows as columns aligned like the routes printed by the server.
+type table struct {
+	w tabwriter.Writer
+}
+
+func newTable(out io.Writer, header ...string) *table {
+	t := &table{}
+	t.w.Init(out, 0, 0, 1, ' ', tabwriter.TabIndent)
+	if len(header) > 0 {
+		t.row(toInterfaces(header)...)
+	}
+
+	return t
+}
+
+func (t *table) row(cells ...interface{}) {
+	var b strings.Builder
+	for _, c := range cells {
+		fmt.Fprintf(&b, "%v\t", c)
+	}
+
+	fmt.Fprintln(&t.w, b.String())
+}
+
+func (t *table) flush() error {
+	return t.w.Flush()
+}
+
+func toInterfaces(s []string) []interface{} {
+	cells := make([]interface{}, len(s))
+	for i, v := range s {
+		cells[i] = v
+	}
+
+	return cells
+}
+
+// writeJSON writes v as indented JSON.
+func writeJSON(out io.Writer, v interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// orDash returns s, or "-" if it is empty, so that the columns of a table stay aligned.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
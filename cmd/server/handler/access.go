@@ -0,0 +1,102 @@
+package handler
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+)
+
+type grantAccessRequest struct {
+	Password string `json:"password"`
+	// TTL is how long the access is valid, in the format of time.ParseDuration, like "1h30m".
+	TTL     string `json:"ttl" validate:"required"`
+	IPRange string `json:"ip_range"`
+}
+
+type accessResponse struct {
+	URL       string    `json:"url"`
+	KeyID     string    `json:"key_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// GrantAccess mints a signed URL to visit a link without its password until it expires.
+func (lnk *Link) GrantAccess() web.Handler {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		id, err := extractID(req)
+		if err != nil {
+			return web.NewError(http.StatusBadRequest, err.Error())
+		}
+
+		var r grantAccessRequest
+		if err := web.Decode(req, &r); err != nil {
+			return err
+		}
+
+		ttl, err := time.ParseDuration(r.TTL)
+		if err != nil {
+			return web.NewErrorf(http.StatusBadRequest, "invalid ttl %q", r.TTL)
+		}
+
+		a, err := lnk.linkService.GrantAccess(req.Context(), id, link.NewAccess{
+			Password: r.Password,
+			Expires:  time.Now().Add(ttl),
+			IPRange:  r.IPRange,
+		})
+		if err != nil {
+			return serviceError(err)
+		}
+
+		resp := accessResponse{
+			URL:       fmt.Sprintf("%s/link/%d?%s", lnk.publicURL, id, accessQuery(a).Encode()),
+			KeyID:     a.KeyID,
+			ExpiresAt: a.Expires.UTC(),
+		}
+
+		return web.Respond(req.Context(), w, resp, http.StatusCreated)
+	}
+}
+
+// accessQuery encodes a as the query parameters of a signed URL.
+func accessQuery(a link.Access) url.Values {
+	query := url.Values{}
+	query.Set("exp", strconv.FormatInt(a.Expires.Unix(), 10))
+	query.Set("kid", a.KeyID)
+	if a.IPRange != "" {
+		query.Set("ip", a.IPRange)
+	}
+	query.Set("sig", base64.RawURLEncoding.EncodeToString(a.Signature))
+	return query
+}
+
+// parseAccess decodes the Access to the link identified by id from the query parameters of a signed URL.
+// It returns nil if the URL is not signed.
+func parseAccess(id int, query url.Values) (*link.Access, error) {
+	if query.Get("sig") == "" {
+		return nil, nil
+	}
+
+	exp, err := strconv.ParseInt(query.Get("exp"), 10, 64)
+	if err != nil {
+		return nil, errors.New("invalid exp")
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(query.Get("sig"))
+	if err != nil {
+		return nil, errors.New("invalid sig")
+	}
+
+	return &link.Access{
+		LinkID:    id,
+		KeyID:     query.Get("kid"),
+		Expires:   time.Unix(exp, 0),
+		IPRange:   query.Get("ip"),
+		Signature: sig,
+	}, nil
+}
@@ -0,0 +1,81 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLink_GrantAccess(t *testing.T) {
+	// Given
+	expires := time.Date(2021, 6, 1, 11, 0, 0, 0, time.UTC)
+	access := link.Access{LinkID: 1, KeyID: "2021-06", Expires: expires, IPRange: "10.0.0.0/8", Signature: []byte{0xfe, 0xed}}
+
+	svcMock := &linkServiceMock{}
+	svcMock.On("GrantAccess", mock.Anything, 1, mock.MatchedBy(func(na link.NewAccess) bool {
+		return na.Password == "1234" && na.IPRange == "10.0.0.0/8" && time.Until(na.Expires) > 59*time.Minute
+	})).Return(access, nil)
+	svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1}, nil)
+	svcMock.On("Redirect", mock.Anything, 1, mock.MatchedBy(func(v link.Visit) bool {
+		return v.Password == "" && v.Access != nil && v.Access.KeyID == access.KeyID &&
+			v.Access.Expires.Equal(expires) && v.Access.IPRange == access.IPRange &&
+			bytes.Equal(v.Access.Signature, access.Signature)
+	})).Return(link.Link{ID: 1, URL: "https://www.google.com"}, nil)
+
+	app := web.New()
+	linkHandler := handler.NewLink(svcMock, handler.WithPublicURL("https://lt.example.com/"))
+	app.Method(http.MethodPost, "/link/{id}/access", linkHandler.GrantAccess())
+	app.Method(http.MethodGet, "/link/{id}", linkHandler.Redirect())
+
+	req := httptest.NewRequest(http.MethodPost, "/link/1/access", bytes.NewReader([]byte(`{"password":"1234","ttl":"1h","ip_range":"10.0.0.0/8"}`)))
+	rr := httptest.NewRecorder()
+
+	// When
+	app.ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+
+	var resp struct {
+		URL string `json:"url"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	u, err := url.Parse(resp.URL)
+	require.NoError(t, err)
+	require.Equal(t, "lt.example.com", u.Host)
+
+	// When
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+
+	// Then
+	require.Equal(t, http.StatusMovedPermanently, rr.Code)
+	require.Equal(t, "https://www.google.com", rr.Header().Get("Location"))
+}
+
+func TestLink_Redirect_InvalidAccess(t *testing.T) {
+	// Given
+	req := httptest.NewRequest(http.MethodGet, "/link/1?exp=soon&kid=a&sig=AAAA", nil)
+	rr := httptest.NewRecorder()
+
+	app := web.New()
+	app.Method(http.MethodGet, "/link/{id}", handler.NewLink(&linkServiceMock{}).Redirect())
+
+	// When
+	app.ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusBadRequest, rr.Code)
+	require.JSONEq(t, `{"code":"bad_request","message":"invalid exp"}`, rr.Body.String())
+}
@@ -0,0 +1,488 @@
+package handler
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/qr"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//go:embed admin/*.html
+var adminFiles embed.FS
+
+var adminTemplates = template.Must(template.ParseFS(adminFiles, "admin/*.html"))
+
+const (
+	// adminCSP only allows the pages to use their own inline styles and to send their forms to the server.
+	adminCSP = "default-src 'none'; style-src 'unsafe-inline'; img-src 'self'; form-action 'self'; frame-ancestors 'none'; base-uri 'none'"
+	// maxAdminForm is the maximum size of the body of a form of the admin pages.
+	maxAdminForm = 64 << 10
+	// sparklineDays is the number of days of unique visitors drawn in the sparkline of a link.
+	sparklineDays = 14
+)
+
+// dummyHash is compared with the passwords of unknown owners, so that they take as long to reject as
+// the wrong passwords of known owners.
+var dummyHash = []byte("$2a$10$udX0j9NPijiSYU1SdaOt3OcTv6GOkDdykvw21E5ZtPV.TU701zlf2")
+
+// Admin serves the pages under /admin where the owners log in to manage their links from the browser.
+// Owners only see and change their own links.
+type Admin struct {
+	linkService link.Service
+	owners      map[string][]byte
+	sessions    *web.Sessions
+	publicURL   string
+}
+
+// NewAdmin creates the handler of the admin pages. The owners that can log in are the keys of owners,
+// whose values are the bcrypt hashes of their passwords. The short URLs of the links are built from
+// publicURL.
+func NewAdmin(l link.Service, owners map[string][]byte, sessions *web.Sessions, publicURL string) *Admin {
+	return &Admin{
+		linkService: l,
+		owners:      owners,
+		sessions:    sessions,
+		publicURL:   strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+// ReadOwners reads the owners that can log in to the admin pages from lines "owner:hash", where hash is
+// the bcrypt hash of the password, such as the lines written by htpasswd -B. Empty lines and lines
+// starting with # are ignored.
+func ReadOwners(r io.Reader) (map[string][]byte, error) {
+	owners := make(map[string][]byte)
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		i := strings.IndexByte(line, ':')
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected owner:hash", n)
+		}
+
+		hash := []byte(line[i+1:])
+		if _, err := bcrypt.Cost(hash); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+
+		owners[line[:i]] = hash
+	}
+
+	return owners, s.Err()
+}
+
+// Register adds the admin pages to app. They are not part of the API, so they are not documented.
+func (a *Admin) Register(app *web.Application) {
+	app.Method("GET", "/admin", http.RedirectHandler("/admin/links", http.StatusSeeOther))
+	app.Method("GET", "/admin/login", a.page(a.loginPage, true))
+	app.Method("POST", "/admin/login", a.page(a.login, true))
+	app.Method("POST", "/admin/logout", a.page(a.logout, true))
+	app.Method("GET", "/admin/links", a.page(a.links, false))
+	app.Method("POST", "/admin/links", a.page(a.create, false))
+	app.Method("POST", "/admin/links/{id}/inactivate", a.page(a.setInactive(true), false))
+	app.Method("POST", "/admin/links/{id}/reactivate", a.page(a.setInactive(false), false))
+	app.Method("GET", "/admin/links/{id}/qr", a.page(a.qr, false))
+}
+
+// adminHandler handles a request to the admin pages in the session of the browser.
+type adminHandler func(w http.ResponseWriter, r *http.Request, sess web.Session) error
+
+// adminPage is what the templates of the admin pages render.
+type adminPage struct {
+	Title string
+	Owner string
+	CSRF  string
+	Error string
+	// Fields are the messages of the fields of a form that are not valid, by name.
+	Fields  map[string]string
+	Form    adminForm
+	Links   []adminLink
+	Created *adminLink
+}
+
+// adminForm holds the values sent in a form, to show them again when it is not valid.
+type adminForm struct {
+	Owner        string `json:"owner"`
+	URL          string `json:"url" validate:"required,url"`
+	Title        string `json:"title" validate:"max=200"`
+	Interstitial bool   `json:"interstitial"`
+	MaxVisits    string `json:"max_visits"`
+}
+
+// adminLink is a link as listed in the admin pages.
+type adminLink struct {
+	ID       int
+	ShortURL string
+	// URL is empty for secret links, whose destination is not shown.
+	URL            string
+	Title          string
+	Public         bool
+	Count          int
+	UniqueVisitors uint64
+	Inactive       bool
+	// Consumed is true for the secret links that were visited as many times as they could.
+	Consumed  bool
+	Sparkline sparkline
+}
+
+// sparkline is a line chart of the unique visitors of a link per day, drawn as an SVG polyline.
+type sparkline struct {
+	Days   int
+	Points string
+	Max    uint64
+	Total  uint64
+}
+
+// page wraps h to run in the session of the browser. Unless public, the page requires an owner to be
+// logged in. The forms sent with POST must carry the CSRF token of the session.
+func (a *Admin) page(h adminHandler, public bool) web.Handler {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		w.Header().Set("Content-Security-Policy", adminCSP)
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Referrer-Policy", "same-origin")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+
+		sess, err := a.sessions.Get(w, r)
+		if err != nil {
+			return err
+		}
+
+		if r.Method == http.MethodPost {
+			r.Body = http.MaxBytesReader(w, r.Body, maxAdminForm)
+			if !a.sessions.VerifyCSRF(r, sess) {
+				return a.renderError(w, sess, web.NewError(http.StatusForbidden, "The form expired. Go back, reload the page and send it again."))
+			}
+		}
+
+		if !public && sess.User == "" {
+			http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+			return nil
+		}
+
+		if err := h(w, r, sess); err != nil {
+			return a.renderError(w, sess, err)
+		}
+
+		return nil
+	}
+}
+
+func (a *Admin) loginPage(w http.ResponseWriter, r *http.Request, sess web.Session) error {
+	if sess.User != "" {
+		http.Redirect(w, r, "/admin/links", http.StatusSeeOther)
+		return nil
+	}
+
+	return a.render(w, http.StatusOK, "login.html", a.newPage("Log in", sess))
+}
+
+func (a *Admin) login(w http.ResponseWriter, r *http.Request, sess web.Session) error {
+	owner := r.PostFormValue("owner")
+	if !a.authenticate(owner, r.PostFormValue("password")) {
+		page := a.newPage("Log in", sess)
+		page.Error = "The owner or the password is not valid."
+		page.Form.Owner = owner
+		return a.render(w, http.StatusUnauthorized, "login.html", page)
+	}
+
+	if _, err := a.sessions.Start(w, owner); err != nil {
+		return err
+	}
+
+	http.Redirect(w, r, "/admin/links", http.StatusSeeOther)
+	return nil
+}
+
+// authenticate reports whether password is the password of owner.
+func (a *Admin) authenticate(owner, password string) bool {
+	hash, ok := a.owners[owner]
+	if !ok {
+		hash = dummyHash
+	}
+
+	return bcrypt.CompareHashAndPassword(hash, []byte(password)) == nil && ok
+}
+
+func (a *Admin) logout(w http.ResponseWriter, r *http.Request, _ web.Session) error {
+	a.sessions.End(w)
+	http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+	return nil
+}
+
+func (a *Admin) links(w http.ResponseWriter, r *http.Request, sess web.Session) error {
+	page := a.newPage("Links", sess)
+	if err := a.listLinks(r.Context(), &page); err != nil {
+		return err
+	}
+
+	if created, err := strconv.Atoi(r.URL.Query().Get("created")); err == nil {
+		for i := range page.Links {
+			if page.Links[i].ID == created {
+				page.Created = &page.Links[i]
+			}
+		}
+	}
+
+	return a.render(w, http.StatusOK, "links.html", page)
+}
+
+func (a *Admin) create(w http.ResponseWriter, r *http.Request, sess web.Session) error {
+	form := adminForm{
+		URL:          strings.TrimSpace(r.PostFormValue("url")),
+		Title:        strings.TrimSpace(r.PostFormValue("title")),
+		Interstitial: r.PostFormValue("interstitial") != "",
+		MaxVisits:    strings.TrimSpace(r.PostFormValue("max_visits")),
+	}
+
+	page := a.newPage("Links", sess)
+	page.Form = form
+
+	nl := link.NewLink{
+		URL:          form.URL,
+		Password:     r.PostFormValue("password"),
+		Owner:        sess.User,
+		Title:        form.Title,
+		Interstitial: form.Interstitial,
+	}
+
+	err := web.Validate(form)
+	if err == nil && form.MaxVisits != "" {
+		if nl.MaxVisits, err = strconv.Atoi(form.MaxVisits); err != nil || nl.MaxVisits < 0 {
+			err = web.NewFieldError(web.FieldError{Field: "max_visits", Code: "min", Message: "max_visits must be a number of visits"})
+		}
+	}
+
+	var l link.Link
+	if err == nil {
+		l, err = a.linkService.Create(r.Context(), nl)
+	}
+
+	if err != nil {
+		status, ok := formError(err, &page)
+		if !ok {
+			return err
+		}
+
+		if err := a.listLinks(r.Context(), &page); err != nil {
+			return err
+		}
+
+		return a.render(w, status, "links.html", page)
+	}
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/links?created=%d", l.ID), http.StatusSeeOther)
+	return nil
+}
+
+// formError shows in page why a form was rejected, and returns the status of the response. It returns
+// false if err is not an error of the form.
+func formError(err error, page *adminPage) (int, bool) {
+	var webErr *web.Error
+	if !errors.As(serviceError(err), &webErr) || webErr.Status >= http.StatusInternalServerError {
+		return 0, false
+	}
+
+	page.Error = webErr.Message
+	if len(webErr.Fields) > 0 {
+		page.Error = "The link was not created."
+		page.Fields = make(map[string]string, len(webErr.Fields))
+		for _, f := range webErr.Fields {
+			page.Fields[f.Field] = f.Message
+		}
+	}
+
+	return webErr.Status, true
+}
+
+// setInactive returns the handler that inactivates the link, or reactivates it.
+func (a *Admin) setInactive(inactive bool) adminHandler {
+	return func(w http.ResponseWriter, r *http.Request, sess web.Session) error {
+		l, err := a.ownedLink(r, sess)
+		if err != nil {
+			return err
+		}
+
+		if inactive {
+			err = a.linkService.Inactivate(r.Context(), l.ID)
+		} else {
+			err = a.linkService.Reactivate(r.Context(), l.ID)
+		}
+		if err != nil {
+			return serviceError(err)
+		}
+
+		http.Redirect(w, r, "/admin/links", http.StatusSeeOther)
+		return nil
+	}
+}
+
+// qr downloads the QR code of the short URL of the link, as a PNG or, with format=svg, as an SVG image.
+func (a *Admin) qr(w http.ResponseWriter, r *http.Request, sess web.Session) error {
+	l, err := a.ownedLink(r, sess)
+	if err != nil {
+		return err
+	}
+
+	opts := qr.Options{Size: 4 * defaultQRSize, Level: qr.LevelM, Margin: defaultQRMargin}
+	content := a.shortURL(l.ID)
+
+	var img []byte
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "png":
+		format = "png"
+		img, err = qr.PNG(content, opts)
+		w.Header().Set("Content-Type", "image/png")
+	case "svg":
+		img, err = qr.SVG(content, opts)
+		w.Header().Set("Content-Type", "image/svg+xml")
+	default:
+		return web.NewErrorf(http.StatusBadRequest, "The format %q is not supported.", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="link-%d.%s"`, l.ID, format))
+	w.Header().Set("Content-Length", strconv.Itoa(len(img)))
+	_, err = w.Write(img)
+	return err
+}
+
+// ownedLink returns the link identified in the request, which must belong to the owner of the session.
+// The links of other owners are not found, so that their IDs are not revealed.
+func (a *Admin) ownedLink(r *http.Request, sess web.Session) (link.Link, error) {
+	id, err := extractID(r)
+	if err != nil {
+		return link.Link{}, err
+	}
+
+	l, err := a.linkService.FindByID(r.Context(), id)
+	if err == nil && l.Owner != sess.User {
+		err = link.ErrNotFound
+	}
+	if err != nil {
+		return link.Link{}, serviceError(err)
+	}
+
+	return l, nil
+}
+
+// listLinks adds the links of the owner of the page to it.
+func (a *Admin) listLinks(ctx context.Context, page *adminPage) error {
+	links, err := a.linkService.List(ctx, page.Owner)
+	if err != nil {
+		return err
+	}
+
+	today := time.Now().UTC()
+	page.Links = make([]adminLink, 0, len(links))
+	for _, l := range links {
+		al := adminLink{
+			ID:             l.ID,
+			ShortURL:       a.shortURL(l.ID),
+			URL:            l.URL,
+			Title:          l.Title,
+			Public:         l.Public(),
+			Count:          l.Count,
+			UniqueVisitors: l.UniqueVisitors(),
+			Inactive:       l.Inactive,
+			Consumed:       l.Secret() && l.RemainingVisits() == 0,
+			Sparkline:      newSparkline(l.DailyUniqueVisitors(), today),
+		}
+		if l.Secret() {
+			al.URL = ""
+		}
+
+		page.Links = append(page.Links, al)
+	}
+
+	return nil
+}
+
+func (a *Admin) shortURL(id int) string {
+	return fmt.Sprintf("%s/link/%d", a.publicURL, id)
+}
+
+// Size of the sparklines, in the units of their viewBox.
+const (
+	sparklineWidth  = 140
+	sparklineHeight = 24
+)
+
+// newSparkline draws the unique visitors of the last sparklineDays days until today.
+func newSparkline(daily map[string]uint64, today time.Time) sparkline {
+	values := make([]uint64, sparklineDays)
+	s := sparkline{Days: sparklineDays}
+	for i := range values {
+		day := today.AddDate(0, 0, i-sparklineDays+1).Format("2006-01-02")
+		values[i] = daily[day]
+		s.Total += values[i]
+		if values[i] > s.Max {
+			s.Max = values[i]
+		}
+	}
+
+	points := make([]string, len(values))
+	for i, v := range values {
+		x := float64(i) * sparklineWidth / (sparklineDays - 1)
+		// The line is kept one unit away from the edges, so that its stroke is not cut.
+		y := float64(sparklineHeight - 1)
+		if s.Max > 0 {
+			y -= float64(v) * (sparklineHeight - 2) / float64(s.Max)
+		}
+		points[i] = strconv.FormatFloat(x, 'f', 1, 64) + "," + strconv.FormatFloat(y, 'f', 1, 64)
+	}
+
+	s.Points = strings.Join(points, " ")
+	return s
+}
+
+func (a *Admin) newPage(title string, sess web.Session) adminPage {
+	return adminPage{Title: title, Owner: sess.User, CSRF: a.sessions.CSRFToken(sess)}
+}
+
+// render writes the template name, executed with page, with the status.
+func (a *Admin) render(w http.ResponseWriter, status int, name string, page adminPage) error {
+	var buf bytes.Buffer
+	if err := adminTemplates.ExecuteTemplate(&buf, name, page); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// renderError shows err in a page. The details of unexpected errors are logged instead of shown.
+func (a *Admin) renderError(w http.ResponseWriter, sess web.Session, err error) error {
+	page := a.newPage("Error", sess)
+
+	var webErr *web.Error
+	if !errors.As(err, &webErr) {
+		log.Printf("admin: %v", err)
+		webErr = web.NewError(http.StatusInternalServerError, "Something went wrong. Try again later.").(*web.Error)
+	}
+
+	page.Title = http.StatusText(webErr.Status)
+	page.Error = webErr.Message
+	return a.render(w, webErr.Status, "error.html", page)
+}
@@ -0,0 +1,266 @@
+package handler_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// adminBrowser is a browser of the admin pages, which keeps their cookies and follows their redirects.
+type adminBrowser struct {
+	t      *testing.T
+	srv    *httptest.Server
+	client *http.Client
+	// csrf is the CSRF token of the last page.
+	csrf string
+}
+
+var csrfInput = regexp.MustCompile(`name="csrf_token" value="([^"]+)"`)
+
+func newAdmin(t *testing.T) (*adminBrowser, link.Service) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("alice-pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	sessions, err := web.NewSessions([]byte("0123456789abcdef0123456789abcdef"), web.DefaultSessionConfig)
+	require.NoError(t, err)
+
+	linkService := link.NewService(link.NewInMemoryRepository())
+	app := web.New(web.WithoutRequestLog())
+	handler.NewAdmin(linkService, map[string][]byte{"alice": hash}, sessions, "http://localhost:8080").Register(app)
+
+	srv := httptest.NewServer(app)
+	t.Cleanup(srv.Close)
+
+	jar, err := cookiejar.New(nil)
+	require.NoError(t, err)
+	client := srv.Client()
+	client.Jar = jar
+
+	return &adminBrowser{t: t, srv: srv, client: client}, linkService
+}
+
+func (b *adminBrowser) get(path string) (*http.Response, string) {
+	resp, err := b.client.Get(b.srv.URL + path)
+	require.NoError(b.t, err)
+	return b.read(resp)
+}
+
+// post sends the form with the CSRF token of the last page.
+func (b *adminBrowser) post(path string, form url.Values) (*http.Response, string) {
+	if form == nil {
+		form = url.Values{}
+	}
+	if form.Get(web.CSRFField) == "" {
+		form.Set(web.CSRFField, b.csrf)
+	}
+
+	resp, err := b.client.PostForm(b.srv.URL+path, form)
+	require.NoError(b.t, err)
+	return b.read(resp)
+}
+
+func (b *adminBrowser) read(resp *http.Response) (*http.Response, string) {
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(b.t, err)
+
+	if m := csrfInput.FindStringSubmatch(string(body)); m != nil {
+		b.csrf = m[1]
+	}
+
+	return resp, string(body)
+}
+
+func (b *adminBrowser) login(owner, password string) (*http.Response, string) {
+	b.get("/admin/login")
+	return b.post("/admin/login", url.Values{"owner": {owner}, "password": {password}})
+}
+
+func TestAdmin_Login(t *testing.T) {
+	// Given
+	b, _ := newAdmin(t)
+
+	// When
+	anonymous, _ := b.get("/admin/links")
+	wrongResp, wrongBody := b.login("alice", "wrong-pass")
+	unknownResp, _ := b.login("bob", "alice-pass")
+	resp, body := b.login("alice", "alice-pass")
+	logoutResp, _ := b.post("/admin/logout", nil)
+	afterLogout, _ := b.get("/admin/links")
+
+	// Then
+	require.Equal(t, "/admin/login", anonymous.Request.URL.Path)
+	require.Equal(t, http.StatusUnauthorized, wrongResp.StatusCode)
+	require.Contains(t, wrongBody, "The owner or the password is not valid.")
+	require.Equal(t, http.StatusUnauthorized, unknownResp.StatusCode)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "/admin/links", resp.Request.URL.Path)
+	require.Contains(t, body, "You have no links yet.")
+	require.Equal(t, "/admin/login", logoutResp.Request.URL.Path)
+	require.Equal(t, "/admin/login", afterLogout.Request.URL.Path)
+}
+
+func TestAdmin_Headers(t *testing.T) {
+	// Given
+	b, _ := newAdmin(t)
+
+	// When
+	resp, body := b.get("/admin/login")
+
+	// Then
+	require.Equal(t, "text/html; charset=utf-8", resp.Header.Get("Content-Type"))
+	require.Contains(t, resp.Header.Get("Content-Security-Policy"), "default-src 'none'")
+	require.Equal(t, "no-store", resp.Header.Get("Cache-Control"))
+	require.NotContains(t, body, "http://", "the pages must not load external assets")
+	require.NotContains(t, body, "https://")
+}
+
+func TestAdmin_CSRF(t *testing.T) {
+	// Given
+	b, linkService := newAdmin(t)
+	b.login("alice", "alice-pass")
+
+	// When
+	resp, body := b.post("/admin/links", url.Values{web.CSRFField: {"forged"}, "url": {"https://www.google.com"}})
+
+	// Then
+	require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	require.Contains(t, body, "The form expired")
+	links, err := linkService.List(context.Background(), "alice")
+	require.NoError(t, err)
+	require.Empty(t, links)
+}
+
+func TestAdmin_Links(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	b, linkService := newAdmin(t)
+	other, err := linkService.Create(ctx, link.NewLink{URL: "https://www.bing.com", Owner: "bob"})
+	require.NoError(t, err)
+	b.login("alice", "alice-pass")
+
+	// When
+	created, createdBody := b.post("/admin/links", url.Values{"url": {"https://www.google.com"}, "title": {"Google"}})
+	_, err = linkService.Redirect(ctx, 2, link.Visit{IP: "203.0.113.1", UserAgent: "Mozilla/5.0"})
+	require.NoError(t, err)
+	_, listBody := b.get("/admin/links")
+	inactivated, inactivatedBody := b.post("/admin/links/2/inactivate", nil)
+	reactivated, _ := b.post("/admin/links/2/reactivate", nil)
+	othersResp, _ := b.post("/admin/links/1/inactivate", nil)
+
+	// Then
+	require.Equal(t, http.StatusOK, created.StatusCode)
+	require.Equal(t, "created=2", created.Request.URL.RawQuery)
+	require.Contains(t, createdBody, "Link 2 created: <a href=\"http://localhost:8080/link/2\">")
+
+	require.Contains(t, listBody, "https://www.google.com")
+	require.Contains(t, listBody, "Google")
+	require.Contains(t, listBody, "1 unique visitors in the last 14 days, up to 1 a day")
+	require.NotContains(t, listBody, "https://www.bing.com", "the links of other owners must not be listed")
+
+	require.Equal(t, http.StatusOK, inactivated.StatusCode)
+	require.Contains(t, inactivatedBody, "Reactivate")
+	require.Equal(t, http.StatusOK, reactivated.StatusCode)
+	l, err := linkService.FindByID(ctx, 2)
+	require.NoError(t, err)
+	require.False(t, l.Inactive)
+
+	require.Equal(t, http.StatusNotFound, othersResp.StatusCode)
+	l, err = linkService.FindByID(ctx, other.ID)
+	require.NoError(t, err)
+	require.False(t, l.Inactive, "the links of other owners must not change")
+}
+
+func TestAdmin_Create_Invalid(t *testing.T) {
+	tt := []struct {
+		name        string
+		form        url.Values
+		wantStatus  int
+		wantMessage string
+	}{
+		{
+			name:        "invalid url",
+			form:        url.Values{"url": {"google"}},
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "url must be an absolute http or https URL",
+		},
+		{
+			name:        "invalid max visits",
+			form:        url.Values{"url": {"https://www.google.com"}, "max_visits": {"many"}},
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "max_visits must be a number of visits",
+		},
+		{
+			name:        "secret interstitial",
+			form:        url.Values{"url": {"https://www.google.com"}, "max_visits": {"1"}, "interstitial": {"on"}},
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "a secret link cannot have an interstitial page",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			b, _ := newAdmin(t)
+			b.login("alice", "alice-pass")
+
+			// When
+			resp, body := b.post("/admin/links", tc.form)
+
+			// Then
+			require.Equal(t, tc.wantStatus, resp.StatusCode)
+			require.Contains(t, body, tc.wantMessage)
+			require.Contains(t, body, `value="`+tc.form.Get("url")+`"`, "the form must keep its values")
+		})
+	}
+}
+
+func TestAdmin_QR(t *testing.T) {
+	// Given
+	b, linkService := newAdmin(t)
+	l, err := linkService.Create(context.Background(), link.NewLink{URL: "https://www.google.com", Owner: "alice"})
+	require.NoError(t, err)
+	b.login("alice", "alice-pass")
+
+	// When
+	png, pngBody := b.get("/admin/links/1/qr")
+	svg, svgBody := b.get("/admin/links/1/qr?format=svg")
+
+	// Then
+	require.Equal(t, 1, l.ID)
+	require.Equal(t, "image/png", png.Header.Get("Content-Type"))
+	require.Equal(t, `attachment; filename="link-1.png"`, png.Header.Get("Content-Disposition"))
+	require.True(t, strings.HasPrefix(pngBody, "\x89PNG"))
+	require.Equal(t, "image/svg+xml", svg.Header.Get("Content-Type"))
+	require.Equal(t, `attachment; filename="link-1.svg"`, svg.Header.Get("Content-Disposition"))
+	require.Contains(t, svgBody, "<svg")
+}
+
+func TestReadOwners(t *testing.T) {
+	// Given
+	hash, err := bcrypt.GenerateFromPassword([]byte("alice-pass"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	// When
+	owners, err := handler.ReadOwners(strings.NewReader("# owners\n\nalice:" + string(hash) + "\n"))
+	_, invalidErr := handler.ReadOwners(strings.NewReader("alice:not-a-hash\n"))
+	_, missingErr := handler.ReadOwners(strings.NewReader("alice\n"))
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, map[string][]byte{"alice": hash}, owners)
+	require.EqualError(t, invalidErr, "line 1: crypto/bcrypt: hashedSecret too short to be a bcrypted password")
+	require.EqualError(t, missingErr, "line 1: expected owner:hash")
+}
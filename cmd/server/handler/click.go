@@ -0,0 +1,130 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+)
+
+// clickCapacity is the number of clicks that can be pending for a client before it is disconnected.
+const clickCapacity = 64
+
+type Click struct {
+	linkService link.Service
+	stream      *link.ClickStream
+	heartbeat   time.Duration
+}
+
+// NewClick creates a handler that streams the clicks published in s as Server-Sent Events,
+// sending a heartbeat comment every time the stream is idle for the given duration.
+func NewClick(l link.Service, s *link.ClickStream, heartbeat time.Duration) *Click {
+	return &Click{
+		linkService: l,
+		stream:      s,
+		heartbeat:   heartbeat,
+	}
+}
+
+// LinkEvents streams the clicks on a single link.
+func (c *Click) LinkEvents() web.Handler {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		id, err := extractID(req)
+		if err != nil {
+			return err
+		}
+
+		if _, err := c.linkService.FindByID(req.Context(), id); err != nil {
+			return serviceError(err)
+		}
+
+		return c.serve(w, req, func(click link.Click) bool {
+			return click.LinkID == id
+		})
+	}
+}
+
+// OwnerEvents streams the clicks on every link of an owner.
+func (c *Click) OwnerEvents() web.Handler {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		owner := web.Param(req, "owner")
+		if owner == "" {
+			return web.NewError(http.StatusBadRequest, "owner param is missing")
+		}
+
+		return c.serve(w, req, func(click link.Click) bool {
+			return click.Owner == owner
+		})
+	}
+}
+
+func (c *Click) serve(w http.ResponseWriter, req *http.Request, filter func(link.Click) bool) error {
+	type event struct {
+		LinkID int       `json:"link_id"`
+		URL    string    `json:"url,omitempty"`
+		Count  int       `json:"count"`
+		Time   time.Time `json:"time"`
+	}
+
+	var lastID uint64
+	if id := web.LastEventID(req); id != "" {
+		var err error
+		lastID, err = strconv.ParseUint(id, 10, 64)
+		if err != nil {
+			return web.NewError(http.StatusBadRequest, "invalid last event id")
+		}
+	}
+
+	sub := c.stream.Subscribe(filter, lastID, clickCapacity)
+	defer sub.Close()
+
+	stream, err := web.NewEventStream(w)
+	if err != nil {
+		return err
+	}
+
+	send := func(click link.Click) error {
+		return stream.Send(strconv.FormatUint(click.ID, 10), "click", event{
+			LinkID: click.LinkID,
+			URL:    click.URL,
+			Count:  click.Count,
+			Time:   click.Time.UTC(),
+		})
+	}
+
+	for _, click := range sub.Replay {
+		if err := send(click); err != nil {
+			return nil
+		}
+	}
+
+	ticker := time.NewTicker(c.heartbeat)
+	defer ticker.Stop()
+
+	shuttingDown := web.ShuttingDown(req)
+	for {
+		select {
+		case <-req.Context().Done():
+			return nil
+		case <-shuttingDown:
+			// The client will reconnect, to this server once it restarts or to another one, and resume.
+			return nil
+		case <-ticker.C:
+			if err := stream.Comment("heartbeat"); err != nil {
+				return nil
+			}
+		case click, ok := <-sub.C:
+			if !ok {
+				// The client fell behind. It will reconnect and resume from the last event it received.
+				return nil
+			}
+
+			if err := send(click); err != nil {
+				return nil
+			}
+			ticker.Reset(c.heartbeat)
+		}
+	}
+}
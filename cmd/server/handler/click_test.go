@@ -0,0 +1,161 @@
+package handler_test
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newClickServer(t *testing.T, svc link.Service, stream *link.ClickStream, heartbeat time.Duration) *httptest.Server {
+	clickHandler := handler.NewClick(svc, stream, heartbeat)
+
+	app := web.New()
+	app.Method(http.MethodGet, "/link/{id}/events", clickHandler.LinkEvents())
+	app.Method(http.MethodGet, "/owner/{owner}/events", clickHandler.OwnerEvents())
+
+	server := httptest.NewServer(app)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// readEvent reads lines from the stream until an empty line terminates an event or a comment.
+func readEvent(t *testing.T, r *bufio.Reader) []string {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return lines
+		}
+
+		lines = append(lines, line)
+	}
+}
+
+func TestClick_LinkEvents(t *testing.T) {
+	// Given
+	stream := link.NewClickStream(10)
+	svcMock := &linkServiceMock{}
+	svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1}, nil)
+	server := newClickServer(t, svcMock, stream, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/link/1/events", nil)
+
+	// When
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	stream.Handle(ctx, link.Event{Type: link.EventVisited, LinkID: 2, URL: "https://www.example.com", Count: 1})
+	stream.Handle(ctx, link.Event{Type: link.EventVisited, LinkID: 1, URL: "https://www.google.com", Count: 7})
+
+	// Then
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := readEvent(t, bufio.NewReader(resp.Body))
+	require.Len(t, lines, 3)
+	require.Equal(t, "id: 2", lines[0])
+	require.Equal(t, "event: click", lines[1])
+	require.Contains(t, lines[2], `"link_id":1`)
+	require.Contains(t, lines[2], `"count":7`)
+}
+
+func TestClick_LinkEvents_Secret(t *testing.T) {
+	// Given
+	stream := link.NewClickStream(10)
+	svcMock := &linkServiceMock{}
+	svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1, MaxVisits: 1}, nil)
+	server := newClickServer(t, svcMock, stream, time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/link/1/events", nil)
+
+	// When
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	stream.Handle(ctx, link.Event{Type: link.EventVisited, LinkID: 1, URL: "https://www.google.com/reset?token=1", Count: 1, Secret: true})
+
+	// Then
+	lines := readEvent(t, bufio.NewReader(resp.Body))
+	require.Len(t, lines, 3)
+	require.Contains(t, lines[2], `"link_id":1`)
+	require.NotContains(t, lines[2], `"url"`, "the destination of a secret link must not be streamed")
+}
+
+func TestClick_LinkEvents_NotFound(t *testing.T) {
+	// Given
+	svcMock := &linkServiceMock{}
+	svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{}, link.ErrNotFound)
+	server := newClickServer(t, svcMock, link.NewClickStream(10), time.Hour)
+
+	// When
+	resp, err := http.Get(server.URL + "/link/1/events")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Then
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestClick_OwnerEvents_Resume(t *testing.T) {
+	// Given
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := link.NewClickStream(10)
+	stream.Handle(ctx, link.Event{Type: link.EventVisited, LinkID: 1, Owner: "alice"})
+	stream.Handle(ctx, link.Event{Type: link.EventVisited, LinkID: 2, Owner: "bob"})
+	stream.Handle(ctx, link.Event{Type: link.EventVisited, LinkID: 3, Owner: "alice"})
+	server := newClickServer(t, &linkServiceMock{}, stream, time.Hour)
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/owner/alice/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+
+	// When
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Then
+	lines := readEvent(t, bufio.NewReader(resp.Body))
+	require.Equal(t, "id: 3", lines[0])
+	require.Contains(t, lines[2], `"link_id":3`)
+}
+
+func TestClick_OwnerEvents_Heartbeat(t *testing.T) {
+	// Given
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := newClickServer(t, &linkServiceMock{}, link.NewClickStream(10), 10*time.Millisecond)
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/owner/alice/events", nil)
+
+	// When
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	// Then
+	require.Equal(t, []string{": heartbeat"}, readEvent(t, bufio.NewReader(resp.Body)))
+}
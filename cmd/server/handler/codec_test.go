@@ -0,0 +1,248 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+func TestLink_Codecs(t *testing.T) {
+	expiresAt := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	newLink := link.NewLink{
+		URL:      "https://www.google.com",
+		Password: "s3cret-pass",
+		Owner:    "alice",
+		Codes:    []link.NewAccessCode{{Name: "bob", Password: "b0b-secret", Expires: expiresAt}},
+	}
+	msgpackBody, err := msgpack.Marshal(map[string]interface{}{
+		"link": "https://www.google.com", "password": "s3cret-pass", "owner": "alice",
+		"codes": []map[string]string{{"name": "bob", "password": "b0b-secret", "expires_at": "2021-06-01T10:00:00Z"}},
+	})
+	require.NoError(t, err)
+
+	tt := []struct {
+		name            string
+		method          string
+		target          string
+		contentType     string
+		accept          string
+		body            string
+		wantStatus      int
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "create from json",
+			method:          http.MethodPost,
+			target:          "/link",
+			contentType:     "application/json",
+			body:            `{"link":"https://www.google.com","password":"s3cret-pass","owner":"alice","codes":[{"name":"bob","password":"b0b-secret","expires_at":"2021-06-01T10:00:00Z"}]}`,
+			wantStatus:      http.StatusCreated,
+			wantContentType: "application/json",
+			wantBody:        `{"id":1}`,
+		},
+		{
+			name:            "create from form",
+			method:          http.MethodPost,
+			target:          "/link",
+			contentType:     "application/x-www-form-urlencoded",
+			accept:          "text/xml",
+			body:            "link=https%3A%2F%2Fwww.google.com&password=s3cret-pass&owner=alice&codes[0].name=bob&codes[0].password=b0b-secret&codes[0].expires_at=2021-06-01T10%3A00%3A00Z",
+			wantStatus:      http.StatusCreated,
+			wantContentType: "text/xml",
+			wantBody:        `<response><id>1</id></response>`,
+		},
+		{
+			name:            "create from xml",
+			method:          http.MethodPost,
+			target:          "/link",
+			contentType:     "application/xml",
+			accept:          "application/xml",
+			body:            `<link><link>https://www.google.com</link><password>s3cret-pass</password><owner>alice</owner><codes><name>bob</name><password>b0b-secret</password><expires_at>2021-06-01T10:00:00Z</expires_at></codes></link>`,
+			wantStatus:      http.StatusCreated,
+			wantContentType: "application/xml",
+			wantBody:        `<response><id>1</id></response>`,
+		},
+		{
+			name:            "create from msgpack",
+			method:          http.MethodPost,
+			target:          "/link",
+			contentType:     "application/msgpack",
+			accept:          "application/msgpack",
+			body:            string(msgpackBody),
+			wantStatus:      http.StatusCreated,
+			wantContentType: "application/msgpack",
+			wantBody:        "\x81\xa2id\x01",
+		},
+		{
+			name:            "create from unsupported media type",
+			method:          http.MethodPost,
+			target:          "/link",
+			contentType:     "text/plain",
+			body:            "https://www.google.com",
+			wantStatus:      http.StatusUnsupportedMediaType,
+			wantContentType: "application/json",
+			wantBody:        `{"code":"unsupported_media_type","message":"unsupported content type \"text/plain\""}`,
+		},
+		{
+			name:            "create with invalid xml",
+			method:          http.MethodPost,
+			target:          "/link",
+			contentType:     "application/xml",
+			accept:          "application/xml",
+			body:            `<link><password>s3cret-pass</password></link>`,
+			wantStatus:      http.StatusBadRequest,
+			wantContentType: "application/xml",
+			wantBody:        `<response><code>invalid_request</code><message>link is missing</message><fields><field>link</field><code>required</code><message>link is missing</message></fields></response>`,
+		},
+		{
+			name:            "create not acceptable",
+			method:          http.MethodPost,
+			target:          "/link",
+			contentType:     "application/json",
+			accept:          "image/png",
+			body:            `{"link":"https://www.google.com","password":"s3cret-pass","owner":"alice","codes":[{"name":"bob","password":"b0b-secret","expires_at":"2021-06-01T10:00:00Z"}]}`,
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: "application/json",
+			wantBody:        `{"code":"not_acceptable","message":"none of the accepted media types \"image/png\" is supported"}`,
+		},
+		{
+			name:            "list",
+			method:          http.MethodGet,
+			target:          "/link?owner=alice",
+			accept:          "application/xml",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<response><item><id>1</id><url>https://www.google.com</url><owner>alice</owner><count>3</count><inactive>false</inactive></item></response>`,
+		},
+		{
+			name:            "list with large numbers",
+			method:          http.MethodGet,
+			target:          "/link?owner=bob",
+			accept:          "application/xml",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/xml",
+			wantBody:        `<response><item><id>9007199254740993</id><url>https://www.bing.com</url><owner>bob</owner><count>1234567</count><inactive>false</inactive></item></response>`,
+		},
+		{
+			name:            "list not acceptable",
+			method:          http.MethodGet,
+			target:          "/link?owner=alice",
+			accept:          "application/pdf",
+			wantStatus:      http.StatusNotAcceptable,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "metrics",
+			method:          http.MethodGet,
+			target:          "/link/1/metrics",
+			accept:          "application/msgpack",
+			wantStatus:      http.StatusOK,
+			wantContentType: "application/msgpack",
+		},
+		{
+			name:            "redirect error",
+			method:          http.MethodGet,
+			target:          "/link/2",
+			accept:          "application/xml",
+			wantStatus:      http.StatusNotFound,
+			wantContentType: "application/xml",
+			wantBody:        `<response><code>link_not_found</code><message>link not found</message></response>`,
+		},
+		{
+			name:       "inactivate",
+			method:     http.MethodPost,
+			target:     "/link/1/inactivate",
+			accept:     "application/xml",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:            "grant access from form",
+			method:          http.MethodPost,
+			target:          "/link/1/access",
+			contentType:     "application/x-www-form-urlencoded",
+			accept:          "application/xml",
+			body:            "password=s3cret-pass&ttl=1h",
+			wantStatus:      http.StatusCreated,
+			wantContentType: "application/xml",
+			wantBody:        `<response><url>http://localhost:8080/link/1?exp=1622541600&amp;kid=2021-06&amp;sig=_u0</url><key_id>2021-06</key_id><expires_at>2021-06-01T10:00:00Z</expires_at></response>`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			svcMock := &linkServiceMock{}
+			svcMock.On("Create", mock.Anything, newLink).Return(link.Link{ID: 1}, nil)
+			svcMock.On("List", mock.Anything, "alice").Return([]link.Link{{ID: 1, URL: "https://www.google.com", Owner: "alice", Count: 3}}, nil)
+			svcMock.On("List", mock.Anything, "bob").Return([]link.Link{{ID: 1<<53 + 1, URL: "https://www.bing.com", Owner: "bob", Count: 1234567}}, nil)
+			svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1, URL: "https://www.google.com", Count: 3}, nil)
+			svcMock.On("FindByID", mock.Anything, 2).Return(link.Link{}, link.ErrNotFound)
+			svcMock.On("Redirect", mock.Anything, 2, mock.Anything).Return(link.Link{}, link.ErrNotFound)
+			svcMock.On("Inactivate", mock.Anything, 1).Return(nil)
+			svcMock.On("GrantAccess", mock.Anything, 1, mock.MatchedBy(func(na link.NewAccess) bool {
+				return na.Password == "s3cret-pass"
+			})).Return(link.Access{LinkID: 1, KeyID: "2021-06", Expires: expiresAt, Signature: []byte{0xfe, 0xed}}, nil)
+
+			linkHandler := handler.NewLink(svcMock, handler.WithPublicURL("http://localhost:8080"))
+			app := web.New()
+			app.Method(http.MethodPost, "/link", linkHandler.Create())
+			app.Method(http.MethodGet, "/link", linkHandler.List())
+			app.Method(http.MethodGet, "/link/{id}", linkHandler.Redirect())
+			app.Method(http.MethodGet, "/link/{id}/metrics", linkHandler.Metrics())
+			app.Method(http.MethodPost, "/link/{id}/inactivate", linkHandler.Inactivate())
+			app.Method(http.MethodPost, "/link/{id}/access", linkHandler.GrantAccess())
+
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			// When
+			app.ServeHTTP(rr, req)
+
+			// Then
+			require.Equal(t, tc.wantStatus, rr.Code, rr.Body.String())
+			require.Equal(t, tc.wantContentType, rr.Header().Get("Content-Type"))
+			if tc.wantBody != "" {
+				require.Equal(t, tc.wantBody, strings.TrimSpace(rr.Body.String()))
+			}
+		})
+	}
+}
+
+func TestLink_Metrics_Msgpack(t *testing.T) {
+	// Given
+	svcMock := &linkServiceMock{}
+	svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1, URL: "https://www.google.com", Count: 3}, nil)
+
+	app := web.New()
+	app.Method(http.MethodGet, "/link/{id}/metrics", handler.NewLink(svcMock).Metrics())
+
+	req := httptest.NewRequest(http.MethodGet, "/link/1/metrics", nil)
+	req.Header.Set("Accept", "application/msgpack")
+	rr := httptest.NewRecorder()
+
+	// When
+	app.ServeHTTP(rr, req)
+
+	// Then
+	var resp map[string]interface{}
+	require.NoError(t, msgpack.Unmarshal(rr.Body.Bytes(), &resp))
+	require.EqualValues(t, 1, resp["id"])
+	require.Equal(t, "https://www.google.com", resp["url"])
+	require.EqualValues(t, 3, resp["count"])
+}
@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/keyring"
+	"github.com/emacampolo/link-tracker/internal/platform/qr"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/emacampolo/link-tracker/internal/webhook"
+)
+
+// Error codes returned by the API. They are stable, so clients can rely on them instead of the messages.
+const (
+	CodeLinkNotFound     = "link_not_found"
+	CodePasswordRequired = "password_required"
+	CodeInvalidPassword  = "invalid_password"
+	CodeLinkInactive     = "link_inactive"
+	CodeLinkClosed       = "link_closed"
+	CodeSecretLink       = "secret_link"
+	CodeInvalidLink      = "invalid_link"
+	CodeInvalidSchedule  = "invalid_schedule"
+	CodeWeakPassword     = "weak_password"
+	CodeInvalidAccess    = "invalid_access"
+	CodeAccessDisabled   = "access_disabled"
+	CodeQRTooSmall       = "qr_too_small"
+	CodeWebhookNotFound  = "webhook_not_found"
+)
+
+// errorCatalog maps the errors of the services to their status and code. The first match wins,
+// so the errors that wrap others go first.
+var errorCatalog = []struct {
+	err    error
+	status int
+	code   string
+}{
+	{link.ErrNotFound, http.StatusNotFound, CodeLinkNotFound},
+	{link.ErrPasswordRequired, http.StatusUnauthorized, CodePasswordRequired},
+	{link.ErrAuthentication, http.StatusUnauthorized, CodeInvalidPassword},
+	{link.ErrInactive, http.StatusUnprocessableEntity, CodeLinkInactive},
+	{link.ErrConsumed, http.StatusUnprocessableEntity, CodeLinkInactive},
+	{link.ErrClosed, http.StatusForbidden, CodeLinkClosed},
+	{link.ErrSecret, http.StatusForbidden, CodeSecretLink},
+	{link.ErrInvalidLink, http.StatusBadRequest, CodeInvalidLink},
+	{link.ErrInvalidSchedule, http.StatusBadRequest, CodeInvalidSchedule},
+	{link.ErrWeakPassword, http.StatusBadRequest, CodeWeakPassword},
+	{link.ErrInvalidAccess, http.StatusBadRequest, CodeInvalidAccess},
+	{link.ErrAccessDisabled, http.StatusNotImplemented, CodeAccessDisabled},
+	{keyring.ErrNoKey, http.StatusNotImplemented, CodeAccessDisabled},
+	{qr.ErrSizeTooSmall, http.StatusBadRequest, CodeQRTooSmall},
+	{webhook.ErrNotFound, http.StatusNotFound, CodeWebhookNotFound},
+}
+
+// serviceError returns the web.Error of err according to the errorCatalog. Unknown errors are returned as they are.
+func serviceError(err error) error {
+	for _, e := range errorCatalog {
+		if errors.Is(err, e.err) {
+			return web.NewCodedError(e.status, e.code, err.Error())
+		}
+	}
+
+	return err
+}
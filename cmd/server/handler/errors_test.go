@@ -0,0 +1,83 @@
+package handler_test
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLink_Redirect_Errors(t *testing.T) {
+	tt := []struct {
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{err: link.ErrNotFound, wantStatus: http.StatusNotFound, wantCode: handler.CodeLinkNotFound},
+		{err: link.ErrPasswordRequired, wantStatus: http.StatusUnauthorized, wantCode: handler.CodePasswordRequired},
+		{err: link.ErrAuthentication, wantStatus: http.StatusUnauthorized, wantCode: handler.CodeInvalidPassword},
+		{err: link.ErrInactive, wantStatus: http.StatusUnprocessableEntity, wantCode: handler.CodeLinkInactive},
+		{err: link.ErrSecret, wantStatus: http.StatusForbidden, wantCode: handler.CodeSecretLink},
+		{err: fmt.Errorf("wrapped: %w", link.ErrInactive), wantStatus: http.StatusUnprocessableEntity, wantCode: handler.CodeLinkInactive},
+		{err: errors.New("boom"), wantStatus: http.StatusInternalServerError, wantCode: "internal_server_error"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.err.Error(), func(t *testing.T) {
+			// Given
+			req := httptest.NewRequest(http.MethodGet, "/link/1?password=s3cret-pass", nil)
+			req.Header.Set("Accept", "text/html, application/problem+json;q=0.9")
+			rr := httptest.NewRecorder()
+
+			svcMock := &linkServiceMock{}
+			svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1}, nil)
+			svcMock.On("Redirect", mock.Anything, 1, mock.Anything).Return(link.Link{}, tc.err)
+
+			app := web.New()
+			app.Method(http.MethodGet, "/link/{id}", handler.NewLink(svcMock).Redirect())
+
+			// When
+			app.ServeHTTP(rr, req)
+
+			// Then
+			require.Equal(t, tc.wantStatus, rr.Code)
+			require.Equal(t, web.ProblemContentType, rr.Header().Get("Content-Type"))
+			require.JSONEq(t, fmt.Sprintf(`{
+				"type": "/problems/%s",
+				"title": %q,
+				"status": %d,
+				"detail": %q,
+				"instance": "/link/1",
+				"code": %q
+			}`, tc.wantCode, http.StatusText(tc.wantStatus), tc.wantStatus, tc.err.Error(), tc.wantCode), rr.Body.String())
+			require.NotContains(t, rr.Body.String(), "s3cret-pass", "the password must not be sent back")
+		})
+	}
+}
+
+func TestLink_Inactivate_NotFound(t *testing.T) {
+	// Given
+	req := httptest.NewRequest(http.MethodPost, "/link/1/inactivate", nil)
+	rr := httptest.NewRecorder()
+
+	svcMock := &linkServiceMock{}
+	svcMock.On("Inactivate", mock.Anything, 1).Return(link.ErrNotFound)
+
+	app := web.New()
+	app.Method(http.MethodPost, "/link/{id}/inactivate", handler.NewLink(svcMock).Inactivate())
+
+	// When
+	app.ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusNotFound, rr.Code)
+	require.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"code":"link_not_found","message":"link not found"}`, rr.Body.String())
+}
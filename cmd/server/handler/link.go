@@ -3,132 +3,472 @@ package handler
 import (
 	"errors"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/emacampolo/link-tracker/internal/bot"
 	"github.com/emacampolo/link-tracker/internal/link"
 	"github.com/emacampolo/link-tracker/internal/platform/web"
 )
 
 type Link struct {
-	linkService link.Service
+	linkService   link.Service
+	botClassifier *bot.Classifier
+	botPreview    bool
+	ownDomains    []string
+	publicURL     string
 }
 
-func NewLink(l link.Service) *Link {
-	return &Link{
-		linkService: l,
+// LinkOption configures optional behaviour of the Link handler.
+type LinkOption func(*Link)
+
+// WithBotClassifier makes Redirect classify the requests with c, so the ones made
+// by bots are not counted as visits.
+func WithBotClassifier(c *bot.Classifier) LinkOption {
+	return func(lnk *Link) {
+		lnk.botClassifier = c
 	}
 }
 
-func (lnk *Link) Create() web.Handler {
-	type request struct {
-		Link     string `json:"link"`
-		Password string `json:"password"`
+// WithBotPreview makes Redirect answer bots with a page containing the Open Graph
+// metadata of the link instead of redirecting them.
+func WithBotPreview() LinkOption {
+	return func(lnk *Link) {
+		lnk.botPreview = true
 	}
+}
 
-	type response struct {
-		ID int `json:"id"`
+// WithOwnDomains sets the domains that belong to us. The interstitial page warns
+// the visitors when the destination of a link is outside of them.
+func WithOwnDomains(domains ...string) LinkOption {
+	return func(lnk *Link) {
+		lnk.ownDomains = domains
 	}
+}
+
+// WithPublicURL sets the address where the server is reachable by the users, used to build
+// the signed URLs. They are relative to the server if it is not set.
+func WithPublicURL(u string) LinkOption {
+	return func(lnk *Link) {
+		lnk.publicURL = strings.TrimSuffix(u, "/")
+	}
+}
+
+func NewLink(l link.Service, opts ...LinkOption) *Link {
+	lnk := &Link{
+		linkService: l,
+	}
+
+	for _, opt := range opts {
+		opt(lnk)
+	}
+
+	return lnk
+}
+
+type createLinkRequest struct {
+	Link         string           `json:"link" validate:"required,url"`
+	Password     string           `json:"password"`
+	Owner        string           `json:"owner" validate:"max=100"`
+	Title        string           `json:"title" validate:"max=200"`
+	Interstitial bool             `json:"interstitial"`
+	FallbackURL  string           `json:"fallback_url" validate:"url"`
+	Schedule     *scheduleRequest `json:"schedule"`
+	MaxVisits    int              `json:"max_visits" validate:"min=0"`
+	Codes        []codeRequest    `json:"codes" validate:"max=20"`
+}
 
+type createLinkResponse struct {
+	ID int `json:"id"`
+}
+
+// createLinkResponseV2 identifies the link with a string, so that its ID can stop being a number.
+type createLinkResponseV2 struct {
+	ID string `json:"id"`
+}
+
+func (lnk *Link) Create() web.Handler {
 	return func(w http.ResponseWriter, req *http.Request) error {
-		var r request
+		var r createLinkRequest
 		if err := web.Decode(req, &r); err != nil {
-			return web.NewError(http.StatusBadRequest, err.Error())
+			return err
 		}
 
-		if r.Link == "" {
-			return web.NewError(http.StatusBadRequest, "link is missing")
+		var schedule link.Schedule
+		if r.Schedule != nil {
+			var err error
+			if schedule, err = r.Schedule.toSchedule(); err != nil {
+				return web.NewError(http.StatusBadRequest, err.Error())
+			}
 		}
 
-		if r.Password == "" {
-			return web.NewError(http.StatusBadRequest, "password is missing")
+		var codes []link.NewAccessCode
+		for _, c := range r.Codes {
+			nc := link.NewAccessCode{Name: c.Name, Password: c.Password}
+			if c.ExpiresAt != nil {
+				nc.Expires = *c.ExpiresAt
+			}
+
+			codes = append(codes, nc)
 		}
 
-		l, err := lnk.linkService.Create(req.Context(), r.Link, r.Password)
+		l, err := lnk.linkService.Create(req.Context(), link.NewLink{
+			URL:          r.Link,
+			Password:     r.Password,
+			Owner:        r.Owner,
+			Title:        r.Title,
+			Interstitial: r.Interstitial,
+			FallbackURL:  r.FallbackURL,
+			Schedule:     schedule,
+			MaxVisits:    r.MaxVisits,
+			Codes:        codes,
+		})
 		if err != nil {
-			return err
+			return serviceError(err)
 		}
 
-		resp := response{
+		var resp interface{} = createLinkResponse{
 			ID: l.ID,
 		}
+		if web.APIVersion(req) == V2 {
+			resp = createLinkResponseV2{ID: strconv.Itoa(l.ID)}
+		}
 
 		return web.Respond(req.Context(), w, resp, http.StatusCreated)
 	}
 }
 
+// Redirect sends the visitor to the destination of the link. The interstitial page is shown
+// instead when the id is followed by a plus sign (/link/1+), when the preview query parameter
+// is 1, or when the link always requires it and the visitor has not confirmed yet.
 func (lnk *Link) Redirect() web.Handler {
 	return func(w http.ResponseWriter, req *http.Request) error {
-		id, err := lnk.extractID(req)
+		query := req.URL.Query()
+		idParam := web.Param(req, "id")
+		preview := query.Get("preview") == "1" || strings.HasSuffix(idParam, "+")
+
+		id, err := parseID(strings.TrimSuffix(idParam, "+"))
 		if err != nil {
 			return web.NewError(http.StatusBadRequest, err.Error())
 		}
 
-		password := req.URL.Query().Get("password")
-		if password == "" {
-			return web.NewError(http.StatusBadRequest, "password is missing")
+		access, err := parseAccess(id, query)
+		if err != nil {
+			return web.NewError(http.StatusBadRequest, err.Error())
 		}
 
-		ll, err := lnk.linkService.Redirect(req.Context(), id, password)
-		if err != nil {
-			if errors.Is(err, link.ErrNotFound) {
-				return web.NewError(http.StatusNotFound, err.Error())
+		password := query.Get("password")
+
+		var verdict bot.Verdict
+		if lnk.botClassifier != nil {
+			verdict = lnk.botClassifier.Classify(req)
+		}
+
+		visit := link.Visit{
+			Password:  password,
+			Access:    access,
+			IP:        web.ClientIP(req),
+			UserAgent: req.UserAgent(),
+			Bot:       verdict.Bot,
+		}
+
+		if !preview && query.Get("confirm") != "1" {
+			l, err := lnk.linkService.FindByID(req.Context(), id)
+			if err == nil && l.Interstitial {
+				preview = true
 			}
+		}
 
-			if errors.Is(err, link.ErrInactive) {
-				return web.NewError(http.StatusUnprocessableEntity, err.Error())
+		if preview {
+			ll, err := lnk.linkService.Preview(req.Context(), id, visit)
+			if err != nil {
+				return visitError(w, req, err)
 			}
 
-			return err
+			return renderInterstitial(w, req, ll, password, !lnk.ownDomain(ll.Destination()))
 		}
 
-		http.Redirect(w, req, ll.URL, http.StatusMovedPermanently)
+		ll, err := lnk.linkService.Redirect(req.Context(), id, visit)
+		if err != nil {
+			return visitError(w, req, err)
+		}
+
+		if verdict.Bot && lnk.botPreview {
+			return renderPreview(w, req, ll)
+		}
+
+		if temporaryRedirect(ll) {
+			w.Header().Set("Cache-Control", "no-store")
+			http.Redirect(w, req, ll.Destination(), http.StatusFound)
+			return nil
+		}
+
+		http.Redirect(w, req, ll.Destination(), http.StatusMovedPermanently)
 		return nil
 	}
 }
 
-func (lnk *Link) Metrics() web.Handler {
-	type response struct {
-		ID       int    `json:"id"`
-		URL      string `json:"url"`
-		Count    int    `json:"count"`
-		Inactive bool   `json:"inactive"`
+// temporaryRedirect tells whether the visitors of l must be redirected without letting the browser remember
+// the destination. A permanent redirect would let it reach a secret destination again without counting the
+// visit, keep sending it to the fallback URL after the destination recovers, or reach a scheduled destination
+// after the link is deactivated or outside of its windows.
+func temporaryRedirect(l link.Link) bool {
+	return l.Secret() || l.FallbackURL != "" || !l.Schedule.IsZero()
+}
+
+// visitError answers a visit that failed with err. Visitors of a link outside of its
+// schedule are sent to its closed URL, if it has one.
+func visitError(w http.ResponseWriter, req *http.Request, err error) error {
+	var closed *link.ClosedError
+	if errors.As(err, &closed) && closed.URL != "" {
+		http.Redirect(w, req, closed.URL, http.StatusFound)
+		return nil
 	}
 
+	return serviceError(err)
+}
+
+type metricsResponse struct {
+	ID                  int               `json:"id"`
+	URL                 string            `json:"url,omitempty"`
+	Owner               string            `json:"owner,omitempty"`
+	Title               string            `json:"title,omitempty"`
+	Interstitial        bool              `json:"interstitial"`
+	Count               int               `json:"count"`
+	PreviewCount        int               `json:"preview_count"`
+	BotCount            int               `json:"bot_count"`
+	UniqueVisitors      uint64            `json:"unique_visitors"`
+	DailyUniqueVisitors map[string]uint64 `json:"daily_unique_visitors"`
+	Inactive            bool              `json:"inactive"`
+	Metadata            *metadataResponse `json:"metadata,omitempty"`
+	FallbackURL         string            `json:"fallback_url,omitempty"`
+	Health              healthResponse    `json:"health"`
+	Schedule            *scheduleResponse `json:"schedule,omitempty"`
+	Public              bool              `json:"public"`
+	Codes               []codeResponse    `json:"codes,omitempty"`
+	MaxVisits           int               `json:"max_visits,omitempty"`
+	RemainingVisits     *int              `json:"remaining_visits,omitempty"`
+	ConsumedAt          *time.Time        `json:"consumed_at,omitempty"`
+}
+
+// metricsResponseV2 is the metricsResponse of version 2, whose ID is a string.
+type metricsResponseV2 struct {
+	metricsResponse
+	ID string `json:"id"`
+}
+
+func (lnk *Link) Metrics() web.Handler {
 	return func(w http.ResponseWriter, req *http.Request) error {
-		id, err := lnk.extractID(req)
+		id, err := extractID(req)
 		if err != nil {
 			return web.NewError(http.StatusBadRequest, err.Error())
 		}
 
 		l, err := lnk.linkService.FindByID(req.Context(), id)
 		if err != nil {
-			if errors.Is(err, link.ErrNotFound) {
-				return web.NewError(http.StatusNotFound, err.Error())
-			}
+			return serviceError(err)
+		}
+
+		resp := metricsResponse{
+			ID:                  l.ID,
+			URL:                 l.URL,
+			Owner:               l.Owner,
+			Title:               l.Title,
+			Interstitial:        l.Interstitial,
+			Count:               l.Count,
+			PreviewCount:        l.PreviewCount,
+			BotCount:            l.BotCount,
+			UniqueVisitors:      l.UniqueVisitors(),
+			DailyUniqueVisitors: l.DailyUniqueVisitors(),
+			Inactive:            l.Inactive,
+			Metadata:            newMetadataResponse(l.Metadata),
+			FallbackURL:         l.FallbackURL,
+			Health:              newHealthResponse(l.Health),
+			Schedule:            newScheduleResponse(l),
+			Public:              l.Public(),
+			Codes:               newCodeResponses(l.Codes),
+			MaxVisits:           l.MaxVisits,
+		}
+
+		if l.Secret() {
+			// The destination of a secret link is only revealed by redirecting.
+			resp.URL = ""
+			resp.FallbackURL = ""
+			remaining := l.RemainingVisits()
+			resp.RemainingVisits = &remaining
+		}
+
+		if !l.ConsumedAt.IsZero() {
+			consumedAt := l.ConsumedAt.UTC()
+			resp.ConsumedAt = &consumedAt
+		}
 
+		if web.APIVersion(req) == V2 {
+			return web.Respond(req.Context(), w, metricsResponseV2{metricsResponse: resp, ID: strconv.Itoa(l.ID)}, http.StatusOK)
+		}
+
+		return web.Respond(req.Context(), w, resp, http.StatusOK)
+	}
+}
+
+type codeRequest struct {
+	Name      string     `json:"name" validate:"required,max=50"`
+	Password  string     `json:"password" validate:"required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type codeResponse struct {
+	Name       string     `json:"name"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	Count      int        `json:"count"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+func newCodeResponses(codes []link.AccessCode) []codeResponse {
+	resp := make([]codeResponse, 0, len(codes))
+	for _, c := range codes {
+		cr := codeResponse{Name: c.Name, Count: c.Count}
+		if !c.Expires.IsZero() {
+			expires := c.Expires.UTC()
+			cr.ExpiresAt = &expires
+		}
+
+		if !c.LastUsedAt.IsZero() {
+			lastUsed := c.LastUsedAt.UTC()
+			cr.LastUsedAt = &lastUsed
+		}
+
+		resp = append(resp, cr)
+	}
+
+	return resp
+}
+
+type checkResponse struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type healthResponse struct {
+	Failing             bool            `json:"failing"`
+	FailingSince        *time.Time      `json:"failing_since,omitempty"`
+	ConsecutiveFailures int             `json:"consecutive_failures"`
+	History             []checkResponse `json:"history"`
+}
+
+func newHealthResponse(h link.Health) healthResponse {
+	resp := healthResponse{
+		Failing:             h.Failing,
+		ConsecutiveFailures: h.ConsecutiveFailures,
+		History:             make([]checkResponse, 0, len(h.History)),
+	}
+
+	if !h.FailingSince.IsZero() {
+		since := h.FailingSince.UTC()
+		resp.FailingSince = &since
+	}
+
+	for _, c := range h.History {
+		resp.History = append(resp.History, checkResponse{
+			Time:       c.Time.UTC(),
+			StatusCode: c.StatusCode,
+			DurationMS: c.Duration.Milliseconds(),
+			Error:      c.Err,
+		})
+	}
+
+	return resp
+}
+
+type linkResponse struct {
+	ID       int               `json:"id"`
+	URL      string            `json:"url,omitempty"`
+	Owner    string            `json:"owner,omitempty"`
+	Title    string            `json:"title,omitempty"`
+	Count    int               `json:"count"`
+	Inactive bool              `json:"inactive"`
+	Metadata *metadataResponse `json:"metadata,omitempty"`
+}
+
+// linkResponseV2 is the linkResponse of version 2, whose ID is a string.
+type linkResponseV2 struct {
+	linkResponse
+	ID string `json:"id"`
+}
+
+// List returns the links, optionally filtered by the owner query parameter.
+func (lnk *Link) List() web.Handler {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		links, err := lnk.linkService.List(req.Context(), req.URL.Query().Get("owner"))
+		if err != nil {
 			return err
 		}
 
-		resp := response{
-			ID:       l.ID,
-			URL:      l.URL,
-			Count:    l.Count,
-			Inactive: l.Inactive,
+		resp := make([]linkResponse, 0, len(links))
+		for _, l := range links {
+			if l.Secret() {
+				l.URL = ""
+			}
+
+			resp = append(resp, linkResponse{
+				ID:       l.ID,
+				URL:      l.URL,
+				Owner:    l.Owner,
+				Title:    l.Title,
+				Count:    l.Count,
+				Inactive: l.Inactive,
+				Metadata: newMetadataResponse(l.Metadata),
+			})
+		}
+
+		if web.APIVersion(req) == V2 {
+			respV2 := make([]linkResponseV2, 0, len(resp))
+			for _, r := range resp {
+				respV2 = append(respV2, linkResponseV2{linkResponse: r, ID: strconv.Itoa(r.ID)})
+			}
+			return web.Respond(req.Context(), w, respV2, http.StatusOK)
 		}
 
 		return web.Respond(req.Context(), w, resp, http.StatusOK)
 	}
 }
 
+type metadataResponse struct {
+	Title       string    `json:"title,omitempty"`
+	Description string    `json:"description,omitempty"`
+	Image       string    `json:"image,omitempty"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// newMetadataResponse returns nil while the metadata has not been fetched yet.
+func newMetadataResponse(m link.Metadata) *metadataResponse {
+	if m.FetchedAt.IsZero() {
+		return nil
+	}
+
+	return &metadataResponse{
+		Title:       m.Title,
+		Description: m.Description,
+		Image:       m.Image,
+		FetchedAt:   m.FetchedAt.UTC(),
+		Error:       m.Err,
+	}
+}
+
 func (lnk *Link) Inactivate() web.Handler {
 	return func(w http.ResponseWriter, req *http.Request) error {
-		id, err := lnk.extractID(req)
+		id, err := extractID(req)
 		if err != nil {
 			return web.NewError(http.StatusBadRequest, err.Error())
 		}
 
 		if err := lnk.linkService.Inactivate(req.Context(), id); err != nil {
-			return err
+			return serviceError(err)
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -136,8 +476,42 @@ func (lnk *Link) Inactivate() web.Handler {
 	}
 }
 
-func (lnk *Link) extractID(req *http.Request) (int, error) {
-	idParam := web.Param(req, "id")
+// ownDomain reports whether rawURL points to any of our domains or their subdomains.
+func (lnk *Link) ownDomain(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, d := range lnk.ownDomains {
+		d = strings.ToLower(d)
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ByLinkID limits each link identified by the id route parameter. The spellings of the same id that
+// the routes accept, such as 01 or 1+, share the limit. Requests with an invalid id are not limited by it.
+func ByLinkID() web.KeyFunc {
+	return func(r *http.Request) string {
+		id, err := parseID(strings.TrimSuffix(web.Param(r, "id"), "+"))
+		if err != nil {
+			return ""
+		}
+
+		return strconv.Itoa(id)
+	}
+}
+
+func extractID(req *http.Request) (int, error) {
+	return parseID(web.Param(req, "id"))
+}
+
+func parseID(idParam string) (int, error) {
 	if idParam == "" {
 		return 0, web.NewError(http.StatusBadRequest, "id param is missing")
 	}
@@ -7,9 +7,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/bot"
 	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
@@ -19,13 +22,18 @@ type linkServiceMock struct {
 	mock.Mock
 }
 
-func (l *linkServiceMock) Create(ctx context.Context, url, password string) (link.Link, error) {
-	args := l.Called(ctx, url, password)
+func (l *linkServiceMock) Create(ctx context.Context, nl link.NewLink) (link.Link, error) {
+	args := l.Called(ctx, nl)
 	return args.Get(0).(link.Link), args.Error(1)
 }
 
-func (l *linkServiceMock) Redirect(ctx context.Context, ID int, password string) (link.Link, error) {
-	args := l.Called(ctx, ID, password)
+func (l *linkServiceMock) Redirect(ctx context.Context, ID int, v link.Visit) (link.Link, error) {
+	args := l.Called(ctx, ID, v)
+	return args.Get(0).(link.Link), args.Error(1)
+}
+
+func (l *linkServiceMock) Preview(ctx context.Context, ID int, v link.Visit) (link.Link, error) {
+	args := l.Called(ctx, ID, v)
 	return args.Get(0).(link.Link), args.Error(1)
 }
 
@@ -34,10 +42,36 @@ func (l *linkServiceMock) FindByID(ctx context.Context, ID int) (link.Link, erro
 	return args.Get(0).(link.Link), args.Error(1)
 }
 
+func (l *linkServiceMock) List(ctx context.Context, owner string) ([]link.Link, error) {
+	args := l.Called(ctx, owner)
+	return args.Get(0).([]link.Link), args.Error(1)
+}
+
 func (l *linkServiceMock) Inactivate(ctx context.Context, ID int) error {
 	return l.Called(ctx, ID).Error(0)
 }
 
+func (l *linkServiceMock) Reactivate(ctx context.Context, ID int) error {
+	return l.Called(ctx, ID).Error(0)
+}
+
+func (l *linkServiceMock) SetMetadata(ctx context.Context, ID int, m link.Metadata) error {
+	return l.Called(ctx, ID, m).Error(0)
+}
+
+func (l *linkServiceMock) RecordCheck(ctx context.Context, ID int, c link.Check) error {
+	return l.Called(ctx, ID, c).Error(0)
+}
+
+func (l *linkServiceMock) SyncSchedule(ctx context.Context, ID int) error {
+	return l.Called(ctx, ID).Error(0)
+}
+
+func (l *linkServiceMock) GrantAccess(ctx context.Context, ID int, na link.NewAccess) (link.Access, error) {
+	args := l.Called(ctx, ID, na)
+	return args.Get(0).(link.Access), args.Error(1)
+}
+
 func TestLink_Create(t *testing.T) {
 	// Given
 	r := struct {
@@ -54,7 +88,7 @@ func TestLink_Create(t *testing.T) {
 	l := link.Link{ID: 1}
 
 	svcMock := &linkServiceMock{}
-	svcMock.On("Create", req.Context(), r.Link, r.Password).Return(l, nil)
+	svcMock.On("Create", mock.Anything, link.NewLink{URL: r.Link, Password: r.Password}).Return(l, nil)
 
 	linkHandler := handler.NewLink(svcMock)
 
@@ -80,12 +114,12 @@ func TestLink_Create_RequiredFields(t *testing.T) {
 		{
 			name:    "link is required",
 			req:     request{Password: "1234"},
-			wantErr: `{"code":"bad_request","message":"link is missing"}`,
+			wantErr: `{"code":"invalid_request","message":"link is missing","fields":[{"field":"link","code":"required","message":"link is missing"}]}`,
 		},
 		{
-			name:    "password is required",
-			req:     request{Link: "https://www.google.com"},
-			wantErr: `{"code":"bad_request","message":"password is missing"}`,
+			name:    "link must be an URL",
+			req:     request{Link: "www.google.com", Password: "1234"},
+			wantErr: `{"code":"invalid_request","message":"link must be an absolute http or https URL","fields":[{"field":"link","code":"url","message":"link must be an absolute http or https URL"}]}`,
 		},
 	}
 
@@ -98,7 +132,7 @@ func TestLink_Create_RequiredFields(t *testing.T) {
 			l := link.Link{ID: 1}
 
 			svcMock := &linkServiceMock{}
-			svcMock.On("Create", req.Context(), tc.req.Link, tc.req.Password).Return(l, nil)
+			svcMock.On("Create", mock.Anything, link.NewLink{URL: tc.req.Link, Password: tc.req.Password}).Return(l, nil)
 
 			linkHandler := handler.NewLink(svcMock)
 
@@ -125,7 +159,7 @@ func TestLink_Create_RequiredFields(t *testing.T) {
 	l := link.Link{ID: 1}
 
 	svcMock := &linkServiceMock{}
-	svcMock.On("Create", req.Context(), r.Link, r.Password).Return(l, nil)
+	svcMock.On("Create", mock.Anything, link.NewLink{URL: r.Link, Password: r.Password}).Return(l, nil)
 
 	linkHandler := handler.NewLink(svcMock)
 
@@ -136,3 +170,528 @@ func TestLink_Create_RequiredFields(t *testing.T) {
 	require.Equal(t, http.StatusCreated, rr.Code)
 	require.JSONEq(t, `{"id":1}`, rr.Body.String())
 }
+
+func TestLink_Redirect_Bot(t *testing.T) {
+	tt := []struct {
+		name       string
+		opts       []handler.LinkOption
+		userAgent  string
+		wantBot    bool
+		wantStatus int
+	}{
+		{
+			name:       "person",
+			opts:       []handler.LinkOption{handler.WithBotClassifier(bot.NewDefaultClassifier()), handler.WithBotPreview()},
+			userAgent:  "Mozilla/5.0",
+			wantStatus: http.StatusMovedPermanently,
+		},
+		{
+			name:       "bot redirected",
+			opts:       []handler.LinkOption{handler.WithBotClassifier(bot.NewDefaultClassifier())},
+			userAgent:  "Twitterbot/1.0",
+			wantBot:    true,
+			wantStatus: http.StatusMovedPermanently,
+		},
+		{
+			name:       "bot served a preview",
+			opts:       []handler.LinkOption{handler.WithBotClassifier(bot.NewDefaultClassifier()), handler.WithBotPreview()},
+			userAgent:  "Twitterbot/1.0",
+			wantBot:    true,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			req := httptest.NewRequest(http.MethodGet, "/link/1?password=1234", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			rr := httptest.NewRecorder()
+
+			svcMock := &linkServiceMock{}
+			svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1, URL: "https://www.google.com"}, nil)
+			svcMock.On("Redirect", mock.Anything, 1, mock.MatchedBy(func(v link.Visit) bool {
+				return v.Password == "1234" && v.Bot == tc.wantBot && v.UserAgent == tc.userAgent
+			})).Return(link.Link{ID: 1, URL: "https://www.google.com"}, nil)
+
+			app := web.New()
+			app.Method(http.MethodGet, "/link/{id}", handler.NewLink(svcMock, tc.opts...).Redirect())
+
+			// When
+			app.ServeHTTP(rr, req)
+
+			// Then
+			require.Equal(t, tc.wantStatus, rr.Code)
+			if tc.wantStatus == http.StatusOK {
+				require.Contains(t, rr.Body.String(), `<meta property="og:url" content="https://www.google.com">`)
+			} else {
+				require.Equal(t, "https://www.google.com", rr.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestLink_Redirect_BotPreview(t *testing.T) {
+	metadata := link.Metadata{Title: "Google", Description: "Search the world's information", Image: "https://www.google.com/logo.png"}
+
+	tt := []struct {
+		name      string
+		link      link.Link
+		wantTags  []string
+		wantNoTag string
+	}{
+		{
+			name: "title of the owner",
+			link: link.Link{ID: 1, URL: "https://www.google.com", Title: "Our search engine", Metadata: metadata},
+			wantTags: []string{
+				`<meta property="og:url" content="https://www.google.com">`,
+				`<meta property="og:title" content="Our search engine">`,
+				`<meta property="og:description" content="Search the world&#39;s information">`,
+				`<meta property="og:image" content="https://www.google.com/logo.png">`,
+				`<meta name="twitter:card" content="summary_large_image">`,
+				`<meta name="twitter:title" content="Our search engine">`,
+			},
+		},
+		{
+			name: "title of the destination",
+			link: link.Link{ID: 1, URL: "https://www.google.com", Metadata: metadata},
+			wantTags: []string{
+				`<meta property="og:title" content="Google">`,
+				`<title>Google</title>`,
+			},
+		},
+		{
+			name: "no title",
+			link: link.Link{ID: 1, URL: "https://www.google.com"},
+			wantTags: []string{
+				`<meta property="og:title" content="https://www.google.com">`,
+				`<meta name="twitter:card" content="summary">`,
+			},
+			wantNoTag: "og:image",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			req := httptest.NewRequest(http.MethodGet, "/link/1", nil)
+			req.Header.Set("User-Agent", "Twitterbot/1.0")
+			rr := httptest.NewRecorder()
+
+			svcMock := &linkServiceMock{}
+			svcMock.On("FindByID", mock.Anything, 1).Return(tc.link, nil)
+			svcMock.On("Redirect", mock.Anything, 1, mock.Anything).Return(tc.link, nil)
+
+			opts := []handler.LinkOption{handler.WithBotClassifier(bot.NewDefaultClassifier()), handler.WithBotPreview()}
+			app := web.New()
+			app.Method(http.MethodGet, "/link/{id}", handler.NewLink(svcMock, opts...).Redirect())
+
+			// When
+			app.ServeHTTP(rr, req)
+
+			// Then
+			require.Equal(t, http.StatusOK, rr.Code)
+			for _, tag := range tc.wantTags {
+				require.Contains(t, rr.Body.String(), tag)
+			}
+			if tc.wantNoTag != "" {
+				require.NotContains(t, rr.Body.String(), tc.wantNoTag)
+			}
+		})
+	}
+}
+
+func TestLink_Redirect_Interstitial(t *testing.T) {
+	tt := []struct {
+		name         string
+		target       string
+		interstitial bool
+		wantPreview  bool
+	}{
+		{name: "plus sign", target: "/link/1+?password=1234", wantPreview: true},
+		{name: "preview query parameter", target: "/link/1?password=1234&preview=1", wantPreview: true},
+		{name: "always interstitial", target: "/link/1?password=1234", interstitial: true, wantPreview: true},
+		{name: "always interstitial confirmed", target: "/link/1?password=1234&confirm=1", interstitial: true},
+		{name: "direct", target: "/link/1?password=1234"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			l := link.Link{ID: 1, URL: "https://www.google.com", Title: "Search <engine>", Interstitial: tc.interstitial}
+			svcMock := &linkServiceMock{}
+			svcMock.On("FindByID", mock.Anything, 1).Return(l, nil)
+			svcMock.On("Preview", mock.Anything, 1, mock.Anything).Return(l, nil)
+			svcMock.On("Redirect", mock.Anything, 1, mock.Anything).Return(l, nil)
+
+			app := web.New()
+			app.Method(http.MethodGet, "/link/{id}", handler.NewLink(svcMock, handler.WithOwnDomains("example.com")).Redirect())
+			rr := httptest.NewRecorder()
+
+			// When
+			app.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+			// Then
+			if !tc.wantPreview {
+				require.Equal(t, http.StatusMovedPermanently, rr.Code)
+				svcMock.AssertNotCalled(t, "Preview", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			require.Equal(t, http.StatusOK, rr.Code)
+			svcMock.AssertNotCalled(t, "Redirect", mock.Anything, mock.Anything, mock.Anything)
+
+			body := rr.Body.String()
+			require.Contains(t, body, "<h1>Search &lt;engine&gt;</h1>")
+			require.Contains(t, body, `<p class="destination">https://www.google.com</p>`)
+			require.Contains(t, body, "outside of our domains")
+			require.Contains(t, body, `href="/link/1?confirm=1&amp;password=1234"`)
+		})
+	}
+}
+
+func TestLink_List(t *testing.T) {
+	// Given
+	req := httptest.NewRequest(http.MethodGet, "/link?owner=alice", nil)
+	rr := httptest.NewRecorder()
+	links := []link.Link{
+		{ID: 1, URL: "https://www.google.com", Owner: "alice", Count: 3},
+		{
+			ID:    2,
+			URL:   "https://golang.org",
+			Owner: "alice",
+			Metadata: link.Metadata{
+				Title:     "The Go Programming Language",
+				Image:     "https://golang.org/gopher.png",
+				FetchedAt: time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	svcMock := &linkServiceMock{}
+	svcMock.On("List", mock.Anything, "alice").Return(links, nil)
+
+	linkHandler := handler.NewLink(svcMock)
+
+	// When
+	linkHandler.List().ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.JSONEq(t, `[
+		{"id":1,"url":"https://www.google.com","owner":"alice","count":3,"inactive":false},
+		{"id":2,"url":"https://golang.org","owner":"alice","count":0,"inactive":false,"metadata":{
+			"title":"The Go Programming Language","image":"https://golang.org/gopher.png","fetched_at":"2021-06-01T12:00:00Z"}}
+	]`, rr.Body.String())
+}
+
+func TestLink_Redirect_Fallback(t *testing.T) {
+	tt := []struct {
+		name         string
+		failing      bool
+		wantLocation string
+	}{
+		{
+			name:         "failing",
+			failing:      true,
+			wantLocation: "https://www.bing.com",
+		},
+		{
+			name:         "recovered",
+			wantLocation: "https://www.google.com",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			req := httptest.NewRequest(http.MethodGet, "/link/1?password=1234", nil)
+			rr := httptest.NewRecorder()
+			l := link.Link{
+				ID:          1,
+				URL:         "https://www.google.com",
+				FallbackURL: "https://www.bing.com",
+				Health:      link.Health{Failing: tc.failing},
+			}
+
+			svcMock := &linkServiceMock{}
+			svcMock.On("FindByID", mock.Anything, 1).Return(l, nil)
+			svcMock.On("Redirect", mock.Anything, 1, mock.Anything).Return(l, nil)
+
+			app := web.New()
+			app.Method(http.MethodGet, "/link/{id}", handler.NewLink(svcMock).Redirect())
+
+			// When
+			app.ServeHTTP(rr, req)
+
+			// Then
+			require.Equal(t, http.StatusFound, rr.Code)
+			require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+			require.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+		})
+	}
+}
+
+func TestLink_Create_Schedule(t *testing.T) {
+	tt := []struct {
+		name       string
+		schedule   string
+		wantStatus int
+	}{
+		{
+			name:       "business hours",
+			schedule:   `{"activate_at":"2021-06-01T10:00:00Z","time_zone":"Europe/Madrid","windows":[{"days":["mon","friday"],"start":"09:00","end":"18:00"}]}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "unknown time zone",
+			schedule:   `{"time_zone":"Mars/Olympus_Mons"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown day",
+			schedule:   `{"windows":[{"days":["someday"],"start":"09:00","end":"18:00"}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid time of day",
+			schedule:   `{"windows":[{"start":"9am","end":"18:00"}]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			body := `{"link":"https://www.google.com","password":"1234","schedule":` + tc.schedule + `}`
+			req := httptest.NewRequest(http.MethodPost, "/link", bytes.NewReader([]byte(body)))
+			rr := httptest.NewRecorder()
+
+			svcMock := &linkServiceMock{}
+			svcMock.On("Create", mock.Anything, mock.MatchedBy(func(nl link.NewLink) bool {
+				w := nl.Schedule.Windows
+				return len(w) == 1 && w[0].Start == 9*time.Hour && w[0].End == 18*time.Hour &&
+					len(w[0].Days) == 2 && w[0].Days[0] == time.Monday && w[0].Days[1] == time.Friday &&
+					nl.Schedule.Location.String() == "Europe/Madrid"
+			})).Return(link.Link{ID: 1}, nil)
+
+			// When
+			handler.NewLink(svcMock).Create().ServeHTTP(rr, req)
+
+			// Then
+			require.Equal(t, tc.wantStatus, rr.Code, rr.Body.String())
+		})
+	}
+}
+
+func TestLink_Redirect_Closed(t *testing.T) {
+	tt := []struct {
+		name         string
+		err          error
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "closed url",
+			err:          &link.ClosedError{URL: "https://www.google.com/soon"},
+			wantStatus:   http.StatusFound,
+			wantLocation: "https://www.google.com/soon",
+		},
+		{
+			name:       "closed message",
+			err:        &link.ClosedError{Message: "The sale starts on Monday"},
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			req := httptest.NewRequest(http.MethodGet, "/link/1?password=1234", nil)
+			rr := httptest.NewRecorder()
+
+			svcMock := &linkServiceMock{}
+			svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1}, nil)
+			svcMock.On("Redirect", mock.Anything, 1, mock.Anything).Return(link.Link{}, tc.err)
+
+			app := web.New()
+			app.Method(http.MethodGet, "/link/{id}", handler.NewLink(svcMock).Redirect())
+
+			// When
+			app.ServeHTTP(rr, req)
+
+			// Then
+			require.Equal(t, tc.wantStatus, rr.Code)
+			require.Equal(t, tc.wantLocation, rr.Header().Get("Location"))
+			if tc.wantStatus == http.StatusForbidden {
+				require.JSONEq(t, `{"code":"link_closed","message":"The sale starts on Monday"}`, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestLink_Metrics_Secret(t *testing.T) {
+	// Given
+	req := httptest.NewRequest(http.MethodGet, "/link/1/metrics", nil)
+	rr := httptest.NewRecorder()
+	consumedAt := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	svcMock := &linkServiceMock{}
+	svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{
+		ID:         1,
+		URL:        "https://www.google.com/reset?token=secret",
+		Count:      1,
+		MaxVisits:  1,
+		Inactive:   true,
+		ConsumedAt: consumedAt,
+	}, nil)
+
+	app := web.New()
+	app.Method(http.MethodGet, "/link/{id}/metrics", handler.NewLink(svcMock).Metrics())
+
+	// When
+	app.ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.NotContains(t, rr.Body.String(), "token=secret")
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.EqualValues(t, 1, resp["max_visits"])
+	require.EqualValues(t, 0, resp["remaining_visits"])
+	require.Equal(t, "2021-06-01T10:00:00Z", resp["consumed_at"])
+}
+
+func TestLink_Redirect_Secret(t *testing.T) {
+	// Given
+	req := httptest.NewRequest(http.MethodGet, "/link/1?password=1234", nil)
+	rr := httptest.NewRecorder()
+	l := link.Link{ID: 1, URL: "https://www.google.com/reset?token=secret", MaxVisits: 1, Count: 1, Inactive: true}
+
+	svcMock := &linkServiceMock{}
+	svcMock.On("FindByID", mock.Anything, 1).Return(l, nil)
+	svcMock.On("Redirect", mock.Anything, 1, mock.Anything).Return(l, nil)
+
+	app := web.New()
+	app.Method(http.MethodGet, "/link/{id}", handler.NewLink(svcMock).Redirect())
+
+	// When
+	app.ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusFound, rr.Code)
+	require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	require.Equal(t, l.URL, rr.Header().Get("Location"))
+}
+
+func TestLink_Redirect_Scheduled(t *testing.T) {
+	// Given
+	req := httptest.NewRequest(http.MethodGet, "/link/1?password=1234", nil)
+	rr := httptest.NewRecorder()
+	l := link.Link{
+		ID:       1,
+		URL:      "https://www.google.com",
+		Schedule: link.Schedule{DeactivateAt: time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)},
+	}
+
+	svcMock := &linkServiceMock{}
+	svcMock.On("FindByID", mock.Anything, 1).Return(l, nil)
+	svcMock.On("Redirect", mock.Anything, 1, mock.Anything).Return(l, nil)
+
+	app := web.New()
+	app.Method(http.MethodGet, "/link/{id}", handler.NewLink(svcMock).Redirect())
+
+	// When
+	app.ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusFound, rr.Code)
+	require.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	require.Equal(t, l.URL, rr.Header().Get("Location"))
+}
+
+func TestByLinkID(t *testing.T) {
+	// Given
+	limiter := web.NewRateLimiter(web.NewMemoryRateStore())
+	limiter.Limit(http.MethodGet, "/link/{id}",
+		web.Policy{Name: "visits per link", Key: handler.ByLinkID(), Limit: web.Limit{Requests: 1, Per: time.Hour}})
+
+	app := web.New()
+	app.RateLimit(limiter)
+	app.Method(http.MethodGet, "/link/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	// When
+	first := httptest.NewRecorder()
+	app.ServeHTTP(first, httptest.NewRequest(http.MethodGet, "/link/01", nil))
+	second := httptest.NewRecorder()
+	app.ServeHTTP(second, httptest.NewRequest(http.MethodGet, "/link/1", nil))
+	other := httptest.NewRecorder()
+	app.ServeHTTP(other, httptest.NewRequest(http.MethodGet, "/link/2", nil))
+
+	// Then
+	require.Equal(t, http.StatusNoContent, first.Code)
+	require.Equal(t, http.StatusTooManyRequests, second.Code, "/link/01 and /link/1 must share the limit")
+	require.Equal(t, http.StatusNoContent, other.Code)
+}
+
+func TestLink_Create_AccessCodes(t *testing.T) {
+	// Given
+	body := `{"link":"https://www.google.com","codes":[{"name":"acme","password":"1234","expires_at":"2021-07-01T00:00:00Z"},{"name":"globex","password":"5678"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/link", bytes.NewReader([]byte(body)))
+	rr := httptest.NewRecorder()
+
+	svcMock := &linkServiceMock{}
+	svcMock.On("Create", mock.Anything, link.NewLink{
+		URL: "https://www.google.com",
+		Codes: []link.NewAccessCode{
+			{Name: "acme", Password: "1234", Expires: time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)},
+			{Name: "globex", Password: "5678"},
+		},
+	}).Return(link.Link{ID: 1}, nil)
+
+	// When
+	handler.NewLink(svcMock).Create().ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+}
+
+func TestLink_Metrics_AccessCodes(t *testing.T) {
+	// Given
+	req := httptest.NewRequest(http.MethodGet, "/link/1/metrics", nil)
+	rr := httptest.NewRecorder()
+	lastUsed := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	svcMock := &linkServiceMock{}
+	svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{
+		ID:    1,
+		URL:   "https://www.google.com",
+		Count: 3,
+		Codes: []link.AccessCode{
+			{Name: "acme", Hash: []byte("hash"), Count: 3, LastUsedAt: lastUsed},
+			{Name: "globex", Hash: []byte("hash")},
+		},
+	}, nil)
+
+	app := web.New()
+	app.Method(http.MethodGet, "/link/{id}/metrics", handler.NewLink(svcMock).Metrics())
+
+	// When
+	app.ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp struct {
+		Public bool              `json:"public"`
+		Codes  []json.RawMessage `json:"codes"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	require.False(t, resp.Public)
+	require.Len(t, resp.Codes, 2)
+	require.JSONEq(t, `{"name":"acme","count":3,"last_used_at":"2021-06-01T10:00:00Z"}`, string(resp.Codes[0]))
+	require.JSONEq(t, `{"name":"globex","count":0}`, string(resp.Codes[1]))
+}
@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+)
+
+// previewTemplate is served to bots instead of a redirect. It only exposes the destination
+// of the link and what is known about it, so chat apps can unfurl it without counting a visit.
+var previewTemplate = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta property="og:type" content="website">
+<meta property="og:url" content="{{.URL}}">
+<meta property="og:title" content="{{.Title}}">
+{{- if .Description}}
+<meta property="og:description" content="{{.Description}}">
+{{- end}}
+{{- if .Image}}
+<meta property="og:image" content="{{.Image}}">
+<meta name="twitter:card" content="summary_large_image">
+{{- else}}
+<meta name="twitter:card" content="summary">
+{{- end}}
+<meta name="twitter:title" content="{{.Title}}">
+{{- if .Description}}
+<meta name="twitter:description" content="{{.Description}}">
+{{- end}}
+{{- if .Image}}
+<meta name="twitter:image" content="{{.Image}}">
+{{- end}}
+<link rel="canonical" href="{{.URL}}">
+</head>
+<body>
+<a href="{{.URL}}">{{.URL}}</a>
+</body>
+</html>
+`))
+
+// renderPreview writes the Open Graph page of l. Its title is the one given by the owner, or else the one
+// fetched from the destination, or else the destination itself.
+func renderPreview(w http.ResponseWriter, req *http.Request, l link.Link) error {
+	data := struct {
+		Title       string
+		Description string
+		Image       string
+		URL         string
+	}{
+		Title:       l.Title,
+		Description: l.Metadata.Description,
+		Image:       l.Metadata.Image,
+		URL:         l.Destination(),
+	}
+
+	if data.Title == "" {
+		data.Title = l.Metadata.Title
+	}
+	if data.Title == "" {
+		data.Title = data.URL
+	}
+
+	var buf bytes.Buffer
+	if err := previewTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+	if req.Method == http.MethodHead {
+		return nil
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// interstitialTemplate shows the visitors where a link is going to take them before redirecting them.
+var interstitialTemplate = template.Must(template.New("interstitial").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{if .Title}}{{.Title}}{{else}}{{.URL}}{{end}}</title>
+<style>
+body { font-family: sans-serif; max-width: 40em; margin: 4em auto; padding: 0 1em; color: #222; }
+.destination { word-break: break-all; font-family: monospace; background: #f4f4f4; padding: .5em; }
+.warning { color: #8a4b00; }
+.continue { display: inline-block; margin-top: 1em; padding: .6em 1.2em; background: #1a5fb4; color: #fff; text-decoration: none; border-radius: 4px; }
+</style>
+</head>
+<body>
+{{if .Title}}<h1>{{.Title}}</h1>{{end}}
+<p>This link will take you to:</p>
+<p class="destination">{{.URL}}</p>
+{{if .External}}<p class="warning">The destination is outside of our domains. Make sure you trust it before continuing.</p>{{end}}
+<a class="continue" href="{{.ContinueURL}}" rel="noreferrer">Continue</a>
+</body>
+</html>
+`))
+
+// renderInterstitial writes the interstitial page of l. The continue button points back to the
+// requested link with the confirm query parameter, so the next request is redirected.
+func renderInterstitial(w http.ResponseWriter, req *http.Request, l link.Link, password string, external bool) error {
+	query := req.URL.Query()
+	query.Del("preview")
+	if password != "" {
+		query.Set("password", password)
+	}
+	query.Set("confirm", "1")
+
+	continueURL := *req.URL
+	continueURL.Path = strings.TrimSuffix(continueURL.Path, "+")
+	continueURL.RawPath = ""
+	continueURL.RawQuery = query.Encode()
+
+	data := struct {
+		Title       string
+		URL         string
+		External    bool
+		ContinueURL string
+	}{
+		Title:       l.Title,
+		URL:         l.Destination(),
+		External:    external,
+		ContinueURL: continueURL.RequestURI(),
+	}
+
+	var buf bytes.Buffer
+	if err := interstitialTemplate.Execute(&buf, data); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
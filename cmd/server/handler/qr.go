@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/qr"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+)
+
+const (
+	defaultQRSize   = 256
+	minQRSize       = 64
+	maxQRSize       = 2048
+	defaultQRMargin = 4
+	maxQRMargin     = 16
+)
+
+type QR struct {
+	linkService link.Service
+	publicURL   string
+}
+
+// NewQR creates a handler that renders QR codes of the short URL of the links,
+// built from publicURL, the address where the server is reachable by the users.
+func NewQR(l link.Service, publicURL string) *QR {
+	return &QR{
+		linkService: l,
+		publicURL:   strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+func (q *QR) Get() web.Handler {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		id, err := extractID(req)
+		if err != nil {
+			return err
+		}
+
+		query := req.URL.Query()
+
+		format := query.Get("format")
+		if format == "" {
+			format = "png"
+		}
+
+		var contentType string
+		switch format {
+		case "png":
+			contentType = "image/png"
+		case "svg":
+			contentType = "image/svg+xml"
+		default:
+			return web.NewErrorf(http.StatusBadRequest, "unsupported format %q", format)
+		}
+
+		opts := qr.Options{
+			Size:   defaultQRSize,
+			Level:  qr.LevelM,
+			Margin: defaultQRMargin,
+		}
+
+		if s := query.Get("size"); s != "" {
+			opts.Size, err = strconv.Atoi(s)
+			if err != nil || opts.Size < minQRSize || opts.Size > maxQRSize {
+				return web.NewErrorf(http.StatusBadRequest, "size must be between %d and %d", minQRSize, maxQRSize)
+			}
+		}
+
+		if s := query.Get("margin"); s != "" {
+			opts.Margin, err = strconv.Atoi(s)
+			if err != nil || opts.Margin < 0 || opts.Margin > maxQRMargin {
+				return web.NewErrorf(http.StatusBadRequest, "margin must be between 0 and %d", maxQRMargin)
+			}
+		}
+
+		if s := query.Get("level"); s != "" {
+			opts.Level, err = qr.ParseLevel(s)
+			if err != nil {
+				return web.NewError(http.StatusBadRequest, err.Error())
+			}
+		}
+
+		if _, err := q.linkService.FindByID(req.Context(), id); err != nil {
+			return serviceError(err)
+		}
+
+		content := fmt.Sprintf("%s/link/%d", q.publicURL, id)
+
+		// The image only depends on its content and options, so the clients can cache it for a day and then
+		// revalidate it with its ETag. Not longer, because the content changes with the public URL of the server.
+		sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%d|%s|%d", content, format, opts.Size, opts.Level, opts.Margin)))
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+
+		if etagMatches(req.Header.Get("If-None-Match"), etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+
+		var img []byte
+		if format == "svg" {
+			img, err = qr.SVG(content, opts)
+		} else {
+			img, err = qr.PNG(content, opts)
+		}
+
+		if err != nil {
+			return serviceError(err)
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Length", strconv.Itoa(len(img)))
+		w.WriteHeader(http.StatusOK)
+		_, err = w.Write(img)
+		return err
+	}
+}
+
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+
+	return false
+}
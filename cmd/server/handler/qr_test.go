@@ -0,0 +1,110 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newQRApp(svc link.Service) *web.Application {
+	app := web.New()
+	app.Method(http.MethodGet, "/link/{id}/qr", handler.NewQR(svc, "https://lnk.example.com/").Get())
+	return app
+}
+
+func TestQR_Get(t *testing.T) {
+	tt := []struct {
+		name        string
+		target      string
+		contentType string
+	}{
+		{name: "png by default", target: "/link/1/qr", contentType: "image/png"},
+		{name: "svg", target: "/link/1/qr?format=svg&size=512&level=H&margin=0", contentType: "image/svg+xml"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			svcMock := &linkServiceMock{}
+			svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1}, nil)
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			rr := httptest.NewRecorder()
+
+			// When
+			newQRApp(svcMock).ServeHTTP(rr, req)
+
+			// Then
+			require.Equal(t, http.StatusOK, rr.Code)
+			require.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+			require.NotEmpty(t, rr.Header().Get("ETag"))
+			require.Equal(t, "public, max-age=86400", rr.Header().Get("Cache-Control"))
+			require.NotZero(t, rr.Body.Len())
+		})
+	}
+}
+
+func TestQR_Get_NotModified(t *testing.T) {
+	// Given
+	svcMock := &linkServiceMock{}
+	svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1}, nil)
+	app := newQRApp(svcMock)
+
+	rr := httptest.NewRecorder()
+	app.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/link/1/qr?size=128", nil))
+	etag := rr.Header().Get("ETag")
+
+	req := httptest.NewRequest(http.MethodGet, "/link/1/qr?size=128", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+
+	// When
+	app.ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusNotModified, rr.Code)
+	require.Zero(t, rr.Body.Len())
+
+	// A different size is a different image.
+	req = httptest.NewRequest(http.MethodGet, "/link/1/qr?size=256", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr = httptest.NewRecorder()
+	app.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestQR_Get_Errors(t *testing.T) {
+	tt := []struct {
+		name     string
+		target   string
+		wantCode int
+	}{
+		{name: "link not found", target: "/link/2/qr", wantCode: http.StatusNotFound},
+		{name: "unsupported format", target: "/link/1/qr?format=gif", wantCode: http.StatusBadRequest},
+		{name: "size too big", target: "/link/1/qr?size=10000", wantCode: http.StatusBadRequest},
+		{name: "negative margin", target: "/link/1/qr?margin=-1", wantCode: http.StatusBadRequest},
+		{name: "unknown level", target: "/link/1/qr?level=Z", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			svcMock := &linkServiceMock{}
+			svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1}, nil)
+			svcMock.On("FindByID", mock.Anything, 2).Return(link.Link{}, link.ErrNotFound)
+			rr := httptest.NewRecorder()
+
+			// When
+			newQRApp(svcMock).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+			// Then
+			require.Equal(t, tc.wantCode, rr.Code)
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+)
+
+// API holds the handlers of every route of the server.
+type API struct {
+	Link    *Link
+	QR      *QR
+	Click   *Click
+	Webhook *Webhook
+	// Info describes the API in its OpenAPI document.
+	Info web.Info
+	// Sunset is when the unversioned routes will be removed, unknown if zero.
+	Sunset time.Time
+}
+
+// Versions of the API. Version 2 identifies the links with strings instead of numbers.
+const (
+	V1 = "v1"
+	V2 = "v2"
+)
+
+var (
+	badRequest        = web.ErrorDoc{Status: http.StatusBadRequest, Code: "bad_request"}
+	linkNotFound      = web.ErrorDoc{Status: http.StatusNotFound, Code: CodeLinkNotFound}
+	webhookNotFound   = web.ErrorDoc{Status: http.StatusNotFound, Code: CodeWebhookNotFound}
+	invalidLink       = web.ErrorDoc{Status: http.StatusBadRequest, Code: CodeInvalidLink}
+	passwordRequired  = web.ErrorDoc{Status: http.StatusUnauthorized, Code: CodePasswordRequired}
+	invalidPassword   = web.ErrorDoc{Status: http.StatusUnauthorized, Code: CodeInvalidPassword}
+	linkInactive      = web.ErrorDoc{Status: http.StatusUnprocessableEntity, Code: CodeLinkInactive}
+	linkClosed        = web.ErrorDoc{Status: http.StatusForbidden, Code: CodeLinkClosed}
+	secretLink        = web.ErrorDoc{Status: http.StatusForbidden, Code: CodeSecretLink}
+	accessDisabled    = web.ErrorDoc{Status: http.StatusNotImplemented, Code: CodeAccessDisabled}
+	invalidAccess     = web.ErrorDoc{Status: http.StatusBadRequest, Code: CodeInvalidAccess}
+	passwordQuery     = web.QueryParam{Name: "password", Description: "The password of the link."}
+	ownerQuery        = web.QueryParam{Name: "owner", Description: "Only the links of this owner."}
+	linkTags          = []string{"links"}
+	eventTags         = []string{"events"}
+	webhookTags       = []string{"webhooks"}
+	serverTags        = []string{"server"}
+	visitErrors       = []web.ErrorDoc{badRequest, linkNotFound, passwordRequired, invalidPassword, linkInactive, linkClosed, secretLink, invalidAccess}
+	eventStreamFormat = "Each event is a JSON object with the link_id, url, count and time of a click."
+)
+
+// Register adds the routes of the server, with their documentation, to app. The routes of the API are
+// mounted under /v1 and /v2, and the ones of version 1 are kept without prefix, deprecated, for the
+// clients that predate the versions. The OpenAPI document of the routes is served at /openapi.json
+// and rendered at /docs.
+func (api API) Register(app *web.Application) {
+	v1 := app.Version(V1)
+	legacy := v1.Alias("", web.Deprecation{Sunset: api.Sunset, Successor: "/" + V1})
+	for _, v := range []*web.Version{legacy, v1, app.Version(V2)} {
+		api.register(v)
+	}
+
+	// The links are shared and printed in QR codes, so they are never versioned.
+	for _, method := range []string{"GET", "HEAD"} {
+		app.Method(method, "/link/{id}", api.Link.Redirect(), web.Doc{
+			Summary: "Visit a link",
+			Description: "Redirects to the destination of the link and counts the visit. The interstitial page is " +
+				"shown instead when the id is followed by a plus sign, when preview is 1, or when the link requires it.",
+			Tags: linkTags,
+			Query: []web.QueryParam{
+				passwordQuery,
+				{Name: "preview", Description: "1 to show the interstitial page."},
+				{Name: "confirm", Description: "1 to skip the interstitial page the link requires."},
+				{Name: "exp", Description: "The expiration of a signed URL, in seconds since the epoch."},
+				{Name: "kid", Description: "The key that signed the URL."},
+				{Name: "ip", Description: "The IP range a signed URL is restricted to."},
+				{Name: "sig", Description: "The signature of a signed URL."},
+			},
+			Status: http.StatusMovedPermanently,
+			Errors: visitErrors,
+		})
+	}
+
+	app.Method("GET", "/openapi.json", app.OpenAPIHandler(api.Info), web.Doc{
+		Summary:     "Get the OpenAPI document of the API",
+		Tags:        serverTags,
+		ContentType: "application/json",
+	})
+	app.Method("GET", "/docs", web.DocsHandler("/openapi.json"), web.Doc{
+		Summary:     "Read the documentation of the API",
+		Tags:        serverTags,
+		ContentType: "text/html",
+	})
+}
+
+// byVersion returns the type of the response of version 2, if v is, or of version 1 otherwise.
+func byVersion(v *web.Version, v1, v2 interface{}) interface{} {
+	if v.Name() == V2 {
+		return v2
+	}
+
+	return v1
+}
+
+// register adds the routes of the version v of the API.
+func (api API) register(v *web.Version) {
+	v.Method("POST", "/link", api.Link.Create(), web.Doc{
+		Summary:     "Create a link",
+		Description: "Shortens a URL, optionally protected by a password or access codes, scheduled, or limited to a number of visits.",
+		Tags:        linkTags,
+		Request:     createLinkRequest{},
+		Status:      http.StatusCreated,
+		Response:    byVersion(v, createLinkResponse{}, createLinkResponseV2{}),
+		Errors: []web.ErrorDoc{
+			invalidLink,
+			{Status: http.StatusBadRequest, Code: CodeInvalidSchedule},
+			{Status: http.StatusBadRequest, Code: CodeWeakPassword},
+			badRequest,
+		},
+	})
+	v.Method("GET", "/link", api.Link.List(), web.Doc{
+		Summary:  "List the links",
+		Tags:     linkTags,
+		Query:    []web.QueryParam{ownerQuery},
+		Response: byVersion(v, []linkResponse{}, []linkResponseV2{}),
+	})
+	v.Method("GET", "/link/{id}/metrics", api.Link.Metrics(), web.Doc{
+		Summary:  "Get the metrics of a link",
+		Tags:     linkTags,
+		Response: byVersion(v, metricsResponse{}, metricsResponseV2{}),
+		Errors:   []web.ErrorDoc{badRequest, linkNotFound},
+	})
+	v.Method("POST", "/link/{id}/inactivate", api.Link.Inactivate(), web.Doc{
+		Summary: "Inactivate a link",
+		Tags:    linkTags,
+		Errors:  []web.ErrorDoc{badRequest, linkNotFound},
+	})
+	v.Method("POST", "/link/{id}/access", api.Link.GrantAccess(), web.Doc{
+		Summary:  "Grant access to a link",
+		Tags:     linkTags,
+		Request:  grantAccessRequest{},
+		Status:   http.StatusCreated,
+		Response: accessResponse{},
+		Errors:   []web.ErrorDoc{badRequest, linkNotFound, passwordRequired, invalidPassword, invalidAccess, accessDisabled},
+	})
+	v.Method("GET", "/link/{id}/qr", api.QR.Get(), web.Doc{
+		Summary: "Get the QR code of a link",
+		Tags:    linkTags,
+		Query: []web.QueryParam{
+			{Name: "format", Description: "png or svg, png by default."},
+			{Name: "size", Description: "The size of the image in pixels."},
+			{Name: "margin", Description: "The margin of the image in modules."},
+			{Name: "level", Description: "The error correction level: L, M, Q or H."},
+		},
+		ContentType: "image/png",
+		Errors:      []web.ErrorDoc{badRequest, linkNotFound, {Status: http.StatusBadRequest, Code: CodeQRTooSmall}},
+	})
+
+	v.Method("GET", "/link/{id}/events", api.Click.LinkEvents(), web.Doc{
+		Summary:     "Stream the clicks on a link",
+		Description: eventStreamFormat,
+		Tags:        eventTags,
+		ContentType: "text/event-stream",
+		Errors:      []web.ErrorDoc{badRequest, linkNotFound},
+	})
+	v.Method("GET", "/owner/{owner}/events", api.Click.OwnerEvents(), web.Doc{
+		Summary:     "Stream the clicks on the links of an owner",
+		Description: eventStreamFormat,
+		Tags:        eventTags,
+		ContentType: "text/event-stream",
+		Errors:      []web.ErrorDoc{badRequest},
+	})
+
+	v.Method("POST", "/webhooks", api.Webhook.Create(), web.Doc{
+		Summary:  "Subscribe a webhook",
+		Tags:     webhookTags,
+		Request:  subscribeRequest{},
+		Status:   http.StatusCreated,
+		Response: subscriptionResponse{},
+		Errors:   []web.ErrorDoc{badRequest},
+	})
+	v.Method("GET", "/webhooks", api.Webhook.List(), web.Doc{
+		Summary:  "List the webhooks",
+		Tags:     webhookTags,
+		Response: []subscriptionResponse{},
+	})
+	v.Method("DELETE", "/webhooks/{id}", api.Webhook.Delete(), web.Doc{
+		Summary: "Unsubscribe a webhook",
+		Tags:    webhookTags,
+		Status:  http.StatusNoContent,
+		Errors:  []web.ErrorDoc{badRequest, webhookNotFound},
+	})
+	v.Method("GET", "/webhooks/{id}/deliveries", api.Webhook.Deliveries(), web.Doc{
+		Summary:  "List the deliveries of a webhook",
+		Tags:     webhookTags,
+		Response: []deliveryResponse{},
+		Errors:   []web.ErrorDoc{badRequest, webhookNotFound},
+	})
+	v.Method("GET", "/webhooks/dead-letters", api.Webhook.DeadLetters(), web.Doc{
+		Summary:  "List the deliveries that failed for good",
+		Tags:     webhookTags,
+		Response: []deadLetterResponse{},
+	})
+}
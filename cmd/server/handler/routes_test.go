@@ -0,0 +1,261 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func newAPI() *web.Application {
+	return newAPIWithLinks(nil)
+}
+
+func newAPIWithLinks(l link.Service) *web.Application {
+	api := handler.API{
+		Link:    handler.NewLink(l),
+		QR:      handler.NewQR(nil, "http://localhost:8080"),
+		Click:   handler.NewClick(nil, nil, time.Second),
+		Webhook: handler.NewWebhook(nil),
+		Info:    web.Info{Title: "Link Tracker", Version: "test"},
+		Sunset:  time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	app := web.New()
+	api.Register(app)
+	return app
+}
+
+func TestAPI_Register_EveryRouteIsDocumented(t *testing.T) {
+	// Given
+	app := newAPI()
+
+	// When
+	routes := app.Routes()
+
+	// Then
+	require.NotEmpty(t, routes)
+	for _, route := range routes {
+		require.NotNil(t, route.Doc, "%s %s is not documented", route.Method, route.Pattern)
+		require.NotEmpty(t, route.Doc.Summary, "%s %s has no summary", route.Method, route.Pattern)
+		require.NotEmpty(t, route.Doc.Tags, "%s %s has no tags", route.Method, route.Pattern)
+		require.False(t, route.Doc.Response != nil && route.Doc.ContentType != "",
+			"%s %s has both a response and a content type", route.Method, route.Pattern)
+	}
+}
+
+func TestAPI_Register_OpenAPI(t *testing.T) {
+	// Given
+	app := newAPI()
+	req := httptest.NewRequest(http.MethodGet, "/openapi.json", nil)
+	rec := httptest.NewRecorder()
+
+	// When
+	app.ServeHTTP(rec, req)
+
+	// Then
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+			Deprecated  bool   `json:"deprecated"`
+			RequestBody struct {
+				Content map[string]struct {
+					Schema struct {
+						Ref string `json:"$ref"`
+					} `json:"schema"`
+				} `json:"content"`
+			} `json:"requestBody"`
+			Responses map[string]struct {
+				Description string `json:"description"`
+			} `json:"responses"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string                          `json:"required"`
+				Properties map[string]map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&spec))
+
+	require.Equal(t, "3.0.3", spec.OpenAPI)
+	for _, route := range app.Routes() {
+		op, ok := spec.Paths[route.Pattern][strings.ToLower(route.Method)]
+		require.True(t, ok, "%s %s is missing from the document", route.Method, route.Pattern)
+		require.NotEmpty(t, op.OperationID)
+	}
+
+	create := spec.Paths["/link"]["post"]
+	require.Equal(t, "#/components/schemas/CreateLinkRequest", create.RequestBody.Content["application/x-www-form-urlencoded"].Schema.Ref)
+	require.Contains(t, create.Responses, "201")
+	require.Contains(t, create.Responses["400"].Description, "invalid_link")
+	require.Contains(t, create.Responses["400"].Description, "invalid_request")
+
+	request := spec.Components.Schemas["CreateLinkRequest"]
+	require.Equal(t, []string{"link"}, request.Required)
+	require.Equal(t, "uri", request.Properties["link"]["format"])
+	require.Equal(t, float64(200), request.Properties["title"]["maxLength"])
+	require.Contains(t, spec.Paths["/link/{id}"]["get"].Responses["404"].Description, handler.CodeLinkNotFound)
+
+	require.True(t, create.Deprecated)
+	require.False(t, spec.Paths["/v1/link"]["post"].Deprecated)
+	require.False(t, spec.Paths["/v2/link"]["post"].Deprecated)
+	require.False(t, spec.Paths["/link/{id}"]["get"].Deprecated)
+	require.Equal(t, map[string]interface{}{"type": "string"}, spec.Components.Schemas["CreateLinkResponseV2"].Properties["id"])
+	require.Equal(t, map[string]interface{}{"type": "string"}, spec.Components.Schemas["MetricsResponseV2"].Properties["id"])
+	require.Equal(t, map[string]interface{}{"type": "integer"}, spec.Components.Schemas["MetricsResponse"].Properties["id"])
+}
+
+func TestAPI_Register_Docs(t *testing.T) {
+	// Given
+	app := newAPI()
+	req := httptest.NewRequest(http.MethodGet, "/docs", nil)
+	rec := httptest.NewRecorder()
+
+	// When
+	app.ServeHTTP(rec, req)
+
+	// Then
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), `href="/openapi.json"`)
+}
+
+func TestAPI_Register_Versions(t *testing.T) {
+	tt := []struct {
+		name           string
+		method         string
+		target         string
+		body           string
+		wantStatus     int
+		wantBody       string
+		wantDeprecated bool
+		wantLink       string
+	}{
+		{
+			name:           "create without version",
+			method:         http.MethodPost,
+			target:         "/link",
+			body:           `{"link":"https://www.google.com"}`,
+			wantStatus:     http.StatusCreated,
+			wantBody:       `{"id":1}`,
+			wantDeprecated: true,
+			wantLink:       `</v1/link>; rel="successor-version"`,
+		},
+		{
+			name:       "create v1",
+			method:     http.MethodPost,
+			target:     "/v1/link",
+			body:       `{"link":"https://www.google.com"}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":1}`,
+		},
+		{
+			name:       "create v2",
+			method:     http.MethodPost,
+			target:     "/v2/link",
+			body:       `{"link":"https://www.google.com"}`,
+			wantStatus: http.StatusCreated,
+			wantBody:   `{"id":"1"}`,
+		},
+		{
+			name:       "list v2",
+			method:     http.MethodGet,
+			target:     "/v2/link?owner=alice",
+			wantStatus: http.StatusOK,
+			wantBody:   `[{"id":"1","url":"https://www.google.com","owner":"alice","count":3,"inactive":false}]`,
+		},
+		{
+			name:           "metrics without version",
+			method:         http.MethodGet,
+			target:         "/link/1/metrics",
+			wantStatus:     http.StatusOK,
+			wantBody:       `{"id":1,"url":"https://www.google.com","interstitial":false,"count":3,"preview_count":0,"bot_count":0,"unique_visitors":0,"daily_unique_visitors":{},"inactive":false,"health":{"consecutive_failures":0,"failing":false,"history":[]},"public":true}`,
+			wantDeprecated: true,
+			wantLink:       `</v1/link/1/metrics>; rel="successor-version"`,
+		},
+		{
+			name:       "metrics v2",
+			method:     http.MethodGet,
+			target:     "/v2/link/1/metrics",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"id":"1","url":"https://www.google.com","interstitial":false,"count":3,"preview_count":0,"bot_count":0,"unique_visitors":0,"daily_unique_visitors":{},"inactive":false,"health":{"consecutive_failures":0,"failing":false,"history":[]},"public":true}`,
+		},
+		{
+			name:       "not found v2",
+			method:     http.MethodGet,
+			target:     "/v2/link/2/metrics",
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"code":"link_not_found","message":"link not found"}`,
+		},
+		{
+			name:       "visit",
+			method:     http.MethodGet,
+			target:     "/link/1",
+			wantStatus: http.StatusMovedPermanently,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			svcMock := &linkServiceMock{}
+			svcMock.On("Create", mock.Anything, link.NewLink{URL: "https://www.google.com"}).Return(link.Link{ID: 1}, nil)
+			svcMock.On("List", mock.Anything, "alice").Return([]link.Link{{ID: 1, URL: "https://www.google.com", Owner: "alice", Count: 3}}, nil)
+			svcMock.On("FindByID", mock.Anything, 1).Return(link.Link{ID: 1, URL: "https://www.google.com", Count: 3}, nil)
+			svcMock.On("FindByID", mock.Anything, 2).Return(link.Link{}, link.ErrNotFound)
+			svcMock.On("Redirect", mock.Anything, 1, mock.Anything).Return(link.Link{ID: 1, URL: "https://www.google.com"}, nil)
+
+			app := newAPIWithLinks(svcMock)
+			req := httptest.NewRequest(tc.method, tc.target, strings.NewReader(tc.body))
+			rec := httptest.NewRecorder()
+
+			// When
+			app.ServeHTTP(rec, req)
+
+			// Then
+			require.Equal(t, tc.wantStatus, rec.Code)
+			if tc.wantBody != "" {
+				require.JSONEq(t, tc.wantBody, rec.Body.String())
+			}
+
+			if tc.wantDeprecated {
+				require.Equal(t, "true", rec.Header().Get("Deprecation"))
+				require.Equal(t, "Sat, 01 Jan 2022 00:00:00 GMT", rec.Header().Get("Sunset"))
+				require.Equal(t, tc.wantLink, rec.Header().Get("Link"))
+			} else {
+				require.Empty(t, rec.Header().Get("Deprecation"))
+				require.Empty(t, rec.Header().Get("Sunset"))
+			}
+		})
+	}
+}
+
+func TestAPI_Register_NotAcceptableBeforeCreating(t *testing.T) {
+	// Given
+	svcMock := &linkServiceMock{}
+	app := newAPIWithLinks(svcMock)
+	req := httptest.NewRequest(http.MethodPost, "/v1/link", strings.NewReader(`{"link":"https://www.google.com"}`))
+	req.Header.Set("Accept", "text/html")
+	rec := httptest.NewRecorder()
+
+	// When
+	app.ServeHTTP(rec, req)
+
+	// Then
+	require.Equal(t, http.StatusNotAcceptable, rec.Code)
+	require.JSONEq(t, `{"code":"not_acceptable","message":"none of the accepted media types \"text/html\" is supported"}`, rec.Body.String())
+	svcMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+)
+
+type windowRequest struct {
+	// Days are names of days of the week, like "monday" or "mon". Every day if empty.
+	Days []string `json:"days"`
+	// Start and End are times of the day in the format 15:04. End may be 24:00.
+	Start string `json:"start" validate:"required,regex=^\\d{1,2}:\\d{2}$"`
+	End   string `json:"end" validate:"required,regex=^\\d{1,2}:\\d{2}$"`
+}
+
+type scheduleRequest struct {
+	ActivateAt    *time.Time      `json:"activate_at"`
+	DeactivateAt  *time.Time      `json:"deactivate_at"`
+	Windows       []windowRequest `json:"windows"`
+	TimeZone      string          `json:"time_zone"`
+	ClosedMessage string          `json:"closed_message"`
+	ClosedURL     string          `json:"closed_url" validate:"url"`
+}
+
+func (r scheduleRequest) toSchedule() (link.Schedule, error) {
+	s := link.Schedule{
+		ClosedMessage: r.ClosedMessage,
+		ClosedURL:     r.ClosedURL,
+	}
+
+	if r.ActivateAt != nil {
+		s.ActivateAt = *r.ActivateAt
+	}
+
+	if r.DeactivateAt != nil {
+		s.DeactivateAt = *r.DeactivateAt
+	}
+
+	if r.TimeZone != "" {
+		loc, err := time.LoadLocation(r.TimeZone)
+		if err != nil {
+			return link.Schedule{}, fmt.Errorf("unknown time zone %q", r.TimeZone)
+		}
+
+		s.Location = loc
+	}
+
+	for _, wr := range r.Windows {
+		var w link.Window
+		for _, d := range wr.Days {
+			day, ok := weekdays[strings.ToLower(d)]
+			if !ok {
+				return link.Schedule{}, fmt.Errorf("unknown day %q", d)
+			}
+
+			w.Days = append(w.Days, day)
+		}
+
+		var err error
+		if w.Start, err = parseTimeOfDay(wr.Start); err != nil {
+			return link.Schedule{}, err
+		}
+
+		if w.End, err = parseTimeOfDay(wr.End); err != nil {
+			return link.Schedule{}, err
+		}
+
+		s.Windows = append(s.Windows, w)
+	}
+
+	return s, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// parseTimeOfDay parses a time in the format 15:04 as an offset from midnight.
+func parseTimeOfDay(s string) (time.Duration, error) {
+	if s == "24:00" {
+		return 24 * time.Hour, nil
+	}
+
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day %q, expected HH:MM", s)
+	}
+
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+type transitionResponse struct {
+	Time   time.Time `json:"time"`
+	Closed bool      `json:"closed"`
+}
+
+type scheduleResponse struct {
+	ActivateAt    *time.Time           `json:"activate_at,omitempty"`
+	DeactivateAt  *time.Time           `json:"deactivate_at,omitempty"`
+	Windows       []windowRequest      `json:"windows,omitempty"`
+	TimeZone      string               `json:"time_zone,omitempty"`
+	ClosedMessage string               `json:"closed_message,omitempty"`
+	ClosedURL     string               `json:"closed_url,omitempty"`
+	Open          bool                 `json:"open"`
+	Transitions   []transitionResponse `json:"transitions"`
+}
+
+// newScheduleResponse returns nil when the link has no schedule.
+func newScheduleResponse(l link.Link) *scheduleResponse {
+	s := l.Schedule
+	if s.IsZero() && len(l.Transitions) == 0 {
+		return nil
+	}
+
+	resp := &scheduleResponse{
+		ClosedMessage: s.ClosedMessage,
+		ClosedURL:     s.ClosedURL,
+		Open:          s.Open(time.Now()),
+		Transitions:   make([]transitionResponse, 0, len(l.Transitions)),
+	}
+
+	if !s.ActivateAt.IsZero() {
+		t := s.ActivateAt.UTC()
+		resp.ActivateAt = &t
+	}
+
+	if !s.DeactivateAt.IsZero() {
+		t := s.DeactivateAt.UTC()
+		resp.DeactivateAt = &t
+	}
+
+	if s.Location != nil {
+		resp.TimeZone = s.Location.String()
+	}
+
+	for _, w := range s.Windows {
+		wr := windowRequest{
+			Start: formatTimeOfDay(w.Start),
+			End:   formatTimeOfDay(w.End),
+		}
+
+		for _, d := range w.Days {
+			wr.Days = append(wr.Days, strings.ToLower(d.String()))
+		}
+
+		resp.Windows = append(resp.Windows, wr)
+	}
+
+	for _, t := range l.Transitions {
+		resp.Transitions = append(resp.Transitions, transitionResponse{Time: t.Time.UTC(), Closed: t.Closed})
+	}
+
+	return resp
+}
+
+func formatTimeOfDay(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
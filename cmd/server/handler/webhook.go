@@ -0,0 +1,181 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/emacampolo/link-tracker/internal/webhook"
+)
+
+type Webhook struct {
+	webhookService webhook.Service
+}
+
+func NewWebhook(s webhook.Service) *Webhook {
+	return &Webhook{
+		webhookService: s,
+	}
+}
+
+type subscriptionResponse struct {
+	ID        int              `json:"id"`
+	URL       string           `json:"url"`
+	Events    []link.EventType `json:"events,omitempty"`
+	URLFilter string           `json:"url_filter,omitempty"`
+	Secret    string           `json:"secret,omitempty"`
+}
+
+type subscribeRequest struct {
+	URL       string           `json:"url" validate:"required,url"`
+	Secret    string           `json:"secret"`
+	Events    []link.EventType `json:"events"`
+	URLFilter string           `json:"url_filter"`
+}
+
+func (wh *Webhook) Create() web.Handler {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		var r subscribeRequest
+		if err := web.Decode(req, &r); err != nil {
+			return err
+		}
+
+		for _, e := range r.Events {
+			if !e.Valid() {
+				return web.NewErrorf(http.StatusBadRequest, "unknown event %q", e)
+			}
+		}
+
+		s, err := wh.webhookService.Subscribe(req.Context(), webhook.NewSubscription{
+			URL:       r.URL,
+			Secret:    r.Secret,
+			Events:    r.Events,
+			URLFilter: r.URLFilter,
+		})
+		if err != nil {
+			return err
+		}
+
+		resp := subscriptionResponse{
+			ID:        s.ID,
+			URL:       s.URL,
+			Events:    s.Events,
+			URLFilter: s.URLFilter,
+			Secret:    s.Secret,
+		}
+
+		return web.Respond(req.Context(), w, resp, http.StatusCreated)
+	}
+}
+
+func (wh *Webhook) List() web.Handler {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		subscriptions, err := wh.webhookService.List(req.Context())
+		if err != nil {
+			return err
+		}
+
+		resp := make([]subscriptionResponse, 0, len(subscriptions))
+		for _, s := range subscriptions {
+			resp = append(resp, subscriptionResponse{
+				ID:        s.ID,
+				URL:       s.URL,
+				Events:    s.Events,
+				URLFilter: s.URLFilter,
+			})
+		}
+
+		return web.Respond(req.Context(), w, resp, http.StatusOK)
+	}
+}
+
+func (wh *Webhook) Delete() web.Handler {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		id, err := extractID(req)
+		if err != nil {
+			return err
+		}
+
+		if err := wh.webhookService.Unsubscribe(req.Context(), id); err != nil {
+			return serviceError(err)
+		}
+
+		return web.Respond(req.Context(), w, nil, http.StatusNoContent)
+	}
+}
+
+type deliveryResponse struct {
+	ID             string         `json:"id"`
+	SubscriptionID int            `json:"subscription_id"`
+	Event          link.EventType `json:"event"`
+	Attempt        int            `json:"attempt"`
+	StatusCode     int            `json:"status_code,omitempty"`
+	Error          string         `json:"error,omitempty"`
+	Succeeded      bool           `json:"succeeded"`
+	Time           time.Time      `json:"time"`
+	DurationMillis int64          `json:"duration_ms"`
+}
+
+func newDeliveryResponse(d webhook.Delivery) deliveryResponse {
+	return deliveryResponse{
+		ID:             d.ID,
+		SubscriptionID: d.SubscriptionID,
+		Event:          d.EventType,
+		Attempt:        d.Attempt,
+		StatusCode:     d.StatusCode,
+		Error:          d.Err,
+		Succeeded:      d.Succeeded,
+		Time:           d.Time.UTC(),
+		DurationMillis: d.Duration.Milliseconds(),
+	}
+}
+
+func (wh *Webhook) Deliveries() web.Handler {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		id, err := extractID(req)
+		if err != nil {
+			return err
+		}
+
+		deliveries, err := wh.webhookService.Deliveries(req.Context(), id)
+		if err != nil {
+			return serviceError(err)
+		}
+
+		resp := make([]deliveryResponse, 0, len(deliveries))
+		for _, d := range deliveries {
+			resp = append(resp, newDeliveryResponse(d))
+		}
+
+		return web.Respond(req.Context(), w, resp, http.StatusOK)
+	}
+}
+
+type deadLetterResponse struct {
+	Delivery deliveryResponse `json:"delivery"`
+	URL      string           `json:"url"`
+	Payload  string           `json:"payload"`
+	Reason   string           `json:"reason"`
+}
+
+func (wh *Webhook) DeadLetters() web.Handler {
+	return func(w http.ResponseWriter, req *http.Request) error {
+		deadLetters, err := wh.webhookService.DeadLetters(req.Context())
+		if err != nil {
+			return err
+		}
+
+		resp := make([]deadLetterResponse, 0, len(deadLetters))
+		for _, dl := range deadLetters {
+			resp = append(resp, deadLetterResponse{
+				Delivery: newDeliveryResponse(dl.Delivery),
+				URL:      dl.URL,
+				Payload:  string(dl.Payload),
+				Reason:   dl.Reason,
+			})
+		}
+
+		return web.Respond(req.Context(), w, resp, http.StatusOK)
+	}
+}
@@ -0,0 +1,116 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/webhook"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type webhookServiceMock struct {
+	mock.Mock
+}
+
+func (s *webhookServiceMock) Subscribe(ctx context.Context, ns webhook.NewSubscription) (webhook.Subscription, error) {
+	args := s.Called(ctx, ns)
+	return args.Get(0).(webhook.Subscription), args.Error(1)
+}
+
+func (s *webhookServiceMock) Unsubscribe(ctx context.Context, ID int) error {
+	return s.Called(ctx, ID).Error(0)
+}
+
+func (s *webhookServiceMock) List(ctx context.Context) ([]webhook.Subscription, error) {
+	args := s.Called(ctx)
+	return args.Get(0).([]webhook.Subscription), args.Error(1)
+}
+
+func (s *webhookServiceMock) Deliveries(ctx context.Context, subscriptionID int) ([]webhook.Delivery, error) {
+	args := s.Called(ctx, subscriptionID)
+	return args.Get(0).([]webhook.Delivery), args.Error(1)
+}
+
+func (s *webhookServiceMock) DeadLetters(ctx context.Context) ([]webhook.DeadLetter, error) {
+	args := s.Called(ctx)
+	return args.Get(0).([]webhook.DeadLetter), args.Error(1)
+}
+
+func TestWebhook_Create(t *testing.T) {
+	// Given
+	body := `{"url":"https://hooks.example.com","events":["link.visited"],"url_filter":"https://*"}`
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(body))
+	rr := httptest.NewRecorder()
+
+	ns := webhook.NewSubscription{
+		URL:       "https://hooks.example.com",
+		Events:    []link.EventType{link.EventVisited},
+		URLFilter: "https://*",
+	}
+
+	svcMock := &webhookServiceMock{}
+	svcMock.On("Subscribe", mock.Anything, ns).Return(webhook.Subscription{
+		ID:        1,
+		URL:       ns.URL,
+		Secret:    "secret",
+		Events:    ns.Events,
+		URLFilter: ns.URLFilter,
+	}, nil)
+
+	webhookHandler := handler.NewWebhook(svcMock)
+
+	// When
+	webhookHandler.Create().ServeHTTP(rr, req)
+
+	// Then
+	require.Equal(t, http.StatusCreated, rr.Code)
+	require.JSONEq(t, `{"id":1,"url":"https://hooks.example.com","events":["link.visited"],"url_filter":"https://*","secret":"secret"}`, rr.Body.String())
+}
+
+func TestWebhook_Create_InvalidRequest(t *testing.T) {
+	tt := []struct {
+		name    string
+		body    string
+		wantErr string
+	}{
+		{
+			name:    "url is required",
+			body:    `{}`,
+			wantErr: `{"code":"invalid_request","message":"url is missing","fields":[{"field":"url","code":"required","message":"url is missing"}]}`,
+		},
+		{
+			name:    "url must be absolute",
+			body:    `{"url":"/hooks"}`,
+			wantErr: `{"code":"invalid_request","message":"url must be an absolute http or https URL","fields":[{"field":"url","code":"url","message":"url must be an absolute http or https URL"}]}`,
+		},
+		{
+			name:    "events must be known",
+			body:    `{"url":"https://hooks.example.com","events":["link.deleted"]}`,
+			wantErr: `{"code":"bad_request","message":"unknown event \"link.deleted\""}`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			req := httptest.NewRequest(http.MethodPost, "/webhooks", bytes.NewBufferString(tc.body))
+			rr := httptest.NewRecorder()
+
+			webhookHandler := handler.NewWebhook(&webhookServiceMock{})
+
+			// When
+			webhookHandler.Create().ServeHTTP(rr, req)
+
+			// Then
+			require.Equal(t, http.StatusBadRequest, rr.Code)
+			require.JSONEq(t, tc.wantErr, rr.Body.String())
+		})
+	}
+}
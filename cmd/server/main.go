@@ -1,11 +1,30 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"expvar"
+	"flag"
+	"fmt"
 	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata" // The time zones of the link schedules must be available even if the system lacks them.
 
 	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/cmd/server/rpc"
+	"github.com/emacampolo/link-tracker/internal/bot"
+	"github.com/emacampolo/link-tracker/internal/healthcheck"
 	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/keyring"
 	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/emacampolo/link-tracker/internal/unfurl"
+	"github.com/emacampolo/link-tracker/internal/webhook"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func main() {
@@ -15,16 +34,308 @@ func main() {
 }
 
 func run() error {
-	linkRepository := link.NewInMemoryRepository()
-	linkService := link.NewService(linkRepository)
-	linkHandler := handler.NewLink(linkService)
+	publicURL := flag.String("public-url", "http://localhost:8080", "address where the links are reachable by the users")
+	visitorSalt := flag.String("visitor-salt", "", "secret used to fingerprint visitors; random if empty")
+	botRules := flag.String("bot-rules", "", "JSON file with the rules to detect bots, reloaded when modified; built-in rules if empty")
+	botPreview := flag.Bool("bot-preview", false, "serve bots an Open Graph preview instead of redirecting them")
+	ownDomains := flag.String("own-domains", "", "comma separated domains that belong to us; the interstitial page warns about any other")
+	healthInterval := flag.Duration("health-interval", healthcheck.DefaultConfig.Interval, "time between two checks of the destination of every link; 0 disables them")
+	accessKeys := flag.String("access-keys", "", "JSON file with the keys to sign access URLs, reloaded when modified; signed access is disabled if empty")
+	passwordHash := flag.String("password-hash", "bcrypt", "algorithm to hash the passwords of the links: bcrypt or argon2id; existing hashes are upgraded when verified")
+	bcryptCost := flag.Int("bcrypt-cost", bcrypt.DefaultCost, "cost of the bcrypt password hashes")
+	minPasswordLength := flag.Int("min-password-length", 8, "minimum length of the passwords of new links")
+	verifiedPasswords := flag.Int("verified-passwords", 10000, "number of verified passwords kept in memory to skip hashing them again; 0 disables the cache")
+	rateLimit := flag.Bool("rate-limit", true, "limit the links created and opened by each client and the visits to each link")
+	trustedProxies := flag.String("trusted-proxies", "", "comma separated CIDRs of the proxies whose Forwarded, X-Forwarded-For and X-Real-IP headers are trusted")
+	verifiedPasswordTTL := flag.Duration("verified-password-ttl", 5*time.Minute, "time a verified password is kept in memory")
+	linksFile := flag.String("links-file", "", "file where the links are stored, a JSON line per change, also used by linkctl -file while the server is stopped; links are kept in memory if empty")
+	adminOwners := flag.String("admin-owners", "", "file with the owner:bcrypt-hash lines of the owners that can log in to /admin, as written by htpasswd -B; the admin pages are disabled if empty")
+	adminSessionKey := flag.String("admin-session-key", "", "secret of at least 32 bytes that signs the sessions of /admin; random if empty, which logs everyone out on restart")
+	grpcAddr := flag.String("grpc-addr", ":9090", "address of the gRPC server, which shares the links of the HTTP API; disabled if empty")
+	debugAddr := flag.String("debug-addr", "localhost:6060", "address, reachable only by the operators, where the runtime variables are served at /debug/vars; disabled if empty")
+	legacySunset := flag.String("legacy-sunset", "", "date, as 2006-01-02, when the routes without version will be removed; announced in their Sunset header if set")
+	flag.Parse()
+
+	// Both servers and the background workers stop gracefully on SIGINT or SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	bus := link.NewBus()
+
+	webhookRepository := webhook.NewInMemoryRepository()
+	deliveryLog := webhook.NewDeliveryLog(100)
+	deadLetterStore := webhook.NewInMemoryDeadLetterStore()
+	dispatcher := webhook.NewDispatcher(webhookRepository, deliveryLog, deadLetterStore, webhook.DefaultConfig)
+	bus.Subscribe(dispatcher.Handle)
+	go dispatcher.Run(ctx)
+
+	webhookService := webhook.NewService(webhookRepository, deliveryLog, deadLetterStore)
+	webhookHandler := handler.NewWebhook(webhookService)
+
+	var linkRepository link.Repository = link.NewInMemoryRepository()
+	if *linksFile != "" {
+		fileRepository, err := link.OpenFileRepository(*linksFile)
+		if err != nil {
+			return err
+		}
+		defer fileRepository.Close()
+		linkRepository = fileRepository
+	}
+	linkOptions := []link.Option{
+		link.WithPublisher(bus),
+		link.WithVisitThresholds(10, 100, 1000, 10000),
+	}
+	switch *passwordHash {
+	case "bcrypt":
+		linkOptions = append(linkOptions, link.WithHasher(link.BcryptHasher{Cost: *bcryptCost}))
+	case "argon2id":
+		linkOptions = append(linkOptions, link.WithHasher(link.DefaultArgon2idHasher))
+	default:
+		return fmt.Errorf("unknown password hash %q", *passwordHash)
+	}
+
+	linkOptions = append(linkOptions, link.WithPasswordPolicy(link.PasswordPolicy{
+		MinLength:      *minPasswordLength,
+		MinCharClasses: 2,
+		RejectCommon:   true,
+	}))
+
+	if *visitorSalt != "" {
+		linkOptions = append(linkOptions, link.WithVisitorSalt([]byte(*visitorSalt)))
+	}
+
+	if *accessKeys != "" {
+		keyRing, err := keyring.New(keyring.Keys{})
+		if err != nil {
+			return err
+		}
+
+		if err := keyRing.Load(*accessKeys); err != nil {
+			return err
+		}
+
+		go keyRing.Watch(ctx, *accessKeys, 10*time.Second)
+		linkOptions = append(linkOptions, link.WithKeyRing(keyRing))
+	}
+
+	if *verifiedPasswords > 0 {
+		cache := link.NewVerificationCache(*verifiedPasswords, *verifiedPasswordTTL)
+		expvar.Publish("verification_cache", expvar.Func(func() interface{} { return cache.Stats() }))
+		linkOptions = append(linkOptions, link.WithVerificationCache(cache))
+	}
+
+	linkService := link.NewService(linkRepository, linkOptions...)
+
+	botClassifier := bot.NewDefaultClassifier()
+	if *botRules != "" {
+		if err := botClassifier.Load(*botRules); err != nil {
+			return err
+		}
+
+		go botClassifier.Watch(ctx, *botRules, 10*time.Second)
+	}
+
+	linkHandlerOptions := []handler.LinkOption{handler.WithBotClassifier(botClassifier), handler.WithPublicURL(*publicURL)}
+	if *ownDomains != "" {
+		linkHandlerOptions = append(linkHandlerOptions, handler.WithOwnDomains(strings.Split(*ownDomains, ",")...))
+	}
+	if *botPreview {
+		linkHandlerOptions = append(linkHandlerOptions, handler.WithBotPreview())
+	}
+
+	linkHandler := handler.NewLink(linkService, linkHandlerOptions...)
+
+	unfurlWorker := unfurl.NewWorker(unfurl.NewFetcher(unfurl.DefaultConfig), linkService, unfurl.DefaultWorkerConfig)
+	bus.Subscribe(unfurlWorker.Handle)
+	go unfurlWorker.Run(ctx)
+
+	if *healthInterval > 0 {
+		healthConfig := healthcheck.DefaultConfig
+		healthConfig.Interval = *healthInterval
+		go healthcheck.NewChecker(linkService, healthConfig).Run(ctx)
+	}
+
+	go link.NewScheduler(linkService, time.Minute).Run(ctx)
+
+	qrHandler := handler.NewQR(linkService, *publicURL)
+
+	clickStream := link.NewClickStream(1000)
+	bus.Subscribe(clickStream.Handle)
+	clickHandler := handler.NewClick(linkService, clickStream, 15*time.Second)
+
+	proxies, err := parseCIDRs(*trustedProxies)
+	if err != nil {
+		return err
+	}
 
 	application := web.New()
+	application.Use(web.RealIP(proxies...))
+
+	// The buckets of the HTTP and gRPC limits are kept in the same store.
+	rateStore := web.NewMemoryRateStore()
+	if *rateLimit {
+		application.RateLimit(newRateLimiter(rateStore))
+	}
+
+	var sunset time.Time
+	if *legacySunset != "" {
+		if sunset, err = time.Parse("2006-01-02", *legacySunset); err != nil {
+			return fmt.Errorf("invalid legacy sunset: %w", err)
+		}
+	}
+
+	handler.API{
+		Link:    linkHandler,
+		QR:      qrHandler,
+		Click:   clickHandler,
+		Webhook: webhookHandler,
+		Info:    web.Info{Title: "Link Tracker", Version: "2.0.0", Description: "Shortens, protects and tracks links."},
+		Sunset:  sunset,
+	}.Register(application)
+
+	if *adminOwners != "" {
+		adminHandler, err := newAdmin(linkService, *adminOwners, *adminSessionKey, *publicURL)
+		if err != nil {
+			return err
+		}
 
-	application.Method("POST", "/link", linkHandler.Create())
-	application.Method("GET", "/link/{id}", linkHandler.Redirect())
-	application.Method("GET", "/link/{id}/metrics", linkHandler.Metrics())
-	application.Method("POST", "/link/{id}/inactivate", linkHandler.Inactivate())
+		adminHandler.Register(application)
+	}
+
+	servers := []func(context.Context) error{
+		func(ctx context.Context) error { return application.Serve(ctx, ":8080") },
+	}
+	if *grpcAddr != "" {
+		var rpcOptions []rpc.Option
+		if *rateLimit {
+			rpcOptions = append(rpcOptions, rpc.WithRateLimiter(newRPCRateLimiter(rateStore)))
+		}
+
+		rpcServer := rpc.NewServer(linkService, clickStream, rpcOptions...)
+		servers = append(servers, func(ctx context.Context) error { return rpcServer.ListenAndServe(ctx, *grpcAddr) })
+	}
+	if *debugAddr != "" {
+		// The variables describe the internals of the server, so they are kept out of the public listener.
+		debug := web.New(web.WithoutRequestLog())
+		debug.Method("GET", "/debug/vars", web.VarsHandler())
+		servers = append(servers, func(ctx context.Context) error { return debug.Serve(ctx, *debugAddr) })
+	}
+
+	return serveAll(ctx, servers...)
+}
+
+// serveAll runs the servers until ctx is done, or until any of them fails, which stops the others.
+// It returns the first error.
+func serveAll(ctx context.Context, servers ...func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	errs := make(chan error, len(servers))
+	for _, serve := range servers {
+		go func(serve func(context.Context) error) {
+			err := serve(ctx)
+			cancel()
+			errs <- err
+		}(serve)
+	}
+
+	var first error
+	for range servers {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// The limits of the creation of links and of the visits, shared by HTTP and gRPC.
+var (
+	linksPerClient  = web.Limit{Requests: 10, Per: time.Minute}
+	visitsPerClient = web.Limit{Requests: 60, Per: time.Minute, Burst: 20}
+	visitsPerLink   = web.Limit{Requests: 6000, Per: time.Minute}
+)
+
+// newRateLimiter protects the creation of links from spam, and their redirections and passwords, and the
+// passwords of the owners, from floods. Its buckets are kept in store.
+func newRateLimiter(store web.RateStore) *web.RateLimiter {
+	byIP := web.ByIP()
+	limiter := web.NewRateLimiter(store)
+
+	limiter.Limit("POST", "/link", web.Policy{Name: "links per client", Key: byIP, Limit: linksPerClient})
+	for _, method := range []string{"GET", "HEAD"} {
+		limiter.Limit(method, "/link/{id}",
+			web.Policy{Name: "visits per client", Key: byIP, Limit: visitsPerClient},
+			web.Policy{Name: "visits per link", Key: handler.ByLinkID(), Limit: visitsPerLink},
+		)
+	}
+	limiter.Limit("POST", "/link/{id}/access", web.Policy{Name: "access per client", Key: byIP, Limit: web.Limit{Requests: 10, Per: time.Minute}})
+	limiter.Limit("POST", "/admin/login", web.Policy{Name: "logins per client", Key: byIP, Limit: web.Limit{Requests: 10, Per: time.Minute}})
+
+	return limiter
+}
+
+// newRPCRateLimiter applies the limits of newRateLimiter to the gRPC methods that create and resolve links.
+// Its buckets are kept in store.
+func newRPCRateLimiter(store web.RateStore) *rpc.RateLimiter {
+	byIP := rpc.ByPeerIP()
+	limiter := rpc.NewRateLimiter(store)
+
+	limiter.Limit("/link.v1.LinkService/CreateLink", rpc.Policy{Name: "links per client", Key: byIP, Limit: linksPerClient})
+	limiter.Limit("/link.v1.LinkService/ResolveLink",
+		rpc.Policy{Name: "visits per client", Key: byIP, Limit: visitsPerClient},
+		rpc.Policy{Name: "visits per link", Key: rpc.ByLinkID(), Limit: visitsPerLink},
+	)
+
+	return limiter
+}
+
+// newAdmin creates the handler of the admin pages, for the owners listed in the file ownersFile.
+func newAdmin(linkService link.Service, ownersFile, sessionKey, publicURL string) (*handler.Admin, error) {
+	f, err := os.Open(ownersFile)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	owners, err := handler.ReadOwners(f)
+	if err != nil {
+		return nil, fmt.Errorf("reading the owners from %s: %w", ownersFile, err)
+	}
+
+	key := []byte(sessionKey)
+	if sessionKey == "" {
+		key = make([]byte, 32)
+		if _, err := rand.Read(key); err != nil {
+			return nil, err
+		}
+	}
+
+	config := web.DefaultSessionConfig
+	config.Name = "admin_session"
+	config.Path = "/admin"
+	config.Secure = strings.HasPrefix(publicURL, "https://")
+	sessions, err := web.NewSessions(key, config)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler.NewAdmin(linkService, owners, sessions, publicURL), nil
+}
+
+func parseCIDRs(s string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, cidr := range strings.Split(s, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		nets = append(nets, n)
+	}
 
-	return application.Run()
+	return nets, nil
 }
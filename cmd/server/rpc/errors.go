@@ -0,0 +1,53 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/keyring"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorCatalog maps the errors of the link service to their status code. The first match wins,
+// so the errors that wrap others go first.
+var errorCatalog = []struct {
+	err  error
+	code codes.Code
+}{
+	{link.ErrNotFound, codes.NotFound},
+	{link.ErrAuthentication, codes.Unauthenticated},
+	{link.ErrInactive, codes.FailedPrecondition},
+	{link.ErrConsumed, codes.FailedPrecondition},
+	{link.ErrClosed, codes.FailedPrecondition},
+	{link.ErrSecret, codes.PermissionDenied},
+	{link.ErrInvalidLink, codes.InvalidArgument},
+	{link.ErrInvalidSchedule, codes.InvalidArgument},
+	{link.ErrWeakPassword, codes.InvalidArgument},
+	{link.ErrInvalidAccess, codes.InvalidArgument},
+	{link.ErrAccessDisabled, codes.Unimplemented},
+	{keyring.ErrNoKey, codes.Unimplemented},
+}
+
+// statusError returns the status of err according to the errorCatalog. Invalid requests are InvalidArgument,
+// the errors of the context keep their meaning, and unknown errors are Internal.
+func statusError(err error) error {
+	for _, e := range errorCatalog {
+		if errors.Is(err, e.err) {
+			return status.Error(e.code, err.Error())
+		}
+	}
+
+	var webErr *web.Error
+	if errors.As(err, &webErr) {
+		return status.Error(codes.InvalidArgument, webErr.Message)
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	return status.Error(codes.Internal, err.Error())
+}
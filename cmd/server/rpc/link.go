@@ -0,0 +1,219 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	linkv1 "github.com/emacampolo/link-tracker/api/link/v1"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// clickCapacity is the number of clicks that can be pending for a client before it is disconnected.
+const clickCapacity = 64
+
+// linkServer implements linkv1.LinkServiceServer with a link.Service.
+type linkServer struct {
+	linkv1.UnimplementedLinkServiceServer
+
+	linkService link.Service
+	clickStream *link.ClickStream
+	done        <-chan struct{}
+}
+
+// newLink is the CreateLinkRequest with the rules of the HTTP API.
+type newLink struct {
+	URL         string `json:"url" validate:"required,url"`
+	Owner       string `json:"owner" validate:"max=100"`
+	Title       string `json:"title" validate:"max=200"`
+	FallbackURL string `json:"fallback_url" validate:"url"`
+	MaxVisits   int32  `json:"max_visits" validate:"min=0"`
+}
+
+func (s *linkServer) CreateLink(ctx context.Context, req *linkv1.CreateLinkRequest) (*linkv1.CreateLinkResponse, error) {
+	if err := web.Validate(newLink{
+		URL:         req.Url,
+		Owner:       req.Owner,
+		Title:       req.Title,
+		FallbackURL: req.FallbackUrl,
+		MaxVisits:   req.MaxVisits,
+	}); err != nil {
+		return nil, statusError(err)
+	}
+
+	l, err := s.linkService.Create(ctx, link.NewLink{
+		URL:          req.Url,
+		Password:     req.Password,
+		Owner:        req.Owner,
+		Title:        req.Title,
+		Interstitial: req.Interstitial,
+		FallbackURL:  req.FallbackUrl,
+		MaxVisits:    int(req.MaxVisits),
+	})
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &linkv1.CreateLinkResponse{Id: int64(l.ID)}, nil
+}
+
+// ResolveLink counts the visit like the redirect of the HTTP API. The visitor is the peer of the call,
+// identified by its IP and the user agent of its metadata.
+func (s *linkServer) ResolveLink(ctx context.Context, req *linkv1.ResolveLinkRequest) (*linkv1.ResolveLinkResponse, error) {
+	l, err := s.linkService.Redirect(ctx, int(req.Id), link.Visit{
+		Password:  req.Password,
+		IP:        peerIP(ctx),
+		UserAgent: userAgent(ctx),
+	})
+	if err != nil {
+		// Visitors of a link outside of its schedule are sent to its closed URL, if it has one.
+		var closed *link.ClosedError
+		if errors.As(err, &closed) && closed.URL != "" {
+			return &linkv1.ResolveLinkResponse{Url: closed.URL}, nil
+		}
+
+		return nil, statusError(err)
+	}
+
+	return &linkv1.ResolveLinkResponse{Url: l.Destination()}, nil
+}
+
+func (s *linkServer) GetMetrics(ctx context.Context, req *linkv1.GetMetricsRequest) (*linkv1.Metrics, error) {
+	l, err := s.linkService.FindByID(ctx, int(req.Id))
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	m := &linkv1.Metrics{
+		Id:                  int64(l.ID),
+		Url:                 l.URL,
+		Owner:               l.Owner,
+		Title:               l.Title,
+		Interstitial:        l.Interstitial,
+		Count:               int64(l.Count),
+		PreviewCount:        int64(l.PreviewCount),
+		BotCount:            int64(l.BotCount),
+		UniqueVisitors:      l.UniqueVisitors(),
+		DailyUniqueVisitors: l.DailyUniqueVisitors(),
+		Inactive:            l.Inactive,
+		Public:              l.Public(),
+		Failing:             l.Health.Failing,
+		MaxVisits:           int32(l.MaxVisits),
+	}
+
+	if l.Secret() {
+		// The destination of a secret link is only revealed by resolving it.
+		m.Url = ""
+		m.RemainingVisits = int32(l.RemainingVisits())
+	}
+
+	if !l.ConsumedAt.IsZero() {
+		m.ConsumedAt = timestamppb.New(l.ConsumedAt)
+	}
+
+	return m, nil
+}
+
+func (s *linkServer) InactivateLink(ctx context.Context, req *linkv1.InactivateLinkRequest) (*linkv1.InactivateLinkResponse, error) {
+	if err := s.linkService.Inactivate(ctx, int(req.Id)); err != nil {
+		return nil, statusError(err)
+	}
+
+	return &linkv1.InactivateLinkResponse{}, nil
+}
+
+// WatchClicks streams the clicks until the client cancels the call, the client falls behind, or the
+// server shuts down. The last two end the stream with Unavailable, so the client can resume it.
+func (s *linkServer) WatchClicks(req *linkv1.WatchClicksRequest, stream linkv1.LinkService_WatchClicksServer) error {
+	var filter func(link.Click) bool
+	switch {
+	case req.LinkId != 0 && req.Owner != "":
+		return status.Error(codes.InvalidArgument, "link_id and owner are mutually exclusive")
+	case req.LinkId != 0:
+		id := int(req.LinkId)
+		if _, err := s.linkService.FindByID(stream.Context(), id); err != nil {
+			return statusError(err)
+		}
+
+		filter = func(c link.Click) bool { return c.LinkID == id }
+	case req.Owner != "":
+		filter = func(c link.Click) bool { return c.Owner == req.Owner }
+	default:
+		return status.Error(codes.InvalidArgument, "link_id or owner is required")
+	}
+
+	sub := s.clickStream.Subscribe(filter, req.LastClickId, clickCapacity)
+	defer sub.Close()
+
+	// The headers tell the client that no click is missed from now on.
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for _, c := range sub.Replay {
+		if err := stream.Send(newClick(c)); err != nil {
+			return err
+		}
+	}
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return statusError(stream.Context().Err())
+		case <-s.done:
+			return status.Error(codes.Unavailable, "the server is shutting down")
+		case c, ok := <-sub.C:
+			if !ok {
+				return status.Error(codes.Unavailable, "the client fell behind the clicks")
+			}
+
+			if err := stream.Send(newClick(c)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func newClick(c link.Click) *linkv1.Click {
+	return &linkv1.Click{
+		Id:     c.ID,
+		LinkId: int64(c.LinkID),
+		Url:    c.URL,
+		Count:  int64(c.Count),
+		Time:   timestamppb.New(c.Time),
+	}
+}
+
+// peerIP returns the IP of the client of the call, or its whole address if it has no port.
+func peerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+
+	return addr
+}
+
+func userAgent(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	if ua := md.Get("user-agent"); len(ua) > 0 {
+		return ua[0]
+	}
+
+	return ""
+}
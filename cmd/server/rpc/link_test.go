@@ -0,0 +1,322 @@
+package rpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	linkv1 "github.com/emacampolo/link-tracker/api/link/v1"
+	"github.com/emacampolo/link-tracker/cmd/server/rpc"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testServer struct {
+	client      linkv1.LinkServiceClient
+	linkService link.Service
+	// stop shuts the server down and returns the result of Serve.
+	stop func() error
+}
+
+func newTestServer(t *testing.T, opts ...rpc.Option) *testServer {
+	bus := link.NewBus()
+	clickStream := link.NewClickStream(10)
+	bus.Subscribe(clickStream.Handle)
+	linkService := link.NewService(link.NewInMemoryRepository(),
+		link.WithPublisher(bus),
+		link.WithHasher(link.BcryptHasher{Cost: bcrypt.MinCost}),
+	)
+
+	lis := bufconn.Listen(1 << 20)
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- rpc.NewServer(linkService, clickStream, opts...).Serve(ctx, lis)
+	}()
+
+	conn, err := grpc.Dial("bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithInsecure(),
+	)
+	require.NoError(t, err)
+
+	stopped := false
+	stop := func() error {
+		if stopped {
+			return nil
+		}
+		stopped = true
+		cancel()
+		return <-served
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		stop()
+	})
+
+	return &testServer{client: linkv1.NewLinkServiceClient(conn), linkService: linkService, stop: stop}
+}
+
+func TestLink_CreateLink_ResolveLink(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	s := newTestServer(t)
+
+	// When
+	created, err := s.client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.google.com", Owner: "alice", Title: "Google"})
+	require.NoError(t, err)
+	resolved, err := s.client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: created.Id})
+	require.NoError(t, err)
+	metrics, err := s.client.GetMetrics(ctx, &linkv1.GetMetricsRequest{Id: created.Id})
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, int64(1), created.Id)
+	require.Equal(t, "https://www.google.com", resolved.Url)
+	require.Equal(t, int64(1), metrics.Id)
+	require.Equal(t, "https://www.google.com", metrics.Url)
+	require.Equal(t, "alice", metrics.Owner)
+	require.Equal(t, "Google", metrics.Title)
+	require.Equal(t, int64(1), metrics.Count)
+	require.Equal(t, uint64(1), metrics.UniqueVisitors)
+	require.True(t, metrics.Public)
+	require.False(t, metrics.Inactive)
+}
+
+func TestLink_GetMetrics_Secret(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	s := newTestServer(t)
+	created, err := s.client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.google.com", Password: "correct horse 42", MaxVisits: 1})
+	require.NoError(t, err)
+
+	// When
+	before, err := s.client.GetMetrics(ctx, &linkv1.GetMetricsRequest{Id: created.Id})
+	require.NoError(t, err)
+	resolved, err := s.client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: created.Id, Password: "correct horse 42"})
+	require.NoError(t, err)
+	after, err := s.client.GetMetrics(ctx, &linkv1.GetMetricsRequest{Id: created.Id})
+	require.NoError(t, err)
+
+	// Then
+	require.Empty(t, before.Url, "the destination of a secret link is only revealed by resolving it")
+	require.Equal(t, int32(1), before.RemainingVisits)
+	require.Nil(t, before.ConsumedAt)
+	require.Equal(t, "https://www.google.com", resolved.Url)
+	require.Equal(t, int32(0), after.RemainingVisits)
+	require.True(t, after.Inactive)
+	require.NotNil(t, after.ConsumedAt)
+}
+
+func TestLink_InactivateLink(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	s := newTestServer(t)
+	created, err := s.client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.google.com"})
+	require.NoError(t, err)
+
+	// When
+	_, err = s.client.InactivateLink(ctx, &linkv1.InactivateLinkRequest{Id: created.Id})
+
+	// Then
+	require.NoError(t, err)
+	l, err := s.linkService.FindByID(ctx, int(created.Id))
+	require.NoError(t, err)
+	require.True(t, l.Inactive, "the link must be inactivated in the shared service")
+}
+
+func TestLink_Errors(t *testing.T) {
+	tt := []struct {
+		name     string
+		call     func(ctx context.Context, client linkv1.LinkServiceClient) error
+		wantCode codes.Code
+	}{
+		{
+			name: "not found",
+			call: func(ctx context.Context, client linkv1.LinkServiceClient) error {
+				_, err := client.GetMetrics(ctx, &linkv1.GetMetricsRequest{Id: 42})
+				return err
+			},
+			wantCode: codes.NotFound,
+		},
+		{
+			name: "password required",
+			call: func(ctx context.Context, client linkv1.LinkServiceClient) error {
+				_, err := client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: 2})
+				return err
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "wrong password",
+			call: func(ctx context.Context, client linkv1.LinkServiceClient) error {
+				_, err := client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: 2, Password: "wrong"})
+				return err
+			},
+			wantCode: codes.Unauthenticated,
+		},
+		{
+			name: "inactive",
+			call: func(ctx context.Context, client linkv1.LinkServiceClient) error {
+				if _, err := client.InactivateLink(ctx, &linkv1.InactivateLinkRequest{Id: 1}); err != nil {
+					return err
+				}
+				_, err := client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: 1})
+				return err
+			},
+			wantCode: codes.FailedPrecondition,
+		},
+		{
+			name: "invalid url",
+			call: func(ctx context.Context, client linkv1.LinkServiceClient) error {
+				_, err := client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "google"})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "secret interstitial",
+			call: func(ctx context.Context, client linkv1.LinkServiceClient) error {
+				_, err := client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.google.com", MaxVisits: 1, Interstitial: true})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "watch without filter",
+			call: func(ctx context.Context, client linkv1.LinkServiceClient) error {
+				stream, err := client.WatchClicks(ctx, &linkv1.WatchClicksRequest{})
+				if err != nil {
+					return err
+				}
+				_, err = stream.Recv()
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "watch unknown link",
+			call: func(ctx context.Context, client linkv1.LinkServiceClient) error {
+				stream, err := client.WatchClicks(ctx, &linkv1.WatchClicksRequest{LinkId: 42})
+				if err != nil {
+					return err
+				}
+				_, err = stream.Recv()
+				return err
+			},
+			wantCode: codes.NotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			ctx := context.Background()
+			s := newTestServer(t)
+			_, err := s.client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.google.com"})
+			require.NoError(t, err)
+			_, err = s.client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.bing.com", Password: "correct horse 42"})
+			require.NoError(t, err)
+
+			// When
+			err = tc.call(ctx, s.client)
+
+			// Then
+			require.Equal(t, tc.wantCode, status.Code(err), err)
+		})
+	}
+}
+
+func TestLink_WatchClicks(t *testing.T) {
+	// Given
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := newTestServer(t)
+	first, err := s.client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.google.com", Owner: "alice"})
+	require.NoError(t, err)
+	second, err := s.client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.bing.com", Owner: "bob"})
+	require.NoError(t, err)
+
+	stream, err := s.client.WatchClicks(ctx, &linkv1.WatchClicksRequest{Owner: "alice"})
+	require.NoError(t, err)
+	// The stream is subscribed once the server has sent its headers.
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	// When
+	_, err = s.client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: second.Id})
+	require.NoError(t, err)
+	_, err = s.client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: first.Id})
+	require.NoError(t, err)
+	click, err := stream.Recv()
+	require.NoError(t, err)
+
+	_, err = s.client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: first.Id})
+	require.NoError(t, err)
+	resumed, err := s.client.WatchClicks(ctx, &linkv1.WatchClicksRequest{LinkId: first.Id, LastClickId: click.Id})
+	require.NoError(t, err)
+	replayed, err := resumed.Recv()
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, first.Id, click.LinkId)
+	require.Equal(t, "https://www.google.com", click.Url)
+	require.Equal(t, int64(1), click.Count)
+	require.NotNil(t, click.Time)
+
+	require.Equal(t, click.Id+1, replayed.Id, "the clicks after the last one must be replayed")
+	require.Equal(t, int64(2), replayed.Count)
+}
+
+func TestLink_WatchClicks_Secret(t *testing.T) {
+	// Given
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := newTestServer(t)
+	created, err := s.client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.google.com/reset?token=1", Password: "correct horse 42", MaxVisits: 1})
+	require.NoError(t, err)
+
+	stream, err := s.client.WatchClicks(ctx, &linkv1.WatchClicksRequest{LinkId: created.Id})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	// When
+	_, err = s.client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: created.Id, Password: "correct horse 42"})
+	require.NoError(t, err)
+	click, err := stream.Recv()
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, created.Id, click.LinkId)
+	require.Empty(t, click.Url, "the destination of a secret link must not be streamed")
+}
+
+func TestServer_Serve_Shutdown(t *testing.T) {
+	// Given
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	s := newTestServer(t)
+	stream, err := s.client.WatchClicks(ctx, &linkv1.WatchClicksRequest{Owner: "alice"})
+	require.NoError(t, err)
+	_, err = stream.Header()
+	require.NoError(t, err)
+
+	// When
+	serveErr := s.stop()
+	_, recvErr := stream.Recv()
+
+	// Then
+	require.NoError(t, serveErr)
+	require.Equal(t, codes.Unavailable, status.Code(recvErr), "the click streams must end when shutting down")
+}
@@ -0,0 +1,88 @@
+package rpc
+
+import (
+	"context"
+	"math"
+	"strconv"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// KeyFunc returns the key that identifies who is limited by a policy on a call with the request req.
+// The policy is skipped if the key is empty.
+type KeyFunc func(ctx context.Context, req interface{}) string
+
+// ByPeerIP limits each IP of the clients, as returned by peerIP.
+func ByPeerIP() KeyFunc {
+	return func(ctx context.Context, _ interface{}) string {
+		return peerIP(ctx)
+	}
+}
+
+// ByLinkID limits each link, for the requests that identify one by its id.
+func ByLinkID() KeyFunc {
+	return func(_ context.Context, req interface{}) string {
+		r, ok := req.(interface{ GetId() int64 })
+		if !ok {
+			return ""
+		}
+
+		return strconv.FormatInt(r.GetId(), 10)
+	}
+}
+
+// Policy limits the calls that share the same key, as web.Policy does for the requests of the HTTP API.
+type Policy struct {
+	// Name identifies the policy in the keys of the store and in the errors.
+	Name  string
+	Key   KeyFunc
+	Limit web.Limit
+}
+
+// RateLimiter applies policies to the unary methods of a Server.
+type RateLimiter struct {
+	store   web.RateStore
+	methods map[string][]Policy
+}
+
+// NewRateLimiter creates a RateLimiter that keeps its buckets in store.
+func NewRateLimiter(store web.RateStore) *RateLimiter {
+	return &RateLimiter{
+		store:   store,
+		methods: make(map[string][]Policy),
+	}
+}
+
+// Limit applies the policies to the method, named as /link.v1.LinkService/ResolveLink. A call is rejected
+// with ResourceExhausted if any of them is exceeded.
+func (rl *RateLimiter) Limit(method string, policies ...Policy) {
+	rl.methods[method] = append(rl.methods[method], policies...)
+}
+
+// unary applies the policies of the method before calling it. The seconds until the call can be retried
+// are sent in the retry-after header.
+func (rl *RateLimiter) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	for _, p := range rl.methods[info.FullMethod] {
+		key := p.Key(ctx, req)
+		if key == "" {
+			continue
+		}
+
+		d, err := rl.store.Take(ctx, info.FullMethod+"|"+p.Name+"|"+key, p.Limit)
+		if err != nil {
+			return nil, statusError(err)
+		}
+
+		if !d.Allowed {
+			seconds := int(math.Ceil(d.RetryAfter.Seconds()))
+			_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(seconds)))
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit %q exceeded, retry in %d seconds", p.Name, seconds)
+		}
+	}
+
+	return handler(ctx, req)
+}
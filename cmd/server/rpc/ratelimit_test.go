@@ -0,0 +1,47 @@
+package rpc_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	linkv1 "github.com/emacampolo/link-tracker/api/link/v1"
+	"github.com/emacampolo/link-tracker/cmd/server/rpc"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRateLimiter(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	limiter := rpc.NewRateLimiter(web.NewMemoryRateStore())
+	limiter.Limit("/link.v1.LinkService/ResolveLink",
+		rpc.Policy{Name: "visits per link", Key: rpc.ByLinkID(), Limit: web.Limit{Requests: 1, Per: time.Minute}})
+	s := newTestServer(t, rpc.WithRateLimiter(limiter))
+
+	created, err := s.client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.google.com"})
+	require.NoError(t, err)
+	other, err := s.client.CreateLink(ctx, &linkv1.CreateLinkRequest{Url: "https://www.bing.com"})
+	require.NoError(t, err)
+	_, err = s.client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: created.Id})
+	require.NoError(t, err)
+
+	// When
+	var header metadata.MD
+	_, err = s.client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: created.Id}, grpc.Header(&header))
+
+	// Then
+	require.Equal(t, codes.ResourceExhausted, status.Code(err))
+	require.Equal(t, []string{"60"}, header.Get("retry-after"))
+
+	_, err = s.client.ResolveLink(ctx, &linkv1.ResolveLinkRequest{Id: other.Id})
+	require.NoError(t, err, "the other links are not limited")
+
+	l, err := s.linkService.FindByID(ctx, int(created.Id))
+	require.NoError(t, err)
+	require.Equal(t, 1, l.Count, "the rejected call must not be counted")
+}
@@ -0,0 +1,138 @@
+// Package rpc serves the link service over gRPC, as described by api/link/v1. It shares the link.Service
+// of the HTTP API, so both see the same links.
+package rpc
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"runtime/debug"
+	"time"
+
+	linkv1 "github.com/emacampolo/link-tracker/api/link/v1"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Server is the gRPC server of the link service.
+type Server struct {
+	grpc *grpc.Server
+	// done is closed when the server starts shutting down, to end the streams that would never finish.
+	done chan struct{}
+	// shutdownTimeout is the maximum amount of time to wait for the calls in progress when shutting down.
+	shutdownTimeout time.Duration
+}
+
+// Option configures optional behaviour of the Server.
+type Option func(*options)
+
+type options struct {
+	limiter *RateLimiter
+}
+
+// WithRateLimiter applies the policies of rl to the unary calls.
+func WithRateLimiter(rl *RateLimiter) Option {
+	return func(o *options) {
+		o.limiter = rl
+	}
+}
+
+// NewServer creates a Server of the links of linkService, whose clicks are streamed from clickStream.
+func NewServer(linkService link.Service, clickStream *link.ClickStream, opts ...Option) *Server {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	unary := []grpc.UnaryServerInterceptor{recoverUnary}
+	if o.limiter != nil {
+		unary = append(unary, o.limiter.unary)
+	}
+
+	s := &Server{
+		grpc: grpc.NewServer(
+			grpc.ChainUnaryInterceptor(unary...),
+			grpc.StreamInterceptor(recoverStream),
+		),
+		done:            make(chan struct{}),
+		shutdownTimeout: web.DefaultShutdownTimeout,
+	}
+
+	linkv1.RegisterLinkServiceServer(s.grpc, &linkServer{
+		linkService: linkService,
+		clickStream: clickStream,
+		done:        s.done,
+	})
+
+	return s
+}
+
+// ListenAndServe listens on the TCP address addr and calls Serve.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	return s.Serve(ctx, lis)
+}
+
+// Serve accepts connections on lis and blocks until ctx is done, when it stops gracefully: the click
+// streams are ended, and the other calls in progress are given DefaultShutdownTimeout to finish.
+// A Server can only be served once.
+func (s *Server) Serve(ctx context.Context, lis net.Listener) error {
+	serverErrors := make(chan error, 1)
+
+	go func() {
+		log.Printf("gRPC listening on %s", lis.Addr())
+		serverErrors <- s.grpc.Serve(lis)
+	}()
+
+	select {
+	case err := <-serverErrors:
+		return fmt.Errorf("error in Serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	close(s.done)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	timer := time.NewTimer(s.shutdownTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-stopped:
+	case <-timer.C:
+		// The calls did not finish in time, so the connections are closed under them.
+		s.grpc.Stop()
+	}
+
+	return nil
+}
+
+// recoverUnary answers a call that panics with an internal error, as the Recoverer middleware does for HTTP.
+func recoverUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer recoverPanic(info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+func recoverStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recoverPanic(info.FullMethod, &err)
+	return handler(srv, ss)
+}
+
+func recoverPanic(method string, err *error) {
+	if p := recover(); p != nil {
+		log.Printf("panic in %s: %v\n%s", method, p, debug.Stack())
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
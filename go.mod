@@ -4,6 +4,12 @@ go 1.16
 
 require (
 	github.com/go-chi/chi/v5 v5.0.3
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/stretchr/testify v1.7.0
+	github.com/vmihailenco/msgpack/v5 v5.3.4
 	golang.org/x/crypto v0.0.0-20210513164829-c07d793c2f9a
+	golang.org/x/net v0.0.0-20210525063256-abc453219eb5
+	golang.org/x/sys v0.0.0-20210423082822-04245dca01da
+	google.golang.org/grpc v1.43.0
+	google.golang.org/protobuf v1.27.1
 )
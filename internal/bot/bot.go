@@ -0,0 +1,170 @@
+// Package bot tells apart the requests made by crawlers, link unfurlers and browser
+// prefetches from the ones made by people.
+package bot
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/filewatch"
+)
+
+//go:embed rules.json
+var defaultRules []byte
+
+// Rules is the definition of what is considered a bot. It is usually read from a JSON file.
+type Rules struct {
+	// UserAgents are regular expressions matched against the User-Agent header.
+	UserAgents []string `json:"user_agents"`
+	// EmptyUserAgent classifies the requests without a User-Agent header as bots.
+	EmptyUserAgent bool `json:"empty_user_agent"`
+	// Methods are the HTTP methods only used by bots, like HEAD.
+	Methods []string `json:"methods"`
+	// Headers maps a header name to a value that marks a request as a prefetch or a preview.
+	// The value is matched case-insensitively against any of the comma or semicolon separated tokens of the header.
+	Headers map[string]string `json:"headers"`
+}
+
+// Verdict is the result of classifying a request.
+type Verdict struct {
+	Bot bool
+	// Reason explains why the request was classified as a bot.
+	Reason string
+}
+
+type compiledRules struct {
+	userAgents     []*regexp.Regexp
+	emptyUserAgent bool
+	methods        map[string]struct{}
+	headers        map[string]string
+}
+
+// Classifier classifies requests using a set of Rules that can be replaced while it is in use.
+type Classifier struct {
+	mu    sync.RWMutex
+	rules compiledRules
+	file  *filewatch.File
+}
+
+// NewClassifier creates a Classifier with the given rules.
+func NewClassifier(r Rules) (*Classifier, error) {
+	c := &Classifier{}
+	c.file = filewatch.New(func(data []byte) error {
+		var r Rules
+		if err := json.Unmarshal(data, &r); err != nil {
+			return err
+		}
+
+		return c.SetRules(r)
+	})
+
+	if err := c.SetRules(r); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// NewDefaultClassifier creates a Classifier with the rules shipped with the binary,
+// which cover the most common crawlers, link unfurlers and prefetch headers.
+func NewDefaultClassifier() *Classifier {
+	var r Rules
+	if err := json.Unmarshal(defaultRules, &r); err != nil {
+		panic(err)
+	}
+
+	c, err := NewClassifier(r)
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+// SetRules replaces the rules used by the Classifier. It leaves the current rules in place if r is invalid.
+func (c *Classifier) SetRules(r Rules) error {
+	compiled := compiledRules{
+		emptyUserAgent: r.EmptyUserAgent,
+		methods:        make(map[string]struct{}, len(r.Methods)),
+		headers:        make(map[string]string, len(r.Headers)),
+	}
+
+	for _, expr := range r.UserAgents {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("compiling user agent pattern %q: %w", expr, err)
+		}
+
+		compiled.userAgents = append(compiled.userAgents, re)
+	}
+
+	for _, m := range r.Methods {
+		compiled.methods[strings.ToUpper(m)] = struct{}{}
+	}
+
+	for name, value := range r.Headers {
+		compiled.headers[http.CanonicalHeaderKey(name)] = strings.ToLower(value)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rules = compiled
+	return nil
+}
+
+// Load replaces the rules of the Classifier with the ones in the JSON file at path.
+func (c *Classifier) Load(path string) error {
+	return c.file.Load(path)
+}
+
+// Watch reloads the rules from path every time the file is modified, as described by filewatch.File.Watch.
+func (c *Classifier) Watch(ctx context.Context, path string, interval time.Duration) {
+	c.file.Watch(ctx, path, interval)
+}
+
+// Classify decides whether r was made by a bot.
+func (c *Classifier) Classify(r *http.Request) Verdict {
+	c.mu.RLock()
+	rules := c.rules
+	c.mu.RUnlock()
+
+	if _, ok := rules.methods[r.Method]; ok {
+		return Verdict{Bot: true, Reason: "method " + r.Method}
+	}
+
+	for name, value := range rules.headers {
+		for _, token := range strings.FieldsFunc(strings.ToLower(r.Header.Get(name)), isSeparator) {
+			if strings.TrimSpace(token) == value {
+				return Verdict{Bot: true, Reason: "header " + name}
+			}
+		}
+	}
+
+	ua := r.UserAgent()
+	if ua == "" {
+		if rules.emptyUserAgent {
+			return Verdict{Bot: true, Reason: "empty user agent"}
+		}
+
+		return Verdict{}
+	}
+
+	for _, re := range rules.userAgents {
+		if re.MatchString(ua) {
+			return Verdict{Bot: true, Reason: "user agent " + re.String()}
+		}
+	}
+
+	return Verdict{}
+}
+
+func isSeparator(r rune) bool {
+	return r == ',' || r == ';'
+}
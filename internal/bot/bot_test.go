@@ -0,0 +1,145 @@
+package bot_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/bot"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifier_Classify(t *testing.T) {
+	tt := []struct {
+		name      string
+		method    string
+		userAgent string
+		header    http.Header
+		wantBot   bool
+	}{
+		{
+			name:      "browser",
+			userAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/14.1 Safari/605.1.15",
+			wantBot:   false,
+		},
+		{
+			name:      "curl",
+			userAgent: "curl/7.64.1",
+			wantBot:   false,
+		},
+		{
+			name:      "search engine crawler",
+			userAgent: "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)",
+			wantBot:   true,
+		},
+		{
+			name:      "chat unfurler",
+			userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			wantBot:   true,
+		},
+		{
+			name:      "social network unfurler",
+			userAgent: "facebookexternalhit/1.1 (+http://www.facebook.com/externalhit_uatext.php)",
+			wantBot:   true,
+		},
+		{
+			name:    "empty user agent",
+			wantBot: true,
+		},
+		{
+			name:      "head request",
+			method:    http.MethodHead,
+			userAgent: "Mozilla/5.0",
+			wantBot:   true,
+		},
+		{
+			name:      "browser prefetch",
+			userAgent: "Mozilla/5.0",
+			header:    http.Header{"Sec-Purpose": []string{"prefetch;prerender"}},
+			wantBot:   true,
+		},
+		{
+			name:      "legacy prefetch",
+			userAgent: "Mozilla/5.0",
+			header:    http.Header{"X-Moz": []string{"Prefetch"}},
+			wantBot:   true,
+		},
+	}
+
+	classifier := bot.NewDefaultClassifier()
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, "/link/1", nil)
+			req.Header.Del("User-Agent")
+			for name, values := range tc.header {
+				req.Header[name] = values
+			}
+
+			if tc.userAgent != "" {
+				req.Header.Set("User-Agent", tc.userAgent)
+			}
+
+			// When
+			verdict := classifier.Classify(req)
+
+			// Then
+			require.Equal(t, tc.wantBot, verdict.Bot, verdict.Reason)
+			if tc.wantBot {
+				require.NotEmpty(t, verdict.Reason)
+			}
+		})
+	}
+}
+
+func TestClassifier_Watch(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"user_agents":["(?i)firstbot"]}`), 0o600))
+
+	classifier := bot.NewDefaultClassifier()
+	require.NoError(t, classifier.Load(path))
+
+	req := httptest.NewRequest(http.MethodGet, "/link/1", nil)
+	req.Header.Set("User-Agent", "SecondBot/1.0")
+	require.False(t, classifier.Classify(req).Bot)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go classifier.Watch(ctx, path, time.Millisecond)
+
+	// When
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"user_agents":["(?i)secondbot"]}`), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	// Then
+	require.Eventually(t, func() bool { return classifier.Classify(req).Bot }, time.Second, time.Millisecond)
+}
+
+func TestClassifier_Load_InvalidRules(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"user_agents":["("]}`), 0o600))
+	classifier := bot.NewDefaultClassifier()
+
+	// When
+	err := classifier.Load(path)
+
+	// Then
+	require.Error(t, err)
+
+	req := httptest.NewRequest(http.MethodGet, "/link/1", nil)
+	req.Header.Set("User-Agent", "Googlebot/2.1")
+	require.True(t, classifier.Classify(req).Bot, "previous rules must be kept")
+}
@@ -0,0 +1,189 @@
+// Package healthcheck periodically requests the URL of the links to find out whether they are still reachable.
+package healthcheck
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/safehttp"
+)
+
+// Store is the subset of link.Service used by the Checker to keep the health of the links.
+type Store interface {
+	List(ctx context.Context, owner string) ([]link.Link, error)
+	RecordCheck(ctx context.Context, ID int, c link.Check) error
+}
+
+// Config controls how often and how fast the links are checked.
+type Config struct {
+	// Interval is the time between two rounds of checks of every active link.
+	Interval time.Duration
+	// Concurrency is the number of hosts checked at the same time.
+	Concurrency int
+	// HostDelay is the time waited between two requests to the same host. The links of a host
+	// are always checked one at a time, so a round never floods any destination.
+	HostDelay time.Duration
+	// Timeout is the maximum amount of time of a check, including redirects.
+	Timeout      time.Duration
+	MaxRedirects int
+	// AllowPrivate disables the protection against requesting private addresses. It is meant for tests.
+	AllowPrivate bool
+	UserAgent    string
+}
+
+// DefaultConfig is the Config used by the server.
+var DefaultConfig = Config{
+	Interval:     15 * time.Minute,
+	Concurrency:  8,
+	HostDelay:    time.Second,
+	Timeout:      10 * time.Second,
+	MaxRedirects: 5,
+	UserAgent:    "link-tracker-healthcheck/1.0",
+}
+
+// Checker records the result of requesting the URL of every active link in its Store.
+type Checker struct {
+	store  Store
+	cfg    Config
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewChecker(s Store, cfg Config) *Checker {
+	return &Checker{
+		store: s,
+		cfg:   cfg,
+		client: safehttp.NewClient(safehttp.Config{
+			Timeout:      cfg.Timeout,
+			MaxRedirects: cfg.MaxRedirects,
+			AllowPrivate: cfg.AllowPrivate,
+		}),
+		now: time.Now,
+	}
+}
+
+// Run checks every active link right away and then every Interval, until ctx is done.
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll checks every active link once and returns when all of them have been checked. Secret links are
+// not checked, since their destination may be a page that only works once.
+func (c *Checker) CheckAll(ctx context.Context) {
+	links, err := c.store.List(ctx, "")
+	if err != nil {
+		log.Printf("healthcheck: listing links: %v", err)
+		return
+	}
+
+	hosts := make(map[string][]link.Link)
+	var order []string
+	for _, l := range links {
+		if l.Inactive || l.Secret() {
+			continue
+		}
+
+		host := l.URL
+		if u, err := url.Parse(l.URL); err == nil {
+			host = u.Host
+		}
+
+		if _, ok := hosts[host]; !ok {
+			order = append(order, host)
+		}
+
+		hosts[host] = append(hosts[host], l)
+	}
+
+	jobs := make(chan []link.Link)
+	var wg sync.WaitGroup
+	for i := 0; i < c.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for links := range jobs {
+				c.checkHost(ctx, links)
+			}
+		}()
+	}
+
+	for _, host := range order {
+		select {
+		case <-ctx.Done():
+		case jobs <- hosts[host]:
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+}
+
+// checkHost checks the links of the same host one after the other, waiting HostDelay between them.
+func (c *Checker) checkHost(ctx context.Context, links []link.Link) {
+	for i, l := range links {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(c.cfg.HostDelay):
+			}
+		}
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err := c.store.RecordCheck(ctx, l.ID, c.Check(ctx, l.URL)); err != nil {
+			log.Printf("healthcheck: recording check of link %d: %v", l.ID, err)
+		}
+	}
+}
+
+// Check requests rawURL with HEAD. Since some servers do not implement HEAD properly,
+// the request is repeated with GET when HEAD does not succeed.
+func (c *Checker) Check(ctx context.Context, rawURL string) link.Check {
+	start := c.now()
+
+	check := c.request(ctx, http.MethodHead, rawURL)
+	if !check.OK() {
+		check = c.request(ctx, http.MethodGet, rawURL)
+	}
+
+	check.Time = start
+	check.Duration = c.now().Sub(start)
+	return check
+}
+
+func (c *Checker) request(ctx context.Context, method, rawURL string) link.Check {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, nil)
+	if err != nil {
+		return link.Check{Err: err.Error()}
+	}
+
+	req.Header.Set("User-Agent", c.cfg.UserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return link.Check{Err: err.Error()}
+	}
+
+	// The body is not needed, and reading it could take long, so the connection is not reused.
+	resp.Body.Close()
+	return link.Check{StatusCode: resp.StatusCode}
+}
@@ -0,0 +1,191 @@
+package healthcheck_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/healthcheck"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+)
+
+func testConfig() healthcheck.Config {
+	cfg := healthcheck.DefaultConfig
+	cfg.AllowPrivate = true
+	cfg.Timeout = time.Second
+	cfg.HostDelay = 0
+	return cfg
+}
+
+func TestChecker_Check(t *testing.T) {
+	tt := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantOK     bool
+	}{
+		{
+			name:       "ok",
+			handler:    func(w http.ResponseWriter, r *http.Request) {},
+			wantStatus: http.StatusOK,
+			wantOK:     true,
+		},
+		{
+			name: "head not allowed",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.Method == http.MethodHead {
+					w.WriteHeader(http.StatusMethodNotAllowed)
+				}
+			},
+			wantStatus: http.StatusOK,
+			wantOK:     true,
+		},
+		{
+			name: "not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name: "redirect to a working page",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/" {
+					http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+				}
+			},
+			wantStatus: http.StatusOK,
+			wantOK:     true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			checker := healthcheck.NewChecker(nil, testConfig())
+
+			// When
+			check := checker.Check(context.Background(), server.URL+"/")
+
+			// Then
+			require.Equal(t, tc.wantStatus, check.StatusCode)
+			require.Equal(t, tc.wantOK, check.OK())
+			require.False(t, check.Time.IsZero())
+		})
+	}
+}
+
+func TestChecker_Check_PrivateAddress(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	checker := healthcheck.NewChecker(nil, healthcheck.DefaultConfig)
+
+	// When
+	check := checker.Check(context.Background(), server.URL)
+
+	// Then
+	require.False(t, check.OK())
+	require.Contains(t, check.Err, "destination address is not public")
+}
+
+func TestChecker_CheckAll(t *testing.T) {
+	// Given
+	var inFlight, maxInFlight int32
+	var mu sync.Mutex
+	paths := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		mu.Lock()
+		paths[r.URL.Path]++
+		mu.Unlock()
+
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	service := link.NewService(link.NewInMemoryRepository(), link.WithFailureThreshold(1))
+	ok, err := service.Create(ctx, link.NewLink{URL: server.URL + "/ok", Password: "1234"})
+	require.NoError(t, err)
+	gone, err := service.Create(ctx, link.NewLink{URL: server.URL + "/gone", Password: "1234", FallbackURL: "https://example.com"})
+	require.NoError(t, err)
+	inactive, err := service.Create(ctx, link.NewLink{URL: server.URL + "/inactive", Password: "1234"})
+	require.NoError(t, err)
+	require.NoError(t, service.Inactivate(ctx, inactive.ID))
+
+	checker := healthcheck.NewChecker(service, testConfig())
+
+	// When
+	checker.CheckAll(ctx)
+
+	// Then
+	require.EqualValues(t, 1, maxInFlight, "the links of the same host must be checked one at a time")
+	require.Zero(t, paths["/inactive"])
+
+	ok, err = service.FindByID(ctx, ok.ID)
+	require.NoError(t, err)
+	require.False(t, ok.Health.Failing)
+	require.Len(t, ok.Health.History, 1)
+
+	gone, err = service.FindByID(ctx, gone.ID)
+	require.NoError(t, err)
+	require.True(t, gone.Health.Failing)
+	require.Equal(t, http.StatusGone, gone.Health.History[0].StatusCode)
+	require.Equal(t, "https://example.com", gone.Destination())
+}
+
+func TestChecker_Run(t *testing.T) {
+	// Given
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	service := link.NewService(link.NewInMemoryRepository())
+	l, err := service.Create(ctx, link.NewLink{URL: server.URL, Password: "1234"})
+	require.NoError(t, err)
+
+	cfg := testConfig()
+	cfg.Interval = 10 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		healthcheck.NewChecker(service, cfg).Run(ctx)
+		close(done)
+	}()
+
+	// Then
+	require.Eventually(t, func() bool {
+		l, _ = service.FindByID(ctx, l.ID)
+		return len(l.Health.History) >= 3
+	}, 2*time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,132 @@
+package link
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/keyring"
+)
+
+// ErrAccessDisabled is returned when granting access without a key ring, see WithKeyRing.
+var ErrAccessDisabled = errors.New("signed access is not enabled")
+
+// ErrInvalidAccess is returned when granting an access that could never be used.
+var ErrInvalidAccess = errors.New("invalid access")
+
+// Access grants the visits to a Link without its password until it expires. It is signed with a key
+// of the key ring of the Service, so it is no longer valid once the key is revoked.
+type Access struct {
+	LinkID  int
+	KeyID   string
+	Expires time.Time
+	// IPRange restricts the visits to the clients within a CIDR. Any client is allowed if empty.
+	IPRange   string
+	Signature []byte
+}
+
+// NewAccess contains the information needed to grant an Access.
+type NewAccess struct {
+	// Password or access code of the Link, to prove that whoever grants the access is allowed to visit it.
+	Password string
+	Expires  time.Time
+	IPRange  string
+}
+
+// WithKeyRing makes the Service accept an Access instead of the password of a Link, signing them with the keys of r.
+func WithKeyRing(r *keyring.Ring) Option {
+	return func(s *service) {
+		s.keyRing = r
+	}
+}
+
+// GrantAccess signs an Access to the Link identified by ID with the current key.
+func (s *service) GrantAccess(ctx context.Context, ID int, na NewAccess) (Access, error) {
+	if s.keyRing == nil {
+		return Access{}, ErrAccessDisabled
+	}
+
+	link, err := s.repository.FindByID(ctx, ID)
+	if err != nil {
+		return Access{}, ErrNotFound
+	}
+
+	if _, err := s.authenticate(link, na.Password); err != nil {
+		return Access{}, err
+	}
+
+	if link.Inactive {
+		return Access{}, ErrInactive
+	}
+
+	if !na.Expires.After(s.now()) {
+		return Access{}, fmt.Errorf("%w: it must expire in the future", ErrInvalidAccess)
+	}
+
+	a := Access{
+		LinkID:  ID,
+		Expires: na.Expires.Truncate(time.Second),
+	}
+
+	if na.IPRange != "" {
+		_, network, err := net.ParseCIDR(na.IPRange)
+		if err != nil {
+			return Access{}, fmt.Errorf("%w: %v", ErrInvalidAccess, err)
+		}
+
+		a.IPRange = network.String()
+	}
+
+	key, err := s.keyRing.Current()
+	if err != nil {
+		return Access{}, err
+	}
+
+	a.KeyID = key.ID
+	a.Signature = a.sign(key.Secret)
+	return a, nil
+}
+
+// verifyAccess checks that a is a valid Access to the link identified by ID for a client at ip.
+// The signature is compared in constant time.
+func (s *service) verifyAccess(ID int, a Access, ip string) error {
+	if s.keyRing == nil || a.LinkID != ID {
+		return ErrAuthentication
+	}
+
+	key, ok := s.keyRing.Get(a.KeyID)
+	if !ok {
+		return ErrAuthentication
+	}
+
+	if !hmac.Equal(a.sign(key.Secret), a.Signature) {
+		return ErrAuthentication
+	}
+
+	if !s.now().Before(a.Expires) {
+		return ErrAuthentication
+	}
+
+	if a.IPRange != "" {
+		_, network, err := net.ParseCIDR(a.IPRange)
+		if err != nil {
+			return ErrAuthentication
+		}
+
+		if clientIP := net.ParseIP(ip); clientIP == nil || !network.Contains(clientIP) {
+			return ErrAuthentication
+		}
+	}
+
+	return nil
+}
+
+func (a Access) sign(secret []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%d\n%d\n%s", a.LinkID, a.Expires.Unix(), a.IPRange)
+	return mac.Sum(nil)
+}
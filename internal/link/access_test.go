@@ -0,0 +1,172 @@
+package link_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/keyring"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_GrantAccess(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	keys := []keyring.Key{
+		{ID: "old", Secret: bytes.Repeat([]byte{1}, keyring.MinSecretSize)},
+		{ID: "new", Secret: bytes.Repeat([]byte{2}, keyring.MinSecretSize)},
+	}
+
+	tt := []struct {
+		name    string
+		access  link.NewAccess
+		tamper  func(a *link.Access)
+		revoke  bool
+		ip      string
+		after   time.Duration
+		wantErr error
+	}{
+		{
+			name:   "valid",
+			access: link.NewAccess{Password: "1234", Expires: now.Add(time.Hour)},
+		},
+		{
+			name:    "expired",
+			access:  link.NewAccess{Password: "1234", Expires: now.Add(time.Hour)},
+			after:   time.Hour,
+			wantErr: link.ErrAuthentication,
+		},
+		{
+			name:    "revoked key",
+			access:  link.NewAccess{Password: "1234", Expires: now.Add(time.Hour)},
+			revoke:  true,
+			wantErr: link.ErrAuthentication,
+		},
+		{
+			name:    "extended expiration",
+			access:  link.NewAccess{Password: "1234", Expires: now.Add(time.Hour)},
+			tamper:  func(a *link.Access) { a.Expires = a.Expires.Add(time.Hour) },
+			wantErr: link.ErrAuthentication,
+		},
+		{
+			name:    "another link",
+			access:  link.NewAccess{Password: "1234", Expires: now.Add(time.Hour)},
+			tamper:  func(a *link.Access) { a.LinkID++ },
+			wantErr: link.ErrAuthentication,
+		},
+		{
+			name:   "within ip range",
+			access: link.NewAccess{Password: "1234", Expires: now.Add(time.Hour), IPRange: "10.0.0.0/8"},
+			ip:     "10.1.2.3",
+		},
+		{
+			name:    "outside ip range",
+			access:  link.NewAccess{Password: "1234", Expires: now.Add(time.Hour), IPRange: "10.0.0.0/8"},
+			ip:      "192.168.1.1",
+			wantErr: link.ErrAuthentication,
+		},
+		{
+			name:    "ip range removed",
+			access:  link.NewAccess{Password: "1234", Expires: now.Add(time.Hour), IPRange: "10.0.0.0/8"},
+			tamper:  func(a *link.Access) { a.IPRange = "" },
+			ip:      "192.168.1.1",
+			wantErr: link.ErrAuthentication,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			ctx := context.Background()
+			clock := now
+			ring, err := keyring.New(keyring.Keys{Keys: keys})
+			require.NoError(t, err)
+
+			repository := link.NewInMemoryRepository()
+			service := link.NewService(repository, fastHasher, link.WithKeyRing(ring), link.WithClock(func() time.Time { return clock }))
+			l := saveLink(t, repository, "1234")
+
+			a, err := service.GrantAccess(ctx, l.ID, tc.access)
+			require.NoError(t, err)
+			require.Equal(t, "new", a.KeyID)
+
+			if tc.tamper != nil {
+				tc.tamper(&a)
+			}
+
+			if tc.revoke {
+				keys := []keyring.Key{keys[0], keys[1]}
+				keys[1].Revoked = true
+				require.NoError(t, ring.Set(keyring.Keys{Keys: keys}))
+			}
+
+			clock = clock.Add(tc.after)
+
+			// When
+			_, err = service.Redirect(ctx, l.ID, link.Visit{Access: &a, IP: tc.ip})
+
+			// Then
+			if tc.wantErr != nil {
+				require.True(t, errors.Is(err, tc.wantErr), err)
+				return
+			}
+
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestService_GrantAccess_Errors(t *testing.T) {
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	ring, err := keyring.New(keyring.Keys{Keys: []keyring.Key{{ID: "k", Secret: bytes.Repeat([]byte{1}, keyring.MinSecretSize)}}})
+	require.NoError(t, err)
+
+	tt := []struct {
+		name    string
+		opts    []link.Option
+		access  link.NewAccess
+		wantErr error
+	}{
+		{
+			name:    "disabled",
+			access:  link.NewAccess{Password: "1234", Expires: now.Add(time.Hour)},
+			wantErr: link.ErrAccessDisabled,
+		},
+		{
+			name:    "wrong password",
+			opts:    []link.Option{link.WithKeyRing(ring)},
+			access:  link.NewAccess{Password: "4321", Expires: now.Add(time.Hour)},
+			wantErr: link.ErrAuthentication,
+		},
+		{
+			name:    "already expired",
+			opts:    []link.Option{link.WithKeyRing(ring)},
+			access:  link.NewAccess{Password: "1234", Expires: now},
+			wantErr: link.ErrInvalidAccess,
+		},
+		{
+			name:    "invalid ip range",
+			opts:    []link.Option{link.WithKeyRing(ring)},
+			access:  link.NewAccess{Password: "1234", Expires: now.Add(time.Hour), IPRange: "10.0.0.1"},
+			wantErr: link.ErrInvalidAccess,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			repository := link.NewInMemoryRepository()
+			opts := append([]link.Option{fastHasher, link.WithClock(func() time.Time { return now })}, tc.opts...)
+			service := link.NewService(repository, opts...)
+			l := saveLink(t, repository, "1234")
+
+			// When
+			_, err := service.GrantAccess(context.Background(), l.ID, tc.access)
+
+			// Then
+			require.True(t, errors.Is(err, tc.wantErr), err)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package link
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+	"time"
+)
+
+// VerificationCache remembers the passwords recently verified for every Link, so the redirects to popular
+// links do not pay for hashing the password every time. The passwords are not stored: entries are keyed by
+// an HMAC of the link and the password, with a random key that never leaves the process.
+//
+// An entry is only used while the hash it was verified against is still in the Link, so changing a password
+// or an access code invalidates it. The entries of a Link are also dropped when it is inactivated.
+type VerificationCache struct {
+	mu       sync.Mutex
+	key      []byte
+	capacity int
+	ttl      time.Duration
+	entries  map[string]*list.Element
+	byLink   map[int]map[string]struct{}
+	// order has the most recently used entries at the front.
+	order *list.List
+	stats CacheStats
+}
+
+// CacheStats counts how the VerificationCache was used.
+type CacheStats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Size      int    `json:"size"`
+}
+
+type cacheEntry struct {
+	key     string
+	linkID  int
+	cred    credential
+	expires time.Time
+}
+
+// NewVerificationCache creates a cache of at most capacity verifications, each remembered for ttl.
+func NewVerificationCache(capacity int, ttl time.Duration) *VerificationCache {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+
+	return &VerificationCache{
+		key:      key,
+		capacity: capacity,
+		ttl:      ttl,
+		entries:  make(map[string]*list.Element),
+		byLink:   make(map[int]map[string]struct{}),
+		order:    list.New(),
+	}
+}
+
+// WithVerificationCache makes the Service remember the successful password verifications in c.
+func WithVerificationCache(c *VerificationCache) Option {
+	return func(s *service) {
+		s.cache = c
+	}
+}
+
+// Stats returns the usage of the cache so far.
+func (c *VerificationCache) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.order.Len()
+	return stats
+}
+
+// get returns the credential that password was verified as for l, if it is still valid at now.
+func (c *VerificationCache) get(l Link, password string, now time.Time) (credential, bool) {
+	key := c.keyOf(l.ID, password)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return credential{}, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if !now.Before(entry.expires) || !entry.cred.validFor(l, now) {
+		c.remove(elem)
+		c.stats.Misses++
+		return credential{}, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.cred, true
+}
+
+// put remembers that password was verified as cred for the link identified by ID.
+func (c *VerificationCache) put(ID int, password string, cred credential, now time.Time) {
+	key := c.keyOf(ID, password)
+	cred.rehash = nil
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	for c.order.Len() >= c.capacity && c.order.Len() > 0 {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, linkID: ID, cred: cred, expires: now.Add(c.ttl)})
+	if c.byLink[ID] == nil {
+		c.byLink[ID] = make(map[string]struct{})
+	}
+	c.byLink[ID][key] = struct{}{}
+}
+
+// invalidate drops the entries of the link identified by ID.
+func (c *VerificationCache) invalidate(ID int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key := range c.byLink[ID] {
+		c.remove(c.entries[key])
+	}
+}
+
+func (c *VerificationCache) remove(elem *list.Element) {
+	entry := c.order.Remove(elem).(*cacheEntry)
+	delete(c.entries, entry.key)
+
+	delete(c.byLink[entry.linkID], entry.key)
+	if len(c.byLink[entry.linkID]) == 0 {
+		delete(c.byLink, entry.linkID)
+	}
+}
+
+func (c *VerificationCache) keyOf(ID int, password string) string {
+	mac := hmac.New(sha256.New, c.key)
+	var id [8]byte
+	binary.BigEndian.PutUint64(id[:], uint64(ID))
+	mac.Write(id[:])
+	mac.Write([]byte(password))
+	return string(mac.Sum(nil))
+}
+
+// validFor tells whether the hash cred was verified against is still a password of l that has not expired.
+func (cred credential) validFor(l Link, now time.Time) bool {
+	if cred.code == "" {
+		return l.Password != nil && bytes.Equal(l.Password, cred.hash)
+	}
+
+	for _, c := range l.Codes {
+		if c.Name == cred.code {
+			return bytes.Equal(c.Hash, cred.hash) && (c.Expires.IsZero() || now.Before(c.Expires))
+		}
+	}
+
+	return false
+}
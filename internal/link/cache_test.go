@@ -0,0 +1,137 @@
+package link_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestVerificationCache(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	cache := link.NewVerificationCache(10, time.Minute)
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher, link.WithVerificationCache(cache), link.WithClock(func() time.Time { return now }))
+	l := saveLink(t, repository, "1234")
+
+	// When
+	_, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+	require.NoError(t, err)
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+	require.NoError(t, err)
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "4321"})
+	require.ErrorIs(t, err, link.ErrAuthentication)
+
+	// Then
+	require.Equal(t, link.CacheStats{Hits: 1, Misses: 2, Size: 1}, cache.Stats())
+
+	// When the entry expires.
+	now = now.Add(time.Minute)
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, link.CacheStats{Hits: 1, Misses: 3, Size: 1}, cache.Stats())
+}
+
+func TestVerificationCache_PasswordChanged(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	cache := link.NewVerificationCache(10, time.Minute)
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher, link.WithVerificationCache(cache))
+	l := saveLink(t, repository, "1234")
+
+	_, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+	require.NoError(t, err)
+
+	// When
+	hash, err := bcrypt.GenerateFromPassword([]byte("5678"), bcrypt.MinCost)
+	require.NoError(t, err)
+	_, err = repository.Modify(ctx, l.ID, func(l *link.Link) error {
+		l.Password = hash
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Then
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+	require.ErrorIs(t, err, link.ErrAuthentication)
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "5678"})
+	require.NoError(t, err)
+	require.Zero(t, cache.Stats().Hits)
+}
+
+func TestVerificationCache_Inactivated(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	cache := link.NewVerificationCache(10, time.Minute)
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher, link.WithVerificationCache(cache))
+	l := saveLink(t, repository, "1234")
+
+	_, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+	require.NoError(t, err)
+	require.Equal(t, 1, cache.Stats().Size)
+
+	// When
+	require.NoError(t, service.Inactivate(ctx, l.ID))
+
+	// Then
+	require.Zero(t, cache.Stats().Size)
+}
+
+func TestVerificationCache_Capacity(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	cache := link.NewVerificationCache(2, time.Minute)
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher, link.WithVerificationCache(cache))
+
+	// When
+	for i := 0; i < 3; i++ {
+		l := saveLink(t, repository, "1234")
+		_, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+		require.NoError(t, err)
+	}
+
+	// Then
+	require.Equal(t, link.CacheStats{Misses: 3, Evictions: 1, Size: 2}, cache.Stats())
+}
+
+func BenchmarkService_Redirect(b *testing.B) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("1234"), bcrypt.DefaultCost)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	for _, cached := range []bool{false, true} {
+		b.Run(fmt.Sprintf("cached=%t", cached), func(b *testing.B) {
+			ctx := context.Background()
+			repository := link.NewInMemoryRepository()
+			id, err := repository.Save(ctx, link.Link{URL: "https://www.google.com", Password: hash})
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			opts := []link.Option{link.WithVisitorSalt([]byte("salt"))}
+			if cached {
+				opts = append(opts, link.WithVerificationCache(link.NewVerificationCache(1000, time.Hour)))
+			}
+			service := link.NewService(repository, opts...)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := service.Redirect(ctx, id, link.Visit{Password: "1234"}); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
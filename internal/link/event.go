@@ -0,0 +1,127 @@
+package link
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EventType identifies what happened to a Link.
+type EventType string
+
+const (
+	// EventCreated is published after a Link has been stored.
+	EventCreated EventType = "link.created"
+
+	// EventVisited is published after a successful redirect.
+	EventVisited EventType = "link.visited"
+
+	// EventInactivated is published after a Link has been inactivated.
+	EventInactivated EventType = "link.inactivated"
+
+	// EventReactivated is published after an inactive Link has been reactivated.
+	EventReactivated EventType = "link.reactivated"
+
+	// EventThresholdReached is published when the Count of a Link reaches one of the configured visit thresholds.
+	EventThresholdReached EventType = "link.threshold_reached"
+
+	// EventOpened is published when a Link enters its Schedule.
+	EventOpened EventType = "link.opened"
+
+	// EventClosed is published when a Link leaves its Schedule.
+	EventClosed EventType = "link.closed"
+)
+
+// Valid reports whether t is one of the event types published by the Service.
+func (t EventType) Valid() bool {
+	switch t {
+	case EventCreated, EventVisited, EventInactivated, EventReactivated, EventThresholdReached, EventOpened, EventClosed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Event describes a change in the lifecycle of a Link.
+// It carries a snapshot of the Link at the time the event was published, without its password.
+type Event struct {
+	Type      EventType
+	LinkID    int
+	Owner     string
+	URL       string
+	Count     int
+	Threshold int
+	// Code is the name of the access code used in a visit, if any.
+	Code string
+	// Secret tells that the destination of the link must not be requested, see Link.Secret.
+	Secret bool
+	Time   time.Time
+}
+
+// Publisher is the interface used by the Service to notify that something happened to a Link.
+type Publisher interface {
+	Publish(ctx context.Context, e Event)
+}
+
+// Bus is an in-process Publisher that fans out every event to its subscribers.
+// Subscribers are called synchronously in the goroutine that publishes the event,
+// so they must not block; any slow work should be handed off to another goroutine.
+type Bus struct {
+	mu          sync.RWMutex
+	nextID      int
+	subscribers map[int]func(context.Context, Event)
+}
+
+// NewBus creates an empty Bus.
+func NewBus() *Bus {
+	return &Bus{
+		subscribers: make(map[int]func(context.Context, Event)),
+	}
+}
+
+// Subscribe registers fn to be called for every published event.
+// The returned function removes the subscription.
+func (b *Bus) Subscribe(fn func(context.Context, Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	id := b.nextID
+	b.subscribers[id] = fn
+
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers, id)
+	}
+}
+
+// Publish delivers e to all the current subscribers.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	b.mu.RLock()
+	subscribers := make([]func(context.Context, Event), 0, len(b.subscribers))
+	for _, fn := range b.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	b.mu.RUnlock()
+
+	for _, fn := range subscribers {
+		fn(ctx, e)
+	}
+}
+
+type nopPublisher struct{}
+
+func (nopPublisher) Publish(context.Context, Event) {}
+
+func newEvent(t EventType, l Link, now time.Time) Event {
+	return Event{
+		Type:   t,
+		LinkID: l.ID,
+		Owner:  l.Owner,
+		URL:    l.URL,
+		Count:  l.Count,
+		Secret: l.Secret(),
+		Time:   now,
+	}
+}
@@ -0,0 +1,65 @@
+package link_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBus_Publish(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	bus := link.NewBus()
+
+	var first, second []link.Event
+	bus.Subscribe(func(_ context.Context, e link.Event) { first = append(first, e) })
+	unsubscribe := bus.Subscribe(func(_ context.Context, e link.Event) { second = append(second, e) })
+
+	// When
+	bus.Publish(ctx, link.Event{Type: link.EventCreated, LinkID: 1})
+	unsubscribe()
+	bus.Publish(ctx, link.Event{Type: link.EventVisited, LinkID: 1})
+
+	// Then
+	require.Len(t, first, 2)
+	require.Len(t, second, 1)
+	require.Equal(t, link.EventCreated, second[0].Type)
+}
+
+func TestService_Redirect_PublishesEvents(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	password := "1234"
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := link.Link{ID: 1, URL: "https://www.google.com", Password: hash, Count: 9}
+
+	repositoryMock := &repositoryMock{}
+	repositoryMock.On("FindByID", ctx, l.ID).Return(l, nil)
+	repositoryMock.On("Update", ctx, mock.Anything).Return(nil)
+
+	bus := link.NewBus()
+	var events []link.Event
+	bus.Subscribe(func(_ context.Context, e link.Event) { events = append(events, e) })
+
+	service := link.NewService(repositoryMock, link.WithPublisher(bus), link.WithVisitThresholds(10))
+
+	// When
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: password})
+
+	// Then
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, link.EventVisited, events[0].Type)
+	require.Equal(t, 10, events[0].Count)
+	require.Equal(t, link.EventThresholdReached, events[1].Type)
+	require.Equal(t, 10, events[1].Threshold)
+	require.Equal(t, l.URL, events[1].URL)
+}
@@ -0,0 +1,417 @@
+package link
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrFileInUse is returned when opening a FileRepository whose file is open by another one, in this
+// process or in another.
+var ErrFileInUse = errors.New("links file in use")
+
+// ErrFileClosed is returned when changing a FileRepository that was closed.
+var ErrFileClosed = errors.New("links file closed")
+
+// FileRepository is a Repository that keeps the links in memory and appends every change to a file,
+// one JSON line with the whole link each, except for the sketches of its visitors, of which only the
+// bytes that changed are written. It is safe for concurrent use. The file is locked until Close, so
+// that no other process changes it at the same time.
+type FileRepository struct {
+	// mu serializes the changes with the writes of the file, so that it always has the last change.
+	mu   sync.Mutex
+	path string
+	// file is the locked file, open until Close, and size its length.
+	file   *os.File
+	size   int64
+	memory *InMemoryRepository
+	// records is the number of lines of the file, which is compacted when most of them are outdated.
+	records int
+}
+
+// fileLink is a Link as stored in the file. The time zone of its schedule is stored by name.
+type fileLink struct {
+	Link
+	TimeZone string `json:",omitempty"`
+	// Sketches is set when the visitor sketches of Link are left out, since they are 4KB each and a visit
+	// changes a few bytes of them at most. It holds the changes since the previous line of the link.
+	Sketches *sketchChanges `json:",omitempty"`
+}
+
+// sketchChanges are the changes of the visitor sketches of a link.
+type sketchChanges struct {
+	// Visitors is nil if the sketch did not change.
+	Visitors *bytesPatch `json:",omitempty"`
+	// DailyVisitors has the sketches of the days that changed or were added, and Dropped the days removed.
+	DailyVisitors map[string]bytesPatch `json:",omitempty"`
+	Dropped       []string              `json:",omitempty"`
+}
+
+// bytesPatch turns a slice into one of Len bytes, which is nil if Len is 0. Set holds pairs of offset
+// and value of the bytes that differ from the previous slice, or from zero beyond its end.
+type bytesPatch struct {
+	Len int
+	Set [][2]int `json:",omitempty"`
+}
+
+// OpenFileRepository opens the FileRepository stored at path, which is created if it does not exist.
+// The last line of a link is its current state. It returns ErrFileInUse if another FileRepository has
+// the file open. The FileRepository must be closed to let others open it.
+func OpenFileRepository(path string) (*FileRepository, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("opening %s: %w", path, err)
+	}
+
+	r := &FileRepository{path: path, file: f, memory: NewInMemoryRepository()}
+	if err := r.load(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// load reads the links of the file.
+func (r *FileRepository) load() error {
+	data, err := ioutil.ReadAll(r.file)
+	if err != nil {
+		return err
+	}
+	r.size = int64(len(data))
+
+	for i, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+
+		if !bytes.HasSuffix(line, []byte("\n")) {
+			// The last change was interrupted while being written, so it was never applied.
+			r.size -= int64(len(line))
+			if err := r.file.Truncate(r.size); err != nil {
+				return err
+			}
+			break
+		}
+
+		var fl fileLink
+		if err := json.Unmarshal(line, &fl); err != nil {
+			return fmt.Errorf("reading links from %s: line %d: %w", r.path, i+1, err)
+		}
+
+		if fl.ID < 1 || fl.ID > len(r.memory.m)+1 {
+			return fmt.Errorf("reading links from %s: line %d has ID %d", r.path, i+1, fl.ID)
+		}
+
+		if fl.TimeZone != "" {
+			if fl.Schedule.Location, err = time.LoadLocation(fl.TimeZone); err != nil {
+				return fmt.Errorf("reading links from %s: line %d: %w", r.path, i+1, err)
+			}
+		}
+
+		if fl.Sketches != nil {
+			if err := fl.Sketches.apply(r.memory.m[fl.ID], &fl.Link); err != nil {
+				return fmt.Errorf("reading links from %s: line %d: %w", r.path, i+1, err)
+			}
+		}
+
+		r.memory.m[fl.ID] = fl.Link
+		r.records++
+	}
+
+	return nil
+}
+
+// Close releases the file, after which the FileRepository can only be read.
+func (r *FileRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *FileRepository) Save(ctx context.Context, l Link) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l.ID = len(r.memory.m) + 1
+	if err := r.commit(l); err != nil {
+		return 0, err
+	}
+
+	return l.ID, nil
+}
+
+func (r *FileRepository) Update(ctx context.Context, l Link) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, err := r.memory.FindByID(ctx, l.ID); err != nil {
+		return err
+	}
+
+	return r.commit(l)
+}
+
+func (r *FileRepository) Modify(ctx context.Context, ID int, fn func(l *Link) error) (Link, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l, err := r.memory.FindByID(ctx, ID)
+	if err != nil {
+		return Link{}, err
+	}
+
+	if err := fn(&l); err != nil {
+		return Link{}, err
+	}
+
+	if err := r.commit(l); err != nil {
+		return Link{}, err
+	}
+
+	return l, nil
+}
+
+func (r *FileRepository) FindByID(ctx context.Context, ID int) (Link, error) {
+	return r.memory.FindByID(ctx, ID)
+}
+
+// List returns all the links sorted by ID.
+func (r *FileRepository) List(ctx context.Context) ([]Link, error) {
+	return r.memory.List(ctx)
+}
+
+// commit writes l to the file and then keeps it in memory, so that a change that could not be written
+// is not applied either. Once most lines of the file are outdated, it is compacted.
+func (r *FileRepository) commit(l Link) error {
+	r.memory.mu.RLock()
+	prev := r.memory.m[l.ID]
+	r.memory.mu.RUnlock()
+
+	if err := r.append(newFileChange(prev, l)); err != nil {
+		return err
+	}
+
+	r.memory.mu.Lock()
+	r.memory.m[l.ID] = l
+	r.memory.mu.Unlock()
+
+	r.records++
+	if r.records > 2*len(r.memory.m)+compactionSlack {
+		// The change is already written, so a failed compaction is just tried again with the next one.
+		_ = r.compact()
+	}
+
+	return nil
+}
+
+// compactionSlack are the outdated lines a file always tolerates, so that small files are not
+// compacted after almost every change.
+const compactionSlack = 100
+
+// append writes fl at the end of the file and waits for it to be on disk, so that the change survives
+// a crash once applied. A line left half written is removed, so that the file can still be read.
+func (r *FileRepository) append(fl fileLink) error {
+	if r.file == nil {
+		return ErrFileClosed
+	}
+
+	data, err := json.Marshal(fl)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if _, err := r.file.WriteAt(data, r.size); err != nil {
+		r.file.Truncate(r.size)
+		return err
+	}
+
+	if err := r.file.Sync(); err != nil {
+		r.file.Truncate(r.size)
+		return err
+	}
+
+	r.size += int64(len(data))
+	return nil
+}
+
+// compact replaces the file with a line per link, with its whole sketches. The new file is locked and
+// then renamed over the old one, so that it is never left half written nor unlocked.
+func (r *FileRepository) compact() error {
+	links, err := r.memory.List(context.Background())
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, l := range links {
+		if err := enc.Encode(newFileLink(l)); err != nil {
+			return err
+		}
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(r.path), filepath.Base(r.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := lockFile(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), r.path); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	// The old file is no longer reachable by its path, so its lock can be released.
+	r.file.Close()
+	r.file = tmp
+	r.size = int64(buf.Len())
+	r.records = len(links)
+	return nil
+}
+
+// newFileLink returns l as stored in the file.
+func newFileLink(l Link) fileLink {
+	fl := fileLink{Link: l}
+	if l.Schedule.Location != nil {
+		fl.TimeZone = l.Schedule.Location.String()
+		fl.Schedule.Location = nil
+	}
+
+	return fl
+}
+
+// newFileChange returns l as appended to the file after prev, its previous state, with only the
+// changes of its sketches.
+func newFileChange(prev, l Link) fileLink {
+	fl := newFileLink(l)
+	fl.Visitors = nil
+	fl.DailyVisitors = nil
+	fl.Sketches = &sketchChanges{}
+
+	if !bytes.Equal(prev.Visitors, l.Visitors) {
+		p := diffBytes(prev.Visitors, l.Visitors)
+		fl.Sketches.Visitors = &p
+	}
+
+	for day, sketch := range l.DailyVisitors {
+		if prevSketch, ok := prev.DailyVisitors[day]; ok && bytes.Equal(prevSketch, sketch) {
+			continue
+		}
+
+		if fl.Sketches.DailyVisitors == nil {
+			fl.Sketches.DailyVisitors = make(map[string]bytesPatch)
+		}
+		fl.Sketches.DailyVisitors[day] = diffBytes(prev.DailyVisitors[day], sketch)
+	}
+
+	for day := range prev.DailyVisitors {
+		if _, ok := l.DailyVisitors[day]; !ok {
+			fl.Sketches.Dropped = append(fl.Sketches.Dropped, day)
+		}
+	}
+	sort.Strings(fl.Sketches.Dropped)
+
+	return fl
+}
+
+// apply sets the sketches of l to the ones of prev, the previous state of l, with the changes of c.
+// The sketches of prev are not modified.
+func (c *sketchChanges) apply(prev Link, l *Link) error {
+	visitors := prev.Visitors
+	if c.Visitors != nil {
+		var err error
+		if visitors, err = c.Visitors.apply(prev.Visitors); err != nil {
+			return err
+		}
+	}
+
+	daily := make(map[string][]byte, len(prev.DailyVisitors)+len(c.DailyVisitors))
+	for day, sketch := range prev.DailyVisitors {
+		daily[day] = sketch
+	}
+	for _, day := range c.Dropped {
+		delete(daily, day)
+	}
+	for day, p := range c.DailyVisitors {
+		sketch, err := p.apply(prev.DailyVisitors[day])
+		if err != nil {
+			return fmt.Errorf("sketch of %s: %w", day, err)
+		}
+		daily[day] = sketch
+	}
+	if len(daily) == 0 {
+		daily = nil
+	}
+
+	l.Visitors = visitors
+	l.DailyVisitors = daily
+	return nil
+}
+
+// diffBytes returns the patch that turns old into b.
+func diffBytes(old, b []byte) bytesPatch {
+	p := bytesPatch{Len: len(b)}
+	for i, v := range b {
+		if (i < len(old) && old[i] != v) || (i >= len(old) && v != 0) {
+			p.Set = append(p.Set, [2]int{i, int(v)})
+		}
+	}
+
+	return p
+}
+
+// apply returns a copy of old patched by p.
+func (p bytesPatch) apply(old []byte) ([]byte, error) {
+	if p.Len == 0 {
+		return nil, nil
+	}
+
+	b := make([]byte, p.Len)
+	copy(b, old)
+	for _, set := range p.Set {
+		offset, value := set[0], set[1]
+		if offset < 0 || offset >= p.Len || value < 0 || value > 255 {
+			return nil, fmt.Errorf("invalid patch of byte %d to %d", offset, value)
+		}
+		b[offset] = byte(value)
+	}
+
+	return b, nil
+}
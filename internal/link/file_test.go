@@ -0,0 +1,252 @@
+package link_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRepository(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.json")
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	require.NoError(t, err)
+
+	repository, err := link.OpenFileRepository(path)
+	require.NoError(t, err)
+
+	scheduled := link.Link{
+		URL:      "https://www.google.com",
+		Password: []byte("hash"),
+		Codes:    []link.AccessCode{{Name: "bob", Hash: []byte("bob-hash"), Expires: time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)}},
+		Schedule: link.Schedule{
+			Windows:  []link.Window{{Days: []time.Weekday{time.Monday}, Start: 9 * time.Hour, End: 17 * time.Hour}},
+			Location: madrid,
+		},
+		DailyVisitors: map[string][]byte{"2021-06-01": []byte("sketch")},
+	}
+
+	// When
+	first, err := repository.Save(ctx, scheduled)
+	require.NoError(t, err)
+	second, err := repository.Save(ctx, newLink())
+	require.NoError(t, err)
+	_, err = repository.Modify(ctx, second, func(l *link.Link) error {
+		l.Count++
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, repository.Close())
+	reopened, err := link.OpenFileRepository(path)
+	require.NoError(t, err)
+	links, err := reopened.List(ctx)
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, 1, first)
+	require.Equal(t, 2, second)
+	require.Len(t, links, 2)
+
+	scheduled.ID = 1
+	require.Equal(t, "Europe/Madrid", links[0].Schedule.Location.String())
+	links[0].Schedule.Location = madrid
+	require.Equal(t, scheduled, links[0])
+	require.Equal(t, 11, links[1].Count)
+
+	third, err := reopened.Save(ctx, newLink())
+	require.NoError(t, err)
+	require.Equal(t, 3, third)
+}
+
+func TestFileRepository_NotFound(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	repository, err := link.OpenFileRepository(filepath.Join(t.TempDir(), "links.json"))
+	require.NoError(t, err)
+
+	// When
+	_, findErr := repository.FindByID(ctx, 1)
+	updateErr := repository.Update(ctx, link.Link{ID: 1})
+
+	// Then
+	require.ErrorIs(t, findErr, link.ErrNotFound)
+	require.ErrorIs(t, updateErr, link.ErrNotFound)
+}
+
+func TestFileRepository_WriteFails(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	repository, err := link.OpenFileRepository(filepath.Join(t.TempDir(), "links.json"))
+	require.NoError(t, err)
+	id, err := repository.Save(ctx, newLink())
+	require.NoError(t, err)
+	require.NoError(t, repository.Close())
+
+	// When
+	_, saveErr := repository.Save(ctx, newLink())
+	_, modifyErr := repository.Modify(ctx, id, func(l *link.Link) error {
+		l.Count++
+		return nil
+	})
+
+	// Then
+	require.ErrorIs(t, saveErr, link.ErrFileClosed)
+	require.ErrorIs(t, modifyErr, link.ErrFileClosed)
+
+	links, err := repository.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, links, 1)
+	require.Equal(t, 10, links[0].Count)
+}
+
+func TestFileRepository_Compaction(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.json")
+	repository, err := link.OpenFileRepository(path)
+	require.NoError(t, err)
+	id, err := repository.Save(ctx, newLink())
+	require.NoError(t, err)
+
+	// When
+	for i := 0; i < 500; i++ {
+		_, err := repository.Modify(ctx, id, func(l *link.Link) error {
+			l.Count++
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	// Then
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.LessOrEqual(t, bytes.Count(data, []byte("\n")), 102)
+
+	_, err = link.OpenFileRepository(path)
+	require.ErrorIs(t, err, link.ErrFileInUse, "the compacted file must still be locked")
+
+	require.NoError(t, repository.Close())
+	reopened, err := link.OpenFileRepository(path)
+	require.NoError(t, err)
+	l, err := reopened.FindByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, 510, l.Count)
+}
+
+func TestFileRepository_SketchChanges(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.json")
+	repository, err := link.OpenFileRepository(path)
+	require.NoError(t, err)
+
+	sketch := func(b byte) []byte {
+		s := make([]byte, 4097)
+		s[0], s[100] = 12, b
+		return s
+	}
+	l := newLink()
+	l.Visitors = sketch(1)
+	l.DailyVisitors = map[string][]byte{"2021-05-31": sketch(1), "2021-06-01": sketch(1)}
+	id, err := repository.Save(ctx, l)
+	require.NoError(t, err)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+
+	// When
+	want, err := repository.Modify(ctx, id, func(l *link.Link) error {
+		l.Count++
+		l.Visitors = sketch(2)
+		l.DailyVisitors = map[string][]byte{"2021-06-01": l.DailyVisitors["2021-06-01"], "2021-06-02": sketch(3)}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Then
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Less(t, len(data)-int(info.Size()), 1024, "only the changes of the sketches are appended")
+
+	require.NoError(t, repository.Close())
+	reopened, err := link.OpenFileRepository(path)
+	require.NoError(t, err)
+	got, err := reopened.FindByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+}
+
+func TestOpenFileRepository_InUse(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "links.json")
+	repository, err := link.OpenFileRepository(path)
+	require.NoError(t, err)
+
+	// When
+	_, inUseErr := link.OpenFileRepository(path)
+	require.NoError(t, repository.Close())
+	reopened, err := link.OpenFileRepository(path)
+
+	// Then
+	require.ErrorIs(t, inUseErr, link.ErrFileInUse)
+	require.NoError(t, err)
+	require.NoError(t, reopened.Close())
+}
+
+func TestOpenFileRepository_InterruptedWrite(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "links.json")
+	content := `{"ID":1,"URL":"https://www.google.com"}` + "\n" + `{"ID":1,"URL":"https://www.go`
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0o600))
+
+	// When
+	repository, err := link.OpenFileRepository(path)
+	require.NoError(t, err)
+	id, err := repository.Save(ctx, newLink())
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, 2, id)
+
+	require.NoError(t, repository.Close())
+	reopened, err := link.OpenFileRepository(path)
+	require.NoError(t, err)
+	links, err := reopened.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	require.Equal(t, "https://www.google.com", links[0].URL)
+}
+
+func TestOpenFileRepository_Invalid(t *testing.T) {
+	tt := []struct {
+		name    string
+		content string
+	}{
+		{name: "not json", content: "links\n"},
+		{name: "missing ids", content: `{"ID":2}` + "\n"},
+		{name: "unknown time zone", content: `{"ID":1,"TimeZone":"Mars/Olympus"}` + "\n"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			path := filepath.Join(t.TempDir(), "links.json")
+			require.NoError(t, ioutil.WriteFile(path, []byte(tc.content), 0o600))
+
+			// When
+			_, err := link.OpenFileRepository(path)
+
+			// Then
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package link
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// errUnknownHash is returned when verifying a hash produced by an algorithm that is not supported.
+var errUnknownHash = errors.New("unknown password hash")
+
+// Hasher hashes the passwords of the links. The hashes are tagged with the algorithm and the parameters
+// used to produce them, so they can be verified after the Hasher of the Service changes.
+type Hasher interface {
+	Hash(password []byte) ([]byte, error)
+	// Current tells whether hash was produced by this Hasher with its current parameters.
+	// When it was not, the hash is replaced after the next successful verification.
+	Current(hash []byte) bool
+}
+
+// WithHasher sets the Hasher used to hash the passwords of the new links, and to rehash the passwords of
+// the existing ones when they are verified. The default is bcrypt with bcrypt.DefaultCost.
+func WithHasher(h Hasher) Option {
+	return func(s *service) {
+		s.hasher = h
+	}
+}
+
+// BcryptHasher hashes passwords with bcrypt, in the format $2a$<cost>$<salt and hash>.
+type BcryptHasher struct {
+	Cost int
+}
+
+func (h BcryptHasher) Hash(password []byte) ([]byte, error) {
+	return bcrypt.GenerateFromPassword(password, h.Cost)
+}
+
+func (h BcryptHasher) Current(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err == nil && cost == h.Cost
+}
+
+// Argon2idHasher hashes passwords with argon2id, in the PHC string format:
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>.
+type Argon2idHasher struct {
+	// Memory is expressed in KiB.
+	Memory  uint32
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2idHasher follows the recommendation of RFC 9106 for memory constrained environments.
+var DefaultArgon2idHasher = Argon2idHasher{
+	Memory:  64 * 1024,
+	Time:    3,
+	Threads: 4,
+	SaltLen: 16,
+	KeyLen:  32,
+}
+
+const argon2idPrefix = "$argon2id$"
+
+func (h Argon2idHasher) Hash(password []byte) ([]byte, error) {
+	salt := make([]byte, h.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey(password, salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+	return []byte(fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))), nil
+}
+
+func (h Argon2idHasher) Current(hash []byte) bool {
+	params, salt, key, err := parseArgon2id(hash)
+	if err != nil {
+		return false
+	}
+
+	return params.Memory == h.Memory && params.Time == h.Time && params.Threads == h.Threads &&
+		uint32(len(salt)) == h.SaltLen && uint32(len(key)) == h.KeyLen
+}
+
+// parseArgon2id decodes a hash produced by Argon2idHasher.
+func parseArgon2id(hash []byte) (Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2idHasher{}, nil, nil, errUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2idHasher{}, nil, nil, errUnknownHash
+	}
+
+	var h Argon2idHasher
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &h.Memory, &h.Time, &h.Threads); err != nil {
+		return Argon2idHasher{}, nil, nil, errUnknownHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2idHasher{}, nil, nil, errUnknownHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2idHasher{}, nil, nil, errUnknownHash
+	}
+
+	return h, salt, key, nil
+}
+
+// compareHash tells whether hash was produced from password, by any of the supported algorithms.
+func compareHash(hash, password []byte) bool {
+	if bytes.HasPrefix(hash, []byte(argon2idPrefix)) {
+		h, salt, key, err := parseArgon2id(hash)
+		if err != nil {
+			return false
+		}
+
+		other := argon2.IDKey(password, salt, h.Time, h.Memory, h.Threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(key, other) == 1
+	}
+
+	return bcrypt.CompareHashAndPassword(hash, password) == nil
+}
@@ -0,0 +1,91 @@
+package link_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testArgon2id uses cheap parameters to keep the tests fast.
+var testArgon2id = link.Argon2idHasher{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func TestArgon2idHasher(t *testing.T) {
+	// Given
+	hash, err := testArgon2id.Hash([]byte("correct horse"))
+	require.NoError(t, err)
+
+	// Then
+	require.True(t, bytes.HasPrefix(hash, []byte("$argon2id$v=19$m=1024,t=1,p=1$")), string(hash))
+	require.True(t, testArgon2id.Current(hash))
+
+	stronger := testArgon2id
+	stronger.Time = 2
+	require.False(t, stronger.Current(hash))
+	require.False(t, testArgon2id.Current([]byte("$2a$10$abcdefghijklmnopqrstuu")))
+}
+
+func TestBcryptHasher_Current(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	require.True(t, link.BcryptHasher{Cost: bcrypt.MinCost}.Current(hash))
+	require.False(t, link.BcryptHasher{Cost: bcrypt.MinCost + 1}.Current(hash))
+	require.False(t, link.BcryptHasher{Cost: bcrypt.MinCost}.Current([]byte("$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$a2V5")))
+}
+
+func TestService_Redirect_Rehash(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	repository := link.NewInMemoryRepository()
+	l := saveLink(t, repository, "main")
+
+	codeHash, err := bcrypt.GenerateFromPassword([]byte("partner"), bcrypt.MinCost)
+	require.NoError(t, err)
+	_, err = repository.Modify(ctx, l.ID, func(l *link.Link) error {
+		l.Codes = []link.AccessCode{{Name: "acme", Hash: codeHash}}
+		return nil
+	})
+	require.NoError(t, err)
+
+	service := link.NewService(repository, link.WithHasher(testArgon2id))
+
+	// When
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "main"})
+	require.NoError(t, err)
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "partner"})
+	require.NoError(t, err)
+
+	// Then
+	l, err = service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.True(t, testArgon2id.Current(l.Password))
+	require.True(t, testArgon2id.Current(l.Codes[0].Hash))
+	require.Equal(t, 1, l.Codes[0].Count)
+
+	// The upgraded hashes keep working.
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "main"})
+	require.NoError(t, err)
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "partner"})
+	require.NoError(t, err)
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "wrong"})
+	require.ErrorIs(t, err, link.ErrAuthentication)
+}
+
+func TestService_Create_Argon2id(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	service := link.NewService(link.NewInMemoryRepository(), link.WithHasher(testArgon2id))
+
+	// When
+	l, err := service.Create(ctx, link.NewLink{URL: "https://www.google.com", Password: "correct horse"})
+	require.NoError(t, err)
+
+	// Then
+	require.True(t, bytes.HasPrefix(l.Password, []byte("$argon2id$")))
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "correct horse"})
+	require.NoError(t, err)
+}
@@ -0,0 +1,92 @@
+package link
+
+import (
+	"context"
+	"time"
+)
+
+// healthHistory is the number of checks kept in the Health of a Link.
+const healthHistory = 20
+
+// Health tells whether the URL of a Link is reachable, based on the most recent checks.
+type Health struct {
+	// Failing is true while the URL does not answer successfully. It is set after a number of consecutive
+	// failed checks, configured with WithFailureThreshold, and cleared by the first successful one.
+	Failing bool
+	// FailingSince is the time of the first failed check of the current failing period.
+	FailingSince        time.Time
+	ConsecutiveFailures int
+	// History contains the most recent checks, from the oldest to the newest.
+	History []Check
+}
+
+// Check is the result of requesting the URL of a Link.
+type Check struct {
+	Time       time.Time
+	StatusCode int
+	Duration   time.Duration
+	// Err is the reason why the request could not be completed, if it could not.
+	Err string
+}
+
+// OK tells whether the URL answered successfully. A 429 Too Many Requests response is considered
+// successful, since the destination is there, we are just not allowed to check it right now.
+func (c Check) OK() bool {
+	if c.Err != "" {
+		return false
+	}
+
+	return c.StatusCode < 400 || c.StatusCode == 429
+}
+
+// Destination returns where the visitors of the link are sent: its FallbackURL while its URL is failing, if it has one.
+func (l Link) Destination() string {
+	if l.Health.Failing && l.FallbackURL != "" {
+		return l.FallbackURL
+	}
+
+	return l.URL
+}
+
+// WithFailureThreshold sets the number of consecutive failed checks after which the URL of a Link is
+// considered failing and its FallbackURL is used. The default is 3.
+func WithFailureThreshold(n int) Option {
+	return func(s *service) {
+		s.failureThreshold = n
+	}
+}
+
+// RecordCheck adds c to the health history of a Link and updates whether its URL is failing.
+func (s *service) RecordCheck(ctx context.Context, ID int, c Check) error {
+	_, err := s.repository.Modify(ctx, ID, func(l *Link) error {
+		h := l.Health
+
+		history := make([]Check, 0, healthHistory)
+		if len(h.History) >= healthHistory {
+			history = append(history, h.History[len(h.History)-healthHistory+1:]...)
+		} else {
+			history = append(history, h.History...)
+		}
+		h.History = append(history, c)
+
+		if c.OK() {
+			h.Failing = false
+			h.FailingSince = time.Time{}
+			h.ConsecutiveFailures = 0
+		} else {
+			if h.ConsecutiveFailures == 0 {
+				h.FailingSince = c.Time
+			}
+
+			h.ConsecutiveFailures++
+			if h.ConsecutiveFailures >= s.failureThreshold {
+				h.Failing = true
+			}
+		}
+
+		l.Health = h
+		return nil
+	})
+
+	return err
+}
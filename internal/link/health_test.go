@@ -0,0 +1,121 @@
+package link_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_RecordCheck(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher, link.WithFailureThreshold(2))
+
+	l := saveLink(t, repository, "1234")
+	_, err := repository.Modify(ctx, l.ID, func(l *link.Link) error {
+		l.FallbackURL = "https://web.archive.org/web/https://www.google.com"
+		return nil
+	})
+	require.NoError(t, err)
+
+	redirect := func() string {
+		l, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+		require.NoError(t, err)
+		return l.Destination()
+	}
+
+	// When
+	require.NoError(t, service.RecordCheck(ctx, l.ID, link.Check{Time: now, StatusCode: 404}))
+
+	// Then
+	require.Equal(t, "https://www.google.com", redirect(), "a single failure is not enough")
+
+	// When
+	require.NoError(t, service.RecordCheck(ctx, l.ID, link.Check{Time: now.Add(time.Minute), Err: "connection refused"}))
+
+	// Then
+	require.Equal(t, "https://web.archive.org/web/https://www.google.com", redirect())
+
+	l, err = service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.True(t, l.Health.Failing)
+	require.Equal(t, now, l.Health.FailingSince)
+	require.Equal(t, 2, l.Health.ConsecutiveFailures)
+
+	// When
+	require.NoError(t, service.RecordCheck(ctx, l.ID, link.Check{Time: now.Add(2 * time.Minute), StatusCode: 200}))
+
+	// Then
+	require.Equal(t, "https://www.google.com", redirect())
+
+	l, err = service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.False(t, l.Health.Failing)
+	require.True(t, l.Health.FailingSince.IsZero())
+	require.Len(t, l.Health.History, 3)
+}
+
+func TestService_RecordCheck_History(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher)
+	l := saveLink(t, repository, "1234")
+
+	// When
+	for i := 0; i < 25; i++ {
+		require.NoError(t, service.RecordCheck(ctx, l.ID, link.Check{Time: now.Add(time.Duration(i) * time.Minute), StatusCode: 200}))
+	}
+
+	// Then
+	l, err := service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.Len(t, l.Health.History, 20)
+	require.Equal(t, now.Add(5*time.Minute), l.Health.History[0].Time)
+	require.Equal(t, now.Add(24*time.Minute), l.Health.History[19].Time)
+}
+
+func TestLink_Destination(t *testing.T) {
+	tt := []struct {
+		name string
+		link link.Link
+		want string
+	}{
+		{
+			name: "healthy",
+			link: link.Link{URL: "https://a.com", FallbackURL: "https://b.com"},
+			want: "https://a.com",
+		},
+		{
+			name: "failing with fallback",
+			link: link.Link{URL: "https://a.com", FallbackURL: "https://b.com", Health: link.Health{Failing: true}},
+			want: "https://b.com",
+		},
+		{
+			name: "failing without fallback",
+			link: link.Link{URL: "https://a.com", Health: link.Health{Failing: true}},
+			want: "https://a.com",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.link.Destination())
+		})
+	}
+}
+
+func TestCheck_OK(t *testing.T) {
+	require.True(t, link.Check{StatusCode: 200}.OK())
+	require.True(t, link.Check{StatusCode: 301}.OK())
+	require.True(t, link.Check{StatusCode: 429}.OK())
+	require.False(t, link.Check{StatusCode: 404}.OK())
+	require.False(t, link.Check{StatusCode: 503}.OK())
+	require.False(t, link.Check{Err: "timeout"}.OK())
+}
@@ -2,8 +2,12 @@ package link
 
 import (
 	"context"
+	"crypto/rand"
 	"errors"
+	"fmt"
+	"time"
 
+	"github.com/emacampolo/link-tracker/internal/platform/keyring"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -13,16 +17,79 @@ var ErrNotFound = errors.New("link not found")
 // ErrAuthentication is returned when the provided credentials cannot be validated.
 var ErrAuthentication = errors.New("authentication failed")
 
+// ErrPasswordRequired is the ErrAuthentication returned when no password is provided for a link that is not public.
+var ErrPasswordRequired = fmt.Errorf("%w: password is required", ErrAuthentication)
+
 // ErrInactive is returned when trying to redirect to a link that has been inactivated.
 var ErrInactive = errors.New("link is inactive")
 
+// ErrInvalidLink is returned when creating a Link whose options are not valid.
+var ErrInvalidLink = errors.New("invalid link")
+
 // Link represents an underlying URL with statistics on how it is used.
 type Link struct {
-	ID       int
-	URL      string
+	ID  int
+	URL string
+	// Password is the hash of the main password of the link. The link is public if it is nil and it has no Codes.
 	Password []byte
-	Count    int
+	// Codes are additional passwords, each with its own expiration and usage.
+	Codes []AccessCode
+	Owner string
+	// Title is a description of the destination provided by the owner, shown in the interstitial page.
+	Title string
+	// Interstitial makes every visitor go through a page showing the destination before being redirected.
+	Interstitial bool
+	Count        int
+	// PreviewCount is the number of times the interstitial page was shown, which are not included in Count.
+	PreviewCount int
+	// BotCount is the number of redirects requested by bots, which are not included in Count.
+	BotCount int
 	Inactive bool
+	// Visitors is an encoded HyperLogLog sketch of the fingerprints of the visitors.
+	Visitors []byte
+	// DailyVisitors holds a sketch per day, keyed by date in the format 2006-01-02 (UTC), for the last 30 days.
+	DailyVisitors map[string][]byte
+	// Metadata describes the destination page. It is fetched in the background, so it may be empty.
+	Metadata Metadata
+	// FallbackURL is where the visitors are sent while URL is failing. It is optional.
+	FallbackURL string
+	// Health is updated by checking URL in the background.
+	Health Health
+	// Schedule restricts when the link can be visited.
+	Schedule Schedule
+	// Closed tells whether the link was outside of its Schedule when it was last synced by the Scheduler.
+	Closed bool
+	// Transitions contains the most recent times the link entered or left its Schedule, from the oldest to the newest.
+	Transitions []Transition
+	// MaxVisits makes the link secret: it is inactivated after being visited that many times. Unlimited if zero.
+	MaxVisits int
+	// ConsumedAt is when a secret link was visited for the last time.
+	ConsumedAt time.Time
+}
+
+// Metadata describes the destination page of a Link, as found in its HTML.
+type Metadata struct {
+	Title       string
+	Description string
+	Image       string
+	FetchedAt   time.Time
+	// Err is the reason why the last fetch failed, if it did.
+	Err string
+}
+
+// NewLink contains the information needed to create a Link.
+type NewLink struct {
+	URL string
+	// Password is optional. The link is public if it is empty and there are no Codes.
+	Password string
+	Codes    []NewAccessCode
+	// Owner identifies who the link belongs to. It is optional.
+	Owner        string
+	Title        string
+	Interstitial bool
+	FallbackURL  string
+	Schedule     Schedule
+	MaxVisits    int
 }
 
 // Service encapsulates the business logic of a Link.
@@ -30,38 +97,149 @@ type Link struct {
 // since the underlying concrete implementation does not export any other method that is not in the interface,
 // we decided to define it where it is implemented rather where it is used (commonly in a handler).
 type Service interface {
-	Create(ctx context.Context, url, password string) (Link, error)
-	Redirect(ctx context.Context, ID int, password string) (Link, error)
+	Create(ctx context.Context, nl NewLink) (Link, error)
+	Redirect(ctx context.Context, ID int, v Visit) (Link, error)
+	Preview(ctx context.Context, ID int, v Visit) (Link, error)
 	FindByID(ctx context.Context, ID int) (Link, error)
+	List(ctx context.Context, owner string) ([]Link, error)
 	Inactivate(ctx context.Context, ID int) error
+	Reactivate(ctx context.Context, ID int) error
+	SetMetadata(ctx context.Context, ID int, m Metadata) error
+	RecordCheck(ctx context.Context, ID int, c Check) error
+	SyncSchedule(ctx context.Context, ID int) error
+	GrantAccess(ctx context.Context, ID int, na NewAccess) (Access, error)
 }
 
 // Repository encapsulates the storage of a Link.
 type Repository interface {
 	Save(ctx context.Context, l Link) (int, error)
 	Update(ctx context.Context, l Link) error
+	// Modify atomically applies fn to the stored Link and saves the result, unless fn returns an error.
+	// It is used to update a Link without overwriting concurrent changes.
+	Modify(ctx context.Context, ID int, fn func(l *Link) error) (Link, error)
 	FindByID(ctx context.Context, ID int) (Link, error)
+	List(ctx context.Context) ([]Link, error)
 }
 
 type service struct {
-	repository Repository
+	repository       Repository
+	publisher        Publisher
+	thresholds       map[int]struct{}
+	salt             []byte
+	failureThreshold int
+	keyRing          *keyring.Ring
+	hasher           Hasher
+	policy           PasswordPolicy
+	cache            *VerificationCache
+	now              func() time.Time
+}
+
+// Option configures optional behaviour of the Service returned by NewService.
+type Option func(*service)
+
+// WithPublisher makes the Service publish an Event to p every time a Link is created, visited, inactivated or
+// reactivated.
+func WithPublisher(p Publisher) Option {
+	return func(s *service) {
+		s.publisher = p
+	}
+}
+
+// WithVisitThresholds makes the Service publish an EventThresholdReached event
+// when the Count of a Link reaches any of the given values.
+func WithVisitThresholds(thresholds ...int) Option {
+	return func(s *service) {
+		for _, t := range thresholds {
+			s.thresholds[t] = struct{}{}
+		}
+	}
+}
+
+// WithVisitorSalt sets the secret used to fingerprint the visitors when estimating the unique visitors of a Link.
+// It must be kept across restarts, otherwise returning visitors are counted again.
+// If not provided, a random salt is generated.
+func WithVisitorSalt(salt []byte) Option {
+	return func(s *service) {
+		s.salt = salt
+	}
+}
+
+// WithClock replaces the function used to get the current time.
+func WithClock(now func() time.Time) Option {
+	return func(s *service) {
+		s.now = now
+	}
 }
 
-func NewService(r Repository) Service {
-	return &service{
-		repository: r,
+func NewService(r Repository, opts ...Option) Service {
+	s := &service{
+		repository:       r,
+		publisher:        nopPublisher{},
+		thresholds:       make(map[int]struct{}),
+		failureThreshold: 3,
+		hasher:           BcryptHasher{Cost: bcrypt.DefaultCost},
+		now:              time.Now,
+	}
+
+	for _, opt := range opts {
+		opt(s)
 	}
+
+	if s.salt == nil {
+		s.salt = make([]byte, 32)
+		if _, err := rand.Read(s.salt); err != nil {
+			panic(err)
+		}
+	}
+
+	return s
 }
 
-func (s *service) Create(ctx context.Context, url, password string) (Link, error) {
-	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (s *service) Create(ctx context.Context, nl NewLink) (Link, error) {
+	if err := nl.Schedule.validate(); err != nil {
+		return Link{}, err
+	}
+
+	if nl.MaxVisits < 0 {
+		return Link{}, fmt.Errorf("%w: max visits must not be negative", ErrInvalidLink)
+	}
+
+	if nl.MaxVisits > 0 && nl.Interstitial {
+		return Link{}, fmt.Errorf("%w: a secret link cannot have an interstitial page", ErrInvalidLink)
+	}
+
+	// The IDs are sequential, so anyone could consume the visits of a secret link that is not protected.
+	if nl.MaxVisits > 0 && nl.Password == "" && len(nl.Codes) == 0 {
+		return Link{}, fmt.Errorf("%w: a secret link requires a password or access codes", ErrInvalidLink)
+	}
+
+	codes, err := s.newAccessCodes(nl.Codes)
 	if err != nil {
 		return Link{}, err
 	}
 
+	var hash []byte
+	if nl.Password != "" {
+		if err := s.policy.Check(nl.Password); err != nil {
+			return Link{}, err
+		}
+
+		if hash, err = s.hasher.Hash([]byte(nl.Password)); err != nil {
+			return Link{}, err
+		}
+	}
+
 	l := Link{
-		Password: hash,
-		URL:      url,
+		Password:     hash,
+		Codes:        codes,
+		URL:          nl.URL,
+		Owner:        nl.Owner,
+		Title:        nl.Title,
+		Interstitial: nl.Interstitial,
+		FallbackURL:  nl.FallbackURL,
+		Schedule:     nl.Schedule,
+		Closed:       !nl.Schedule.Open(s.now()),
+		MaxVisits:    nl.MaxVisits,
 	}
 
 	id, err := s.repository.Save(ctx, l)
@@ -70,41 +248,197 @@ func (s *service) Create(ctx context.Context, url, password string) (Link, error
 	}
 
 	l.ID = id
+	s.publisher.Publish(ctx, newEvent(EventCreated, l, s.now()))
 	return l, nil
 }
 
-func (s *service) Redirect(ctx context.Context, ID int, password string) (Link, error) {
+// Redirect counts the visit to the Link identified by ID. The visitor must be sent to the Destination
+// of the returned Link, which is its FallbackURL while its URL is failing.
+func (s *service) Redirect(ctx context.Context, ID int, v Visit) (Link, error) {
+	link, cred, err := s.authorize(ctx, ID, v)
+	if err != nil {
+		return Link{}, err
+	}
+
+	if v.Bot && link.Secret() {
+		return Link{}, ErrSecret
+	}
+
+	// The hash is upgraded to the current Hasher while the password is known.
+	s.rehash(&cred, v.Password)
+
+	now := s.now()
+	fp := fingerprint(s.salt, v)
+
+	// The visits of a secret link are consumed while holding the lock of the repository,
+	// so no more than MaxVisits concurrent redirects can succeed.
+	link, err = s.repository.Modify(ctx, ID, func(l *Link) error {
+		if l.Inactive {
+			return ErrInactive
+		}
+
+		if v.Bot {
+			l.BotCount++
+			return nil
+		}
+
+		l.Count++
+		cred.use(l, now)
+		if l.Secret() && l.Count >= l.MaxVisits {
+			l.Inactive = true
+			l.ConsumedAt = now
+		}
+
+		return recordVisitor(l, fp, now)
+	})
+	if err != nil {
+		return Link{}, err
+	}
+
+	if v.Bot {
+		return link, nil
+	}
+
+	visited := newEvent(EventVisited, link, now)
+	visited.Code = cred.code
+	s.publisher.Publish(ctx, visited)
+	if link.Secret() && link.Inactive {
+		s.invalidate(link.ID)
+		s.publisher.Publish(ctx, newEvent(EventInactivated, link, now))
+	}
+	if _, ok := s.thresholds[link.Count]; ok {
+		e := newEvent(EventThresholdReached, link, now)
+		e.Threshold = link.Count
+		s.publisher.Publish(ctx, e)
+	}
+
+	return link, nil
+}
+
+// Preview validates the visit like Redirect, but it counts it as a view of the interstitial page.
+func (s *service) Preview(ctx context.Context, ID int, v Visit) (Link, error) {
+	link, _, err := s.authorize(ctx, ID, v)
+	if err != nil {
+		return Link{}, err
+	}
+
+	if link.Secret() {
+		return Link{}, ErrSecret
+	}
+
+	return s.repository.Modify(ctx, ID, func(l *Link) error {
+		if l.Inactive {
+			return ErrInactive
+		}
+
+		if v.Bot {
+			l.BotCount++
+		} else {
+			l.PreviewCount++
+		}
+
+		return nil
+	})
+}
+
+// authorize returns the link identified by ID if v is allowed to visit it, either with its password, one of
+// its access codes or an Access, along with the credential used.
+func (s *service) authorize(ctx context.Context, ID int, v Visit) (Link, credential, error) {
 	link, err := s.repository.FindByID(ctx, ID)
 	if err != nil {
-		return Link{}, ErrNotFound
+		return Link{}, credential{}, ErrNotFound
 	}
 
-	if err := bcrypt.CompareHashAndPassword(link.Password, []byte(password)); err != nil {
-		return Link{}, ErrAuthentication
+	var cred credential
+	if v.Access != nil {
+		err = s.verifyAccess(ID, *v.Access, v.IP)
+	} else {
+		cred, err = s.authenticate(link, v.Password)
+	}
+	if err != nil {
+		return Link{}, credential{}, err
 	}
 
 	if link.Inactive {
-		return Link{}, ErrInactive
+		return Link{}, credential{}, ErrInactive
 	}
 
-	link.Count++
-	if err := s.repository.Update(ctx, link); err != nil {
-		return Link{}, err
+	if !link.Schedule.Open(s.now()) {
+		return Link{}, credential{}, &ClosedError{Message: link.Schedule.ClosedMessage, URL: link.Schedule.ClosedURL}
 	}
 
-	return link, nil
+	return link, cred, nil
 }
 
 func (s *service) FindByID(ctx context.Context, ID int) (Link, error) {
 	return s.repository.FindByID(ctx, ID)
 }
 
+func (s *service) List(ctx context.Context, owner string) ([]Link, error) {
+	links, err := s.repository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if owner == "" {
+		return links, nil
+	}
+
+	owned := make([]Link, 0, len(links))
+	for _, l := range links {
+		if l.Owner == owner {
+			owned = append(owned, l)
+		}
+	}
+
+	return owned, nil
+}
+
 func (s *service) Inactivate(ctx context.Context, ID int) error {
-	link, err := s.repository.FindByID(ctx, ID)
+	link, err := s.repository.Modify(ctx, ID, func(l *Link) error {
+		l.Inactive = true
+		return nil
+	})
 	if err != nil {
-		return ErrNotFound
+		return err
 	}
 
-	link.Inactive = true
-	return s.repository.Update(ctx, link)
+	s.invalidate(ID)
+	s.publisher.Publish(ctx, newEvent(EventInactivated, link, s.now()))
+	return nil
+}
+
+// Reactivate makes an inactive Link visitable again. A secret Link whose visits were consumed cannot be reactivated.
+func (s *service) Reactivate(ctx context.Context, ID int) error {
+	link, err := s.repository.Modify(ctx, ID, func(l *Link) error {
+		if l.Secret() && l.RemainingVisits() == 0 {
+			return ErrConsumed
+		}
+
+		l.Inactive = false
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.publisher.Publish(ctx, newEvent(EventReactivated, link, s.now()))
+	return nil
+}
+
+// invalidate forgets the verified passwords of the link identified by ID.
+func (s *service) invalidate(ID int) {
+	if s.cache != nil {
+		s.cache.invalidate(ID)
+	}
+}
+
+// SetMetadata replaces the metadata of the destination page of a Link.
+func (s *service) SetMetadata(ctx context.Context, ID int, m Metadata) error {
+	_, err := s.repository.Modify(ctx, ID, func(l *Link) error {
+		l.Metadata = m
+		return nil
+	})
+
+	return err
 }
@@ -29,6 +29,30 @@ func (r *repositoryMock) FindByID(ctx context.Context, ID int) (link.Link, error
 	return args.Get(0).(link.Link), args.Error(1)
 }
 
+func (r *repositoryMock) List(ctx context.Context) ([]link.Link, error) {
+	args := r.Mock.Called(ctx)
+	return args.Get(0).([]link.Link), args.Error(1)
+}
+
+// Modify mimics a Repository by applying fn to the link returned by FindByID and saving the result with Update,
+// so the tests can set their expectations on those methods.
+func (r *repositoryMock) Modify(ctx context.Context, ID int, fn func(l *link.Link) error) (link.Link, error) {
+	l, err := r.FindByID(ctx, ID)
+	if err != nil {
+		return link.Link{}, err
+	}
+
+	if err := fn(&l); err != nil {
+		return link.Link{}, err
+	}
+
+	if err := r.Update(ctx, l); err != nil {
+		return link.Link{}, err
+	}
+
+	return l, nil
+}
+
 func TestService_Create(t *testing.T) {
 	// Given
 	ctx := context.Background()
@@ -43,7 +67,7 @@ func TestService_Create(t *testing.T) {
 	service := link.NewService(repositoryMock)
 
 	// When
-	l, err := service.Create(ctx, url, password)
+	l, err := service.Create(ctx, link.NewLink{URL: url, Password: password})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -72,14 +96,13 @@ func TestService_Redirect(t *testing.T) {
 	repositoryMock := &repositoryMock{}
 	repositoryMock.On("FindByID", ctx, l.ID).Return(l, nil)
 	repositoryMock.On("Update", ctx, mock.MatchedBy(func(l2 link.Link) bool {
-		l.Count++
-		return assert.ObjectsAreEqual(l, l2)
+		return l2.ID == l.ID && l2.Count == l.Count+1 && l2.UniqueVisitors() == 1
 	})).Return(nil)
 
 	service := link.NewService(repositoryMock)
 
 	// When
-	l, err = service.Redirect(ctx, 1, password)
+	l, err = service.Redirect(ctx, 1, link.Visit{Password: password, IP: "10.0.0.1", UserAgent: "curl/7.64.1"})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -128,3 +151,74 @@ func TestService_Inactivate(t *testing.T) {
 	require.NoError(t, err)
 	mock.AssertExpectationsForObjects(t, repositoryMock)
 }
+
+func TestService_Reactivate(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	l := link.Link{ID: 1, Inactive: true}
+
+	repositoryMock := &repositoryMock{}
+	repositoryMock.On("FindByID", ctx, l.ID).Return(l, nil)
+	repositoryMock.On("Update", ctx, mock.MatchedBy(func(l2 link.Link) bool {
+		l.Inactive = false
+		return assert.ObjectsAreEqual(l, l2)
+	})).Return(nil)
+
+	service := link.NewService(repositoryMock)
+
+	// When
+	err := service.Reactivate(ctx, l.ID)
+
+	// Then
+	require.NoError(t, err)
+	mock.AssertExpectationsForObjects(t, repositoryMock)
+}
+
+func TestService_Reactivate_Consumed(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	l := link.Link{ID: 1, Inactive: true, MaxVisits: 1, Count: 1}
+
+	repositoryMock := &repositoryMock{}
+	repositoryMock.On("FindByID", ctx, l.ID).Return(l, nil)
+
+	service := link.NewService(repositoryMock)
+
+	// When
+	err := service.Reactivate(ctx, l.ID)
+
+	// Then
+	require.ErrorIs(t, err, link.ErrConsumed)
+	repositoryMock.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+}
+
+func TestService_Preview(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	password := "1234"
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := link.Link{ID: 1, URL: "https://www.google.com", Password: hash, Count: 3}
+
+	repositoryMock := &repositoryMock{}
+	repositoryMock.On("FindByID", ctx, l.ID).Return(l, nil)
+	repositoryMock.On("Update", ctx, mock.MatchedBy(func(l2 link.Link) bool {
+		return l2.PreviewCount == 1 && l2.Count == 3
+	})).Return(nil)
+
+	service := link.NewService(repositoryMock)
+
+	// When
+	_, err = service.Preview(ctx, l.ID, link.Visit{Password: "wrong"})
+	require.ErrorIs(t, err, link.ErrAuthentication)
+
+	l, err = service.Preview(ctx, l.ID, link.Visit{Password: password})
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, 1, l.PreviewCount)
+	mock.AssertExpectationsForObjects(t, repositoryMock)
+}
@@ -0,0 +1,21 @@
+//go:build !windows
+// +build !windows
+
+package link
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// lockFile takes an exclusive lock on f, held until f is closed. It returns ErrFileInUse if another
+// open file holds it.
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrFileInUse
+	}
+
+	return err
+}
@@ -0,0 +1,162 @@
+package link
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"time"
+)
+
+// AccessCode is a named password of a Link, so it can be handed to a partner and its usage told apart
+// from the one of the other codes.
+type AccessCode struct {
+	Name string
+	Hash []byte
+	// Expires is when the code stops being accepted. It never expires if zero.
+	Expires time.Time
+	// Count is the number of redirects made with the code.
+	Count      int
+	LastUsedAt time.Time
+}
+
+// NewAccessCode contains the information needed to add an AccessCode to a Link.
+type NewAccessCode struct {
+	Name     string
+	Password string
+	Expires  time.Time
+}
+
+// Public tells whether the link can be visited without any password.
+func (l Link) Public() bool {
+	return l.Password == nil && len(l.Codes) == 0
+}
+
+func (s *service) newAccessCodes(ncs []NewAccessCode) ([]AccessCode, error) {
+	codes := make([]AccessCode, 0, len(ncs))
+	names := make(map[string]struct{}, len(ncs))
+
+	for _, nc := range ncs {
+		if nc.Name == "" || nc.Password == "" {
+			return nil, fmt.Errorf("%w: access codes must have a name and a password", ErrInvalidLink)
+		}
+
+		if _, ok := names[nc.Name]; ok {
+			return nil, fmt.Errorf("%w: duplicated access code %q", ErrInvalidLink, nc.Name)
+		}
+		names[nc.Name] = struct{}{}
+
+		if err := s.policy.Check(nc.Password); err != nil {
+			return nil, fmt.Errorf("access code %q: %w", nc.Name, err)
+		}
+
+		hash, err := s.hasher.Hash([]byte(nc.Password))
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, AccessCode{Name: nc.Name, Hash: hash, Expires: nc.Expires})
+	}
+
+	return codes, nil
+}
+
+// credential tells which password of a Link was used in a visit.
+type credential struct {
+	// code is the name of the access code, empty for the password of the link.
+	code string
+	// hash is the stored hash that matched the password.
+	hash []byte
+	// rehash replaces hash when it was not produced with the current Hasher.
+	rehash []byte
+}
+
+// authenticate checks password against the password and the access codes of l which are not expired,
+// unless it was recently verified according to the VerificationCache of the Service.
+func (s *service) authenticate(l Link, password string) (credential, error) {
+	if l.Public() {
+		return credential{}, nil
+	}
+
+	if password == "" {
+		return credential{}, ErrPasswordRequired
+	}
+
+	now := s.now()
+	if s.cache != nil {
+		if cred, ok := s.cache.get(l, password, now); ok {
+			return cred, nil
+		}
+	}
+
+	cred, err := verify(l, password, now)
+	if err != nil {
+		return credential{}, err
+	}
+
+	if s.cache != nil {
+		s.cache.put(l.ID, password, cred, now)
+	}
+
+	return cred, nil
+}
+
+// verify compares password with the hashes of the password and the access codes of l which are not expired at now.
+func verify(l Link, password string, now time.Time) (credential, error) {
+	if l.Password != nil && compareHash(l.Password, []byte(password)) {
+		return credential{hash: l.Password}, nil
+	}
+
+	for _, c := range l.Codes {
+		if !c.Expires.IsZero() && !now.Before(c.Expires) {
+			continue
+		}
+
+		if compareHash(c.Hash, []byte(password)) {
+			return credential{code: c.Name, hash: c.Hash}, nil
+		}
+	}
+
+	return credential{}, ErrAuthentication
+}
+
+// rehash hashes password again with the current Hasher if cred was not produced by it.
+func (s *service) rehash(cred *credential, password string) {
+	if cred.hash == nil || s.hasher.Current(cred.hash) {
+		return
+	}
+
+	rehash, err := s.hasher.Hash([]byte(password))
+	if err != nil {
+		log.Printf("link: rehashing password: %v", err)
+		return
+	}
+
+	cred.rehash = rehash
+}
+
+// use counts a redirect made with cred, and replaces its hash if it was rehashed and it did not change meanwhile.
+func (cred credential) use(l *Link, now time.Time) {
+	if cred.code == "" {
+		if cred.rehash != nil && bytes.Equal(l.Password, cred.hash) {
+			l.Password = cred.rehash
+		}
+
+		return
+	}
+
+	codes := make([]AccessCode, len(l.Codes))
+	copy(codes, l.Codes)
+	for i := range codes {
+		if codes[i].Name != cred.code {
+			continue
+		}
+
+		codes[i].Count++
+		codes[i].LastUsedAt = now
+		if cred.rehash != nil && bytes.Equal(codes[i].Hash, cred.hash) {
+			codes[i].Hash = cred.rehash
+		}
+	}
+
+	l.Codes = codes
+}
@@ -0,0 +1,114 @@
+package link_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestService_Redirect_Public(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher)
+	id, err := repository.Save(ctx, link.Link{URL: "https://www.google.com"})
+	require.NoError(t, err)
+
+	// When
+	l, err := service.Redirect(ctx, id, link.Visit{})
+
+	// Then
+	require.NoError(t, err)
+	require.True(t, l.Public())
+	require.Equal(t, 1, l.Count)
+}
+
+func TestService_Redirect_AccessCodes(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	repository := link.NewInMemoryRepository()
+
+	bus := link.NewBus()
+	var events []link.Event
+	bus.Subscribe(func(_ context.Context, e link.Event) { events = append(events, e) })
+
+	service := link.NewService(repository, fastHasher, link.WithPublisher(bus), link.WithClock(func() time.Time { return now }))
+
+	hash := func(password string) []byte {
+		h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+		require.NoError(t, err)
+		return h
+	}
+
+	id, err := repository.Save(ctx, link.Link{
+		URL:      "https://www.google.com",
+		Password: hash("main"),
+		Codes: []link.AccessCode{
+			{Name: "acme", Hash: hash("acme-code")},
+			{Name: "globex", Hash: hash("globex-code"), Expires: now.Add(time.Hour)},
+			{Name: "initech", Hash: hash("initech-code"), Expires: now},
+		},
+	})
+	require.NoError(t, err)
+
+	// When
+	for _, password := range []string{"acme-code", "acme-code", "globex-code", "main"} {
+		_, err := service.Redirect(ctx, id, link.Visit{Password: password})
+		require.NoError(t, err)
+	}
+	_, expiredErr := service.Redirect(ctx, id, link.Visit{Password: "initech-code"})
+	_, missingErr := service.Redirect(ctx, id, link.Visit{})
+
+	// Then
+	require.True(t, errors.Is(expiredErr, link.ErrAuthentication))
+	require.True(t, errors.Is(missingErr, link.ErrAuthentication))
+
+	l, err := service.FindByID(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, 4, l.Count)
+	require.Equal(t, 2, l.Codes[0].Count)
+	require.Equal(t, now, l.Codes[0].LastUsedAt)
+	require.Equal(t, 1, l.Codes[1].Count)
+	require.Equal(t, 0, l.Codes[2].Count)
+
+	require.Len(t, events, 4)
+	require.Equal(t, "acme", events[0].Code)
+	require.Equal(t, "globex", events[2].Code)
+	require.Empty(t, events[3].Code)
+}
+
+func TestService_Create_InvalidAccessCodes(t *testing.T) {
+	tt := []struct {
+		name  string
+		codes []link.NewAccessCode
+	}{
+		{
+			name:  "without name",
+			codes: []link.NewAccessCode{{Password: "1234"}},
+		},
+		{
+			name:  "without password",
+			codes: []link.NewAccessCode{{Name: "acme"}},
+		},
+		{
+			name:  "duplicated name",
+			codes: []link.NewAccessCode{{Name: "acme", Password: "1234"}, {Name: "acme", Password: "5678"}},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := link.NewService(link.NewInMemoryRepository(), fastHasher).Create(context.Background(), link.NewLink{
+				URL:   "https://www.google.com",
+				Codes: tc.codes,
+			})
+			require.True(t, errors.Is(err, link.ErrInvalidLink))
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package link
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrWeakPassword is returned when creating a Link with a password that does not satisfy the PasswordPolicy.
+var ErrWeakPassword = errors.New("weak password")
+
+// PasswordPolicy is the strength required to the passwords and access codes of new links.
+// The zero value accepts any password.
+type PasswordPolicy struct {
+	MinLength int
+	// MinCharClasses is the number of different kinds of characters required,
+	// among lowercase and uppercase letters, digits and symbols.
+	MinCharClasses int
+	// RejectCommon rejects the most frequently used passwords.
+	RejectCommon bool
+}
+
+// WithPasswordPolicy makes the Service reject new links whose passwords do not satisfy p.
+func WithPasswordPolicy(p PasswordPolicy) Option {
+	return func(s *service) {
+		s.policy = p
+	}
+}
+
+// Check returns an error wrapping ErrWeakPassword that explains why password does not satisfy the policy.
+func (p PasswordPolicy) Check(password string) error {
+	if n := utf8.RuneCountInString(password); n < p.MinLength {
+		return fmt.Errorf("%w: it must have at least %d characters", ErrWeakPassword, p.MinLength)
+	}
+
+	if classes := charClasses(password); classes < p.MinCharClasses {
+		return fmt.Errorf("%w: it must combine at least %d of lowercase, uppercase, digits and symbols", ErrWeakPassword, p.MinCharClasses)
+	}
+
+	if _, ok := commonPasswords[strings.ToLower(password)]; p.RejectCommon && ok {
+		return fmt.Errorf("%w: it is too common", ErrWeakPassword)
+	}
+
+	return nil
+}
+
+func charClasses(password string) int {
+	var lower, upper, digit, symbol int
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = 1
+		case unicode.IsUpper(r):
+			upper = 1
+		case unicode.IsDigit(r):
+			digit = 1
+		default:
+			symbol = 1
+		}
+	}
+
+	return lower + upper + digit + symbol
+}
+
+// commonPasswords are some of the most used passwords that would satisfy a reasonable length and classes requirement.
+var commonPasswords = map[string]struct{}{
+	"password":     {},
+	"password1":    {},
+	"password123":  {},
+	"passw0rd":     {},
+	"p@ssw0rd":     {},
+	"12345678":     {},
+	"123456789":    {},
+	"1234567890":   {},
+	"qwerty123":    {},
+	"qwertyuiop":   {},
+	"1q2w3e4r":     {},
+	"1qaz2wsx":     {},
+	"iloveyou":     {},
+	"sunshine":     {},
+	"princess":     {},
+	"football":     {},
+	"baseball":     {},
+	"welcome1":     {},
+	"letmein1":     {},
+	"trustno1":     {},
+	"admin123":     {},
+	"abc12345":     {},
+	"changeme":     {},
+	"superman":     {},
+	"whatever":     {},
+	"starwars":     {},
+	"dragon123":    {},
+	"monkey123":    {},
+	"qwerty12345":  {},
+	"password1234": {},
+}
@@ -0,0 +1,56 @@
+package link_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	policy := link.PasswordPolicy{MinLength: 8, MinCharClasses: 2, RejectCommon: true}
+
+	tt := []struct {
+		name     string
+		password string
+		wantErr  string
+	}{
+		{name: "strong", password: "correct horse 42"},
+		{name: "too short", password: "a1b2", wantErr: "weak password: it must have at least 8 characters"},
+		{name: "single class", password: "abcdefghij", wantErr: "weak password: it must combine at least 2 of lowercase, uppercase, digits and symbols"},
+		{name: "common", password: "Password1", wantErr: "weak password: it is too common"},
+		{name: "unicode length", password: "contraseña1"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := policy.Check(tc.password)
+			if tc.wantErr == "" {
+				require.NoError(t, err)
+				return
+			}
+
+			require.EqualError(t, err, tc.wantErr)
+			require.True(t, errors.Is(err, link.ErrWeakPassword))
+		})
+	}
+}
+
+func TestService_Create_WeakPassword(t *testing.T) {
+	// Given
+	service := link.NewService(link.NewInMemoryRepository(), fastHasher, link.WithPasswordPolicy(link.PasswordPolicy{MinLength: 8}))
+
+	// When
+	_, passwordErr := service.Create(context.Background(), link.NewLink{URL: "https://www.google.com", Password: "1234"})
+	_, codeErr := service.Create(context.Background(), link.NewLink{
+		URL:   "https://www.google.com",
+		Codes: []link.NewAccessCode{{Name: "acme", Password: "1234"}},
+	})
+
+	// Then
+	require.True(t, errors.Is(passwordErr, link.ErrWeakPassword))
+	require.True(t, errors.Is(codeErr, link.ErrWeakPassword))
+	require.Contains(t, codeErr.Error(), `access code "acme"`)
+}
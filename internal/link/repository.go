@@ -2,10 +2,13 @@ package link
 
 import (
 	"context"
+	"sync"
 )
 
+// InMemoryRepository is a volatile Repository safe for concurrent use.
 type InMemoryRepository struct {
-	m map[int]Link
+	mu sync.RWMutex
+	m  map[int]Link
 }
 
 func NewInMemoryRepository() *InMemoryRepository {
@@ -15,6 +18,9 @@ func NewInMemoryRepository() *InMemoryRepository {
 }
 
 func (r *InMemoryRepository) Update(ctx context.Context, l Link) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	_, ok := r.m[l.ID]
 	if !ok {
 		return ErrNotFound
@@ -24,13 +30,36 @@ func (r *InMemoryRepository) Update(ctx context.Context, l Link) error {
 	return nil
 }
 
+func (r *InMemoryRepository) Modify(ctx context.Context, ID int, fn func(l *Link) error) (Link, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	link, ok := r.m[ID]
+	if !ok {
+		return Link{}, ErrNotFound
+	}
+
+	if err := fn(&link); err != nil {
+		return Link{}, err
+	}
+
+	r.m[ID] = link
+	return link, nil
+}
+
 func (r *InMemoryRepository) Save(ctx context.Context, l Link) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	l.ID = len(r.m) + 1
 	r.m[l.ID] = l
 	return l.ID, nil
 }
 
 func (r *InMemoryRepository) FindByID(ctx context.Context, ID int) (Link, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	link, ok := r.m[ID]
 	if !ok {
 		return Link{}, ErrNotFound
@@ -38,3 +67,16 @@ func (r *InMemoryRepository) FindByID(ctx context.Context, ID int) (Link, error)
 
 	return link, nil
 }
+
+// List returns all the links sorted by ID.
+func (r *InMemoryRepository) List(ctx context.Context) ([]Link, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	links := make([]Link, 0, len(r.m))
+	for id := 1; id <= len(r.m); id++ {
+		links = append(links, r.m[id])
+	}
+
+	return links, nil
+}
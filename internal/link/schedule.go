@@ -0,0 +1,243 @@
+package link
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// ErrInvalidSchedule is returned when creating a Link with a Schedule that can never be satisfied.
+var ErrInvalidSchedule = errors.New("invalid schedule")
+
+// ErrClosed is matched by the ClosedError returned when visiting a Link outside of its Schedule.
+var ErrClosed = errors.New("link is not available at this time")
+
+// transitionHistory is the number of transitions kept in a Link.
+const transitionHistory = 50
+
+// ClosedError is returned when visiting a Link outside of its Schedule. It carries what the
+// visitor must be shown instead of the destination.
+type ClosedError struct {
+	Message string
+	URL     string
+}
+
+func (e *ClosedError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+
+	return ErrClosed.Error()
+}
+
+// Is makes errors.Is(err, ErrClosed) true for any ClosedError.
+func (e *ClosedError) Is(target error) bool {
+	return target == ErrClosed
+}
+
+// Schedule restricts when a Link can be visited. The zero value allows visits at any time.
+type Schedule struct {
+	// ActivateAt is when the link starts accepting visits. It accepts them right away if zero.
+	ActivateAt time.Time
+	// DeactivateAt is when the link stops accepting visits. It never stops if zero.
+	DeactivateAt time.Time
+	// Windows limits the visits to some hours of the week, like business hours. If empty, visits are allowed at any time.
+	Windows []Window
+	// Location is the time zone of the Windows. UTC if nil.
+	Location *time.Location
+	// ClosedMessage is shown to the visitors outside of the schedule.
+	ClosedMessage string
+	// ClosedURL is where the visitors are sent outside of the schedule. It takes precedence over ClosedMessage.
+	ClosedURL string
+}
+
+// Window is a recurring period of time during which a Link can be visited.
+type Window struct {
+	// Days are the days of the week the window applies to, every day if empty.
+	Days []time.Weekday
+	// Start and End are offsets from midnight. If End is before Start the window spans midnight,
+	// and it applies to the day it starts.
+	Start time.Duration
+	End   time.Duration
+}
+
+// Transition records when a Link entered or left its Schedule.
+type Transition struct {
+	Time   time.Time
+	Closed bool
+}
+
+// IsZero tells whether the schedule allows visits at any time.
+func (s Schedule) IsZero() bool {
+	return s.ActivateAt.IsZero() && s.DeactivateAt.IsZero() && len(s.Windows) == 0
+}
+
+// Open tells whether the link can be visited at t.
+func (s Schedule) Open(t time.Time) bool {
+	if !s.ActivateAt.IsZero() && t.Before(s.ActivateAt) {
+		return false
+	}
+
+	if !s.DeactivateAt.IsZero() && !t.Before(s.DeactivateAt) {
+		return false
+	}
+
+	if len(s.Windows) == 0 {
+		return true
+	}
+
+	loc := s.Location
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	t = t.In(loc)
+	for _, w := range s.Windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s Schedule) validate() error {
+	if !s.ActivateAt.IsZero() && !s.DeactivateAt.IsZero() && !s.DeactivateAt.After(s.ActivateAt) {
+		return fmt.Errorf("%w: deactivation must be after activation", ErrInvalidSchedule)
+	}
+
+	for _, w := range s.Windows {
+		if w.Start < 0 || w.Start >= 24*time.Hour || w.End < 0 || w.End > 24*time.Hour {
+			return fmt.Errorf("%w: window times must be within a day", ErrInvalidSchedule)
+		}
+
+		if w.Start == w.End {
+			return fmt.Errorf("%w: window must not be empty", ErrInvalidSchedule)
+		}
+	}
+
+	return nil
+}
+
+func (w Window) contains(t time.Time) bool {
+	// The offset is the time on the clock, not the time elapsed since midnight, which differs on the days
+	// the clocks change for daylight saving time.
+	hour, min, sec := t.Clock()
+	offset := time.Duration(hour)*time.Hour + time.Duration(min)*time.Minute + time.Duration(sec)*time.Second +
+		time.Duration(t.Nanosecond())
+
+	if w.Start < w.End {
+		return w.appliesTo(t.Weekday()) && offset >= w.Start && offset < w.End
+	}
+
+	// The window spans midnight: t is either in the part that starts today or in the one that started yesterday.
+	if offset >= w.Start {
+		return w.appliesTo(t.Weekday())
+	}
+
+	return offset < w.End && w.appliesTo((t.Weekday()+6)%7)
+}
+
+func (w Window) appliesTo(d time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, day := range w.Days {
+		if day == d {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SyncSchedule records whether the Link identified by ID is within its Schedule now. When that changed since
+// the last time, the transition is added to the Link and an EventOpened or EventClosed event is published.
+func (s *service) SyncSchedule(ctx context.Context, ID int) error {
+	now := s.now()
+
+	var changed bool
+	link, err := s.repository.Modify(ctx, ID, func(l *Link) error {
+		closed := !l.Schedule.Open(now)
+		if closed == l.Closed {
+			changed = false
+			return nil
+		}
+
+		changed = true
+		l.Closed = closed
+
+		transitions := make([]Transition, 0, transitionHistory)
+		if len(l.Transitions) >= transitionHistory {
+			transitions = append(transitions, l.Transitions[len(l.Transitions)-transitionHistory+1:]...)
+		} else {
+			transitions = append(transitions, l.Transitions...)
+		}
+		l.Transitions = append(transitions, Transition{Time: now, Closed: closed})
+		return nil
+	})
+	if err != nil || !changed {
+		return err
+	}
+
+	eventType := EventOpened
+	if link.Closed {
+		eventType = EventClosed
+	}
+
+	s.publisher.Publish(ctx, newEvent(eventType, link, now))
+	return nil
+}
+
+// Scheduler periodically records the transitions of the links that have a Schedule.
+type Scheduler struct {
+	service  Service
+	interval time.Duration
+}
+
+// NewScheduler creates a Scheduler that checks the links every interval, so the transitions
+// are recorded with that precision. Redirect honors the Schedule regardless of it.
+func NewScheduler(s Service, interval time.Duration) *Scheduler {
+	return &Scheduler{
+		service:  s,
+		interval: interval,
+	}
+}
+
+// Run syncs the schedule of every link right away and then every interval, until ctx is done.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.SyncAll(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncAll syncs the schedule of every active link that has one.
+func (s *Scheduler) SyncAll(ctx context.Context) {
+	links, err := s.service.List(ctx, "")
+	if err != nil {
+		log.Printf("scheduler: listing links: %v", err)
+		return
+	}
+
+	for _, l := range links {
+		if l.Inactive || (l.Schedule.IsZero() && !l.Closed) {
+			continue
+		}
+
+		if err := s.service.SyncSchedule(ctx, l.ID); err != nil {
+			log.Printf("scheduler: syncing link %d: %v", l.ID, err)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package link_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Open(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	require.NoError(t, err)
+
+	launch := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	businessHours := []link.Window{{
+		Days:  []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		Start: 9 * time.Hour,
+		End:   18 * time.Hour,
+	}}
+
+	tt := []struct {
+		name     string
+		schedule link.Schedule
+		time     time.Time
+		want     bool
+	}{
+		{
+			name: "no schedule",
+			time: launch,
+			want: true,
+		},
+		{
+			name:     "before activation",
+			schedule: link.Schedule{ActivateAt: launch},
+			time:     launch.Add(-time.Second),
+			want:     false,
+		},
+		{
+			name:     "at activation",
+			schedule: link.Schedule{ActivateAt: launch},
+			time:     launch,
+			want:     true,
+		},
+		{
+			name:     "at deactivation",
+			schedule: link.Schedule{DeactivateAt: launch},
+			time:     launch,
+			want:     false,
+		},
+		{
+			name:     "within business hours",
+			schedule: link.Schedule{Windows: businessHours},
+			time:     time.Date(2021, 6, 1, 17, 59, 0, 0, time.UTC), // Tuesday
+			want:     true,
+		},
+		{
+			name:     "after business hours",
+			schedule: link.Schedule{Windows: businessHours},
+			time:     time.Date(2021, 6, 1, 18, 0, 0, 0, time.UTC),
+			want:     false,
+		},
+		{
+			name:     "weekend",
+			schedule: link.Schedule{Windows: businessHours},
+			time:     time.Date(2021, 6, 5, 12, 0, 0, 0, time.UTC), // Saturday
+			want:     false,
+		},
+		{
+			name:     "business hours in another time zone",
+			schedule: link.Schedule{Windows: businessHours, Location: madrid},
+			time:     time.Date(2021, 6, 1, 16, 30, 0, 0, time.UTC), // 18:30 in Madrid
+			want:     false,
+		},
+		{
+			name:     "daylight saving time starts",
+			schedule: link.Schedule{Windows: []link.Window{{Start: 9 * time.Hour, End: 18 * time.Hour}}, Location: madrid},
+			time:     time.Date(2021, 3, 28, 7, 30, 0, 0, time.UTC), // 09:30 in Madrid, one hour less since midnight
+			want:     true,
+		},
+		{
+			name:     "daylight saving time ends",
+			schedule: link.Schedule{Windows: []link.Window{{Start: 9 * time.Hour, End: 18 * time.Hour}}, Location: madrid},
+			time:     time.Date(2021, 10, 31, 16, 30, 0, 0, time.UTC), // 17:30 in Madrid, one hour more since midnight
+			want:     true,
+		},
+		{
+			name:     "window spanning midnight after it",
+			schedule: link.Schedule{Windows: []link.Window{{Days: []time.Weekday{time.Friday}, Start: 22 * time.Hour, End: 2 * time.Hour}}},
+			time:     time.Date(2021, 6, 5, 1, 0, 0, 0, time.UTC), // Saturday
+			want:     true,
+		},
+		{
+			name:     "window spanning midnight on another day",
+			schedule: link.Schedule{Windows: []link.Window{{Days: []time.Weekday{time.Friday}, Start: 22 * time.Hour, End: 2 * time.Hour}}},
+			time:     time.Date(2021, 6, 4, 1, 0, 0, 0, time.UTC), // Friday
+			want:     false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.schedule.Open(tc.time))
+		})
+	}
+}
+
+func TestService_Create_InvalidSchedule(t *testing.T) {
+	// Given
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	service := link.NewService(link.NewInMemoryRepository(), fastHasher)
+
+	// When
+	_, err := service.Create(context.Background(), link.NewLink{
+		URL:      "https://www.google.com",
+		Password: "1234",
+		Schedule: link.Schedule{ActivateAt: now, DeactivateAt: now.Add(-time.Hour)},
+	})
+
+	// Then
+	require.True(t, errors.Is(err, link.ErrInvalidSchedule))
+}
+
+func TestService_Redirect_Closed(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher, link.WithClock(func() time.Time { return now }))
+
+	l := saveLink(t, repository, "1234")
+	_, err := repository.Modify(ctx, l.ID, func(l *link.Link) error {
+		l.Schedule = link.Schedule{ActivateAt: now.Add(time.Hour), ClosedURL: "https://www.google.com/soon"}
+		return nil
+	})
+	require.NoError(t, err)
+
+	// When
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+
+	// Then
+	var closed *link.ClosedError
+	require.True(t, errors.As(err, &closed))
+	require.True(t, errors.Is(err, link.ErrClosed))
+	require.Equal(t, "https://www.google.com/soon", closed.URL)
+
+	l, err = service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.Zero(t, l.Count)
+}
+
+func TestScheduler_SyncAll(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	bus := link.NewBus()
+	var events []link.Event
+	bus.Subscribe(func(_ context.Context, e link.Event) { events = append(events, e) })
+
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher, link.WithPublisher(bus), link.WithClock(func() time.Time { return now }))
+	scheduler := link.NewScheduler(service, time.Minute)
+
+	l, err := service.Create(ctx, link.NewLink{
+		URL:      "https://www.google.com",
+		Password: "1234",
+		Schedule: link.Schedule{ActivateAt: now.Add(time.Hour), DeactivateAt: now.Add(2 * time.Hour)},
+	})
+	require.NoError(t, err)
+	require.True(t, l.Closed)
+	events = nil
+
+	// When
+	scheduler.SyncAll(ctx)
+	now = now.Add(time.Hour)
+	scheduler.SyncAll(ctx)
+	scheduler.SyncAll(ctx)
+	now = now.Add(time.Hour)
+	scheduler.SyncAll(ctx)
+
+	// Then
+	require.Len(t, events, 2)
+	require.Equal(t, link.EventOpened, events[0].Type)
+	require.Equal(t, link.EventClosed, events[1].Type)
+
+	l, err = service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.True(t, l.Closed)
+	require.Equal(t, []link.Transition{
+		{Time: now.Add(-time.Hour), Closed: false},
+		{Time: now, Closed: true},
+	}, l.Transitions)
+}
@@ -0,0 +1,25 @@
+package link
+
+import "errors"
+
+// ErrSecret is returned when trying to preview a secret Link, or when a bot visits it,
+// since its destination must only be revealed to the visits it was created for.
+var ErrSecret = errors.New("the destination of a secret link is only revealed by redirecting")
+
+// ErrConsumed is returned when trying to reactivate a secret Link that was visited MaxVisits times.
+var ErrConsumed = errors.New("the visits of the secret link were consumed")
+
+// Secret tells whether the link can only be visited MaxVisits times. The destination of a secret link is
+// never previewed nor requested in the background, since it may be a page that only works once.
+func (l Link) Secret() bool {
+	return l.MaxVisits > 0
+}
+
+// RemainingVisits returns how many more times a secret link can be visited.
+func (l Link) RemainingVisits() int {
+	if !l.Secret() || l.Count >= l.MaxVisits {
+		return 0
+	}
+
+	return l.MaxVisits - l.Count
+}
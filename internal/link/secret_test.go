@@ -0,0 +1,165 @@
+package link_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+)
+
+// saveSecretLink stores a link that can only be visited maxVisits times.
+func saveSecretLink(t *testing.T, r link.Repository, maxVisits int) link.Link {
+	l := saveLink(t, r, "1234")
+	l, err := r.Modify(context.Background(), l.ID, func(l *link.Link) error {
+		l.MaxVisits = maxVisits
+		return nil
+	})
+	require.NoError(t, err)
+	return l
+}
+
+func TestService_Redirect_Secret(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	repository := link.NewInMemoryRepository()
+
+	bus := link.NewBus()
+	var events []link.Event
+	bus.Subscribe(func(_ context.Context, e link.Event) { events = append(events, e) })
+
+	service := link.NewService(repository, fastHasher, link.WithPublisher(bus), link.WithClock(func() time.Time { return now }))
+	l := saveSecretLink(t, repository, 2)
+
+	// When
+	first, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+	require.NoError(t, err)
+	second, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+	require.NoError(t, err)
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "1234"})
+
+	// Then
+	require.Equal(t, 1, first.RemainingVisits())
+	require.Equal(t, 0, second.RemainingVisits())
+	require.True(t, errors.Is(err, link.ErrInactive))
+
+	l, err = service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.True(t, l.Inactive)
+	require.Equal(t, now, l.ConsumedAt)
+	require.Equal(t, 2, l.Count)
+
+	require.Len(t, events, 3)
+	require.Equal(t, link.EventInactivated, events[2].Type)
+	require.True(t, events[2].Secret)
+}
+
+func TestService_Redirect_SecretConcurrently(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher)
+	l := saveSecretLink(t, repository, 1)
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var succeeded int
+
+	// When
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234"}); err == nil {
+				mu.Lock()
+				succeeded++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Then
+	require.Equal(t, 1, succeeded)
+
+	l, err := service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.Equal(t, 1, l.Count)
+}
+
+func TestService_Secret_NotRevealed(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher)
+	l := saveSecretLink(t, repository, 1)
+
+	// When
+	_, previewErr := service.Preview(ctx, l.ID, link.Visit{Password: "1234"})
+	_, botErr := service.Redirect(ctx, l.ID, link.Visit{Password: "1234", Bot: true})
+
+	// Then
+	require.True(t, errors.Is(previewErr, link.ErrSecret))
+	require.True(t, errors.Is(botErr, link.ErrSecret))
+
+	l, err := service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.False(t, l.Inactive)
+	require.Zero(t, l.Count)
+	require.Zero(t, l.BotCount)
+}
+
+func TestService_Create_Secret(t *testing.T) {
+	tt := []struct {
+		name string
+		nl   link.NewLink
+	}{
+		{
+			name: "with password",
+			nl:   link.NewLink{URL: "https://www.google.com", Password: "1234", MaxVisits: 1},
+		},
+		{
+			name: "with access codes",
+			nl:   link.NewLink{URL: "https://www.google.com", Codes: []link.NewAccessCode{{Name: "acme", Password: "1234"}}, MaxVisits: 1},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			l, err := link.NewService(link.NewInMemoryRepository(), fastHasher).Create(context.Background(), tc.nl)
+			require.NoError(t, err)
+			require.True(t, l.Secret())
+		})
+	}
+}
+
+func TestService_Create_InvalidSecret(t *testing.T) {
+	tt := []struct {
+		name string
+		nl   link.NewLink
+	}{
+		{
+			name: "negative max visits",
+			nl:   link.NewLink{URL: "https://www.google.com", Password: "1234", MaxVisits: -1},
+		},
+		{
+			name: "interstitial",
+			nl:   link.NewLink{URL: "https://www.google.com", Password: "1234", MaxVisits: 1, Interstitial: true},
+		},
+		{
+			name: "without password",
+			nl:   link.NewLink{URL: "https://www.google.com", MaxVisits: 1},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := link.NewService(link.NewInMemoryRepository(), fastHasher).Create(context.Background(), tc.nl)
+			require.True(t, errors.Is(err, link.ErrInvalidLink))
+		})
+	}
+}
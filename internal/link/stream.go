@@ -0,0 +1,145 @@
+package link
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Click is a successful redirect as delivered by a ClickStream.
+type Click struct {
+	// ID is a sequence number, unique within the ClickStream, that can be used to resume a subscription.
+	ID     uint64
+	LinkID int
+	Owner  string
+	// URL is empty for secret links, whose destination is only revealed by redirecting.
+	URL   string
+	Count int
+	Time  time.Time
+}
+
+// ClickStream is an in-process pub/sub of the clicks on every Link. It keeps the most
+// recent clicks in a bounded buffer, so subscribers can resume from the last click they saw.
+//
+// Publishing never blocks: a subscriber that does not keep up with the stream is
+// disconnected, and it is expected to resubscribe from the last click it received.
+type ClickStream struct {
+	mu          sync.Mutex
+	seq         uint64
+	buffer      []Click
+	size        int
+	subscribers map[*ClickSubscription]struct{}
+}
+
+// ClickSubscription receives the clicks that match its filter through C.
+// C is closed when the subscription is closed or when the subscriber falls behind.
+type ClickSubscription struct {
+	// Replay holds the buffered clicks that happened after the ID given when subscribing.
+	Replay []Click
+	C      <-chan Click
+
+	c       chan Click
+	filter  func(Click) bool
+	stream  *ClickStream
+	lagging bool
+}
+
+// NewClickStream creates a ClickStream that remembers up to size clicks.
+func NewClickStream(size int) *ClickStream {
+	return &ClickStream{
+		size:        size,
+		subscribers: make(map[*ClickSubscription]struct{}),
+	}
+}
+
+// Handle publishes EventVisited events as clicks. It can be subscribed directly to a Bus.
+func (s *ClickStream) Handle(_ context.Context, e Event) {
+	if e.Type != EventVisited {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seq++
+	c := Click{
+		ID:     s.seq,
+		LinkID: e.LinkID,
+		Owner:  e.Owner,
+		URL:    e.URL,
+		Count:  e.Count,
+		Time:   e.Time,
+	}
+	if e.Secret {
+		c.URL = ""
+	}
+
+	s.buffer = append(s.buffer, c)
+	if len(s.buffer) > s.size {
+		s.buffer = s.buffer[len(s.buffer)-s.size:]
+	}
+
+	for sub := range s.subscribers {
+		if !sub.filter(c) {
+			continue
+		}
+
+		select {
+		case sub.c <- c:
+		default:
+			sub.lagging = true
+			s.remove(sub)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the clicks accepted by filter that happen after lastID.
+// Buffered clicks newer than lastID are available in Replay. A lastID of zero skips the replay.
+// The capacity is the number of clicks that can be pending for the subscriber before it is disconnected.
+func (s *ClickStream) Subscribe(filter func(Click) bool, lastID uint64, capacity int) *ClickSubscription {
+	c := make(chan Click, capacity)
+	sub := &ClickSubscription{
+		C:      c,
+		c:      c,
+		filter: filter,
+		stream: s,
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if lastID > 0 {
+		for _, click := range s.buffer {
+			if click.ID > lastID && filter(click) {
+				sub.Replay = append(sub.Replay, click)
+			}
+		}
+	}
+
+	s.subscribers[sub] = struct{}{}
+	return sub
+}
+
+// Lagging reports whether the subscription was closed because the subscriber fell behind.
+// It must be called after C is closed.
+func (sub *ClickSubscription) Lagging() bool {
+	sub.stream.mu.Lock()
+	defer sub.stream.mu.Unlock()
+	return sub.lagging
+}
+
+// Close stops the delivery of clicks and closes C.
+func (sub *ClickSubscription) Close() {
+	sub.stream.mu.Lock()
+	defer sub.stream.mu.Unlock()
+	sub.stream.remove(sub)
+}
+
+func (s *ClickStream) remove(sub *ClickSubscription) {
+	if _, ok := s.subscribers[sub]; !ok {
+		return
+	}
+
+	delete(s.subscribers, sub)
+	close(sub.c)
+}
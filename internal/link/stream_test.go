@@ -0,0 +1,99 @@
+package link_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+)
+
+func visit(linkID int, owner string) link.Event {
+	return link.Event{Type: link.EventVisited, LinkID: linkID, Owner: owner}
+}
+
+func TestClickStream_Subscribe(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	stream := link.NewClickStream(10)
+	sub := stream.Subscribe(func(c link.Click) bool { return c.Owner == "alice" }, 0, 10)
+	defer sub.Close()
+
+	// When
+	stream.Handle(ctx, visit(1, "alice"))
+	stream.Handle(ctx, visit(2, "bob"))
+	stream.Handle(ctx, link.Event{Type: link.EventCreated, LinkID: 3, Owner: "alice"})
+	stream.Handle(ctx, visit(3, "alice"))
+
+	// Then
+	require.Empty(t, sub.Replay)
+	first, second := <-sub.C, <-sub.C
+	require.Equal(t, uint64(1), first.ID)
+	require.Equal(t, 1, first.LinkID)
+	require.Equal(t, uint64(3), second.ID)
+	require.Equal(t, 3, second.LinkID)
+}
+
+func TestClickStream_Handle_Secret(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	stream := link.NewClickStream(10)
+	sub := stream.Subscribe(func(link.Click) bool { return true }, 0, 10)
+	defer sub.Close()
+
+	// When
+	stream.Handle(ctx, link.Event{Type: link.EventVisited, LinkID: 1, URL: "https://www.google.com/reset?token=1", Secret: true})
+	stream.Handle(ctx, link.Event{Type: link.EventVisited, LinkID: 2, URL: "https://www.google.com"})
+
+	// Then
+	secret, public := <-sub.C, <-sub.C
+	require.Empty(t, secret.URL, "the destination of a secret link must not be streamed")
+	require.Equal(t, "https://www.google.com", public.URL)
+}
+
+func TestClickStream_Subscribe_Resume(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	stream := link.NewClickStream(3)
+	for i := 0; i < 5; i++ {
+		stream.Handle(ctx, visit(1, ""))
+	}
+
+	// When
+	resumed := stream.Subscribe(func(link.Click) bool { return true }, 3, 10)
+	defer resumed.Close()
+	tooOld := stream.Subscribe(func(link.Click) bool { return true }, 1, 10)
+	defer tooOld.Close()
+
+	// Then
+	require.Len(t, resumed.Replay, 2)
+	require.Equal(t, uint64(4), resumed.Replay[0].ID)
+	require.Equal(t, uint64(5), resumed.Replay[1].ID)
+
+	// Only the last three clicks are buffered.
+	require.Len(t, tooOld.Replay, 3)
+	require.Equal(t, uint64(3), tooOld.Replay[0].ID)
+}
+
+func TestClickStream_Handle_SlowSubscriber(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	stream := link.NewClickStream(10)
+	slow := stream.Subscribe(func(link.Click) bool { return true }, 0, 1)
+	fast := stream.Subscribe(func(link.Click) bool { return true }, 0, 10)
+	defer fast.Close()
+
+	// When
+	stream.Handle(ctx, visit(1, ""))
+	stream.Handle(ctx, visit(1, ""))
+
+	// Then
+	<-slow.C
+	_, ok := <-slow.C
+	require.False(t, ok)
+	require.True(t, slow.Lagging())
+
+	require.Len(t, fast.C, 2)
+	require.False(t, fast.Lagging())
+	slow.Close()
+}
@@ -0,0 +1,124 @@
+package link
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"sort"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/hll"
+)
+
+const (
+	// visitorPrecision gives a standard error of 1.6% using 4KB per sketch.
+	visitorPrecision = 12
+
+	// visitorDays is the number of days for which the daily unique visitors are kept.
+	visitorDays = 30
+
+	dayLayout = "2006-01-02"
+)
+
+// Visit contains the information of a request to be redirected to a Link.
+type Visit struct {
+	Password string
+	// Access is used instead of Password when it is not nil.
+	Access    *Access
+	IP        string
+	UserAgent string
+	// Bot tells that the request was made by a crawler, a link unfurler or a prefetch,
+	// so it is not counted as a visit.
+	Bot bool
+}
+
+// UniqueVisitors returns the estimated number of distinct visitors of the link.
+func (l Link) UniqueVisitors() uint64 {
+	return estimate(l.Visitors)
+}
+
+// DailyUniqueVisitors returns the estimated number of distinct visitors of the link
+// per day, keyed by date in the format 2006-01-02 (UTC).
+func (l Link) DailyUniqueVisitors() map[string]uint64 {
+	daily := make(map[string]uint64, len(l.DailyVisitors))
+	for day, data := range l.DailyVisitors {
+		daily[day] = estimate(data)
+	}
+
+	return daily
+}
+
+func estimate(data []byte) uint64 {
+	if len(data) == 0 {
+		return 0
+	}
+
+	var sketch hll.Sketch
+	if err := sketch.UnmarshalBinary(data); err != nil {
+		return 0
+	}
+
+	return sketch.Count()
+}
+
+// fingerprint identifies a visitor without storing any personal data. The salt prevents
+// recovering the IP address of a visitor by brute force from the stored sketches.
+func fingerprint(salt []byte, v Visit) uint64 {
+	h := hmac.New(sha256.New, salt)
+	h.Write([]byte(v.IP))
+	h.Write([]byte{0})
+	h.Write([]byte(v.UserAgent))
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
+
+// recordVisitor adds the visitor to the sketches of l. The sketches are replaced instead
+// of modified in place, since l may share them with the copy held by the Repository.
+func recordVisitor(l *Link, fp uint64, now time.Time) error {
+	visitors, err := addToSketch(l.Visitors, fp)
+	if err != nil {
+		return err
+	}
+
+	today := now.UTC().Format(dayLayout)
+	daily, err := addToSketch(l.DailyVisitors[today], fp)
+	if err != nil {
+		return err
+	}
+
+	days := make([]string, 0, len(l.DailyVisitors)+1)
+	for day := range l.DailyVisitors {
+		if day != today {
+			days = append(days, day)
+		}
+	}
+	sort.Strings(days)
+	if len(days) >= visitorDays {
+		days = days[len(days)-visitorDays+1:]
+	}
+
+	dailyVisitors := make(map[string][]byte, len(days)+1)
+	for _, day := range days {
+		dailyVisitors[day] = l.DailyVisitors[day]
+	}
+	dailyVisitors[today] = daily
+
+	l.Visitors = visitors
+	l.DailyVisitors = dailyVisitors
+	return nil
+}
+
+func addToSketch(data []byte, fp uint64) ([]byte, error) {
+	sketch, err := hll.New(visitorPrecision)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > 0 {
+		if err := sketch.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+	}
+
+	sketch.Add(fp)
+	return sketch.MarshalBinary()
+}
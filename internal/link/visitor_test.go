@@ -0,0 +1,110 @@
+package link_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fastHasher keeps the hashes of saveLink, which would otherwise be upgraded to the default cost on the first redirect.
+var fastHasher = link.WithHasher(link.BcryptHasher{Cost: bcrypt.MinCost})
+
+// saveLink stores a link whose password is hashed with the minimum cost, to keep the tests fast.
+func saveLink(t *testing.T, r link.Repository, password string) link.Link {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	l := link.Link{URL: "https://www.google.com", Password: hash}
+	l.ID, err = r.Save(context.Background(), l)
+	require.NoError(t, err)
+	return l
+}
+
+func TestService_Redirect_UniqueVisitors(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 23, 0, 0, 0, time.UTC)
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher, link.WithVisitorSalt([]byte("salt")), link.WithClock(func() time.Time { return now }))
+
+	l := saveLink(t, repository, "1234")
+
+	redirect := func(ip, userAgent string) {
+		_, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234", IP: ip, UserAgent: userAgent})
+		require.NoError(t, err)
+	}
+
+	// When
+	redirect("10.0.0.1", "Firefox")
+	redirect("10.0.0.1", "Firefox")
+	redirect("10.0.0.1", "Chrome")
+	now = now.Add(2 * time.Hour)
+	redirect("10.0.0.2", "Firefox")
+	redirect("10.0.0.1", "Firefox")
+
+	// Then
+	l, err := service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.Equal(t, 5, l.Count)
+	require.Equal(t, uint64(3), l.UniqueVisitors())
+	require.Equal(t, map[string]uint64{"2021-06-01": 2, "2021-06-02": 2}, l.DailyUniqueVisitors())
+}
+
+func TestService_Redirect_DailyVisitorsRetention(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	repository := link.NewInMemoryRepository()
+	service := link.NewService(repository, fastHasher, link.WithVisitorSalt([]byte("salt")), link.WithClock(func() time.Time { return now }))
+
+	l := saveLink(t, repository, "1234")
+
+	// When
+	for day := 0; day < 40; day++ {
+		_, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234", IP: fmt.Sprintf("10.0.0.%d", day)})
+		require.NoError(t, err)
+		now = now.AddDate(0, 0, 1)
+	}
+
+	// Then
+	l, err := service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	daily := l.DailyUniqueVisitors()
+	require.Len(t, daily, 30)
+	require.NotContains(t, daily, "2021-06-10")
+	require.Contains(t, daily, "2021-06-11")
+	require.Contains(t, daily, "2021-07-10")
+	require.InDelta(t, 40, l.UniqueVisitors(), 2)
+}
+
+func TestService_Redirect_Bot(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	repository := link.NewInMemoryRepository()
+	bus := link.NewBus()
+	var events []link.Event
+	bus.Subscribe(func(_ context.Context, e link.Event) { events = append(events, e) })
+	service := link.NewService(repository, fastHasher, link.WithPublisher(bus))
+
+	l := saveLink(t, repository, "1234")
+
+	// When
+	_, err := service.Redirect(ctx, l.ID, link.Visit{Password: "1234", UserAgent: "Slackbot", Bot: true})
+	require.NoError(t, err)
+	_, err = service.Redirect(ctx, l.ID, link.Visit{Password: "wrong", Bot: true})
+
+	// Then
+	require.ErrorIs(t, err, link.ErrAuthentication)
+
+	l, err = service.FindByID(ctx, l.ID)
+	require.NoError(t, err)
+	require.Equal(t, 0, l.Count)
+	require.Equal(t, 1, l.BotCount)
+	require.Equal(t, uint64(0), l.UniqueVisitors())
+	require.Empty(t, events)
+}
@@ -0,0 +1,83 @@
+// Package filewatch reloads the files that configure the server while it runs, every time they are modified.
+package filewatch
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// File is a file whose content is handed to a parse function every time it is loaded. It is safe for
+// concurrent use.
+type File struct {
+	parse func(data []byte) error
+
+	mu      sync.Mutex
+	modTime time.Time
+}
+
+// New creates a File loaded by parse, which must keep the previous content in place when it fails.
+func New(parse func(data []byte) error) *File {
+	return &File{parse: parse}
+}
+
+// Load reads the file at path and passes its content to parse.
+func (f *File) Load(path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	if err := f.parse(data); err != nil {
+		return fmt.Errorf("loading %s: %w", path, err)
+	}
+
+	f.mu.Lock()
+	f.modTime = info.ModTime()
+	f.mu.Unlock()
+	return nil
+}
+
+// Watch loads the file at path every time it is modified, checking it at the given interval.
+// It blocks until ctx is done. Invalid files are logged and ignored, keeping the previous content.
+func (f *File) Watch(ctx context.Context, path string, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			info, err := os.Stat(path)
+			if err != nil {
+				log.Printf("filewatch: checking %s: %v", path, err)
+				continue
+			}
+
+			f.mu.Lock()
+			modified := !info.ModTime().Equal(f.modTime)
+			f.mu.Unlock()
+
+			if !modified {
+				continue
+			}
+
+			if err := f.Load(path); err != nil {
+				log.Printf("filewatch: %v", err)
+				continue
+			}
+
+			log.Printf("filewatch: %s reloaded", path)
+		}
+	}
+}
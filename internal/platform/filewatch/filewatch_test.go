@@ -0,0 +1,87 @@
+package filewatch_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/filewatch"
+	"github.com/stretchr/testify/require"
+)
+
+// content keeps the last valid content of a file, rejecting the empty ones.
+type content struct {
+	mu   sync.Mutex
+	data string
+}
+
+func (c *content) parse(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("empty file")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.data = string(data)
+	return nil
+}
+
+func (c *content) get() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.data
+}
+
+// modify writes data to path with a modification time that differs from the previous one.
+func modify(t *testing.T, path, data string, after time.Duration) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(data), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(after)))
+}
+
+func TestFile_Load(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte("first"), 0o600))
+	var c content
+	f := filewatch.New(c.parse)
+
+	// When
+	err := f.Load(path)
+	missingErr := f.Load(filepath.Join(t.TempDir(), "missing.json"))
+	modify(t, path, "", time.Minute)
+	invalidErr := f.Load(path)
+
+	// Then
+	require.NoError(t, err)
+	require.True(t, os.IsNotExist(missingErr))
+	require.EqualError(t, invalidErr, "loading "+path+": empty file")
+	require.Equal(t, "first", c.get(), "the previous content must be kept")
+}
+
+func TestFile_Watch(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte("first"), 0o600))
+	var c content
+	f := filewatch.New(c.parse)
+	require.NoError(t, f.Load(path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go f.Watch(ctx, path, time.Millisecond)
+
+	// When
+	modify(t, path, "", time.Minute)
+	time.Sleep(20 * time.Millisecond)
+	invalid := c.get()
+	modify(t, path, "second", 2*time.Minute)
+
+	// Then
+	require.Equal(t, "first", invalid, "invalid files must be ignored")
+	require.Eventually(t, func() bool { return c.get() == "second" }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,131 @@
+// Package hll implements the HyperLogLog algorithm to estimate the number of distinct
+// elements of a set using a small, fixed amount of memory.
+//
+// See http://algo.inria.fr/flajolet/Publications/FlFuGaMe07.pdf
+package hll
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/bits"
+)
+
+const (
+	// MinPrecision and MaxPrecision bound the number of bits of the hash used to select a register.
+	MinPrecision = 4
+	MaxPrecision = 16
+)
+
+// ErrPrecisionMismatch is returned when merging sketches with different precision.
+var ErrPrecisionMismatch = errors.New("sketches have different precision")
+
+// Sketch estimates the cardinality of a set. A sketch of precision p uses 2^p bytes and has
+// a standard error of about 1.04/sqrt(2^p), e.g. 1.6% with a precision of 12.
+//
+// The zero value is not usable; sketches must be created with New or decoded with UnmarshalBinary.
+type Sketch struct {
+	precision uint8
+	registers []uint8
+}
+
+// New creates an empty Sketch with the given precision.
+func New(precision uint8) (*Sketch, error) {
+	if precision < MinPrecision || precision > MaxPrecision {
+		return nil, fmt.Errorf("precision must be between %d and %d", MinPrecision, MaxPrecision)
+	}
+
+	return &Sketch{
+		precision: precision,
+		registers: make([]uint8, 1<<precision),
+	}, nil
+}
+
+// Add records an element of the set given its hash. The hash must be uniformly
+// distributed, like the output of a cryptographic hash function.
+func (s *Sketch) Add(hash uint64) {
+	index := hash >> (64 - s.precision)
+	// The remaining bits, with a sentinel bit so the rank is at most 64 - precision + 1.
+	w := hash<<s.precision | 1<<(s.precision-1)
+	rank := uint8(bits.LeadingZeros64(w)) + 1
+
+	if rank > s.registers[index] {
+		s.registers[index] = rank
+	}
+}
+
+// Count returns the estimated number of distinct elements added to the sketch.
+func (s *Sketch) Count() uint64 {
+	m := float64(len(s.registers))
+
+	var sum float64
+	var zeros int
+	for _, r := range s.registers {
+		sum += 1 / float64(uint64(1)<<r)
+		if r == 0 {
+			zeros++
+		}
+	}
+
+	estimate := alpha(m) * m * m / sum
+
+	// Small range correction: linear counting is more accurate while there are empty registers.
+	if estimate <= 2.5*m && zeros > 0 {
+		estimate = m * math.Log(m/float64(zeros))
+	}
+
+	return uint64(estimate + 0.5)
+}
+
+// Merge adds the elements of o to s, so s estimates the cardinality of the union of both sets.
+func (s *Sketch) Merge(o *Sketch) error {
+	if s.precision != o.precision {
+		return ErrPrecisionMismatch
+	}
+
+	for i, r := range o.registers {
+		if r > s.registers[i] {
+			s.registers[i] = r
+		}
+	}
+
+	return nil
+}
+
+// MarshalBinary encodes the sketch as its precision followed by its registers.
+func (s *Sketch) MarshalBinary() ([]byte, error) {
+	data := make([]byte, 1+len(s.registers))
+	data[0] = s.precision
+	copy(data[1:], s.registers)
+	return data, nil
+}
+
+// UnmarshalBinary decodes a sketch encoded by MarshalBinary. It copies data, so it can be modified afterwards.
+func (s *Sketch) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return errors.New("hll: empty data")
+	}
+
+	precision := data[0]
+	if precision < MinPrecision || precision > MaxPrecision || len(data) != 1+1<<precision {
+		return errors.New("hll: invalid data")
+	}
+
+	s.precision = precision
+	s.registers = make([]uint8, 1<<precision)
+	copy(s.registers, data[1:])
+	return nil
+}
+
+func alpha(m float64) float64 {
+	switch m {
+	case 16:
+		return 0.673
+	case 32:
+		return 0.697
+	case 64:
+		return 0.709
+	default:
+		return 0.7213 / (1 + 1.079/m)
+	}
+}
@@ -0,0 +1,83 @@
+package hll_test
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/internal/platform/hll"
+	"github.com/stretchr/testify/require"
+)
+
+func hash(s string) uint64 {
+	sum := sha256.Sum256([]byte(s))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+func TestSketch_Count(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		t.Run(strconv.Itoa(n), func(t *testing.T) {
+			// Given
+			sketch, err := hll.New(12)
+			require.NoError(t, err)
+
+			// When
+			for i := 0; i < n; i++ {
+				sketch.Add(hash(strconv.Itoa(i)))
+				// Repeated elements must not change the estimation.
+				sketch.Add(hash(strconv.Itoa(i)))
+			}
+
+			// Then
+			got := float64(sketch.Count())
+			require.LessOrEqual(t, math.Abs(got-float64(n)), float64(n)*0.05, "estimated %v for %d elements", got, n)
+		})
+	}
+}
+
+func TestSketch_Merge(t *testing.T) {
+	// Given
+	a, _ := hll.New(12)
+	b, _ := hll.New(12)
+	for i := 0; i < 1000; i++ {
+		a.Add(hash(strconv.Itoa(i)))
+		b.Add(hash(strconv.Itoa(i + 500)))
+	}
+
+	// When
+	err := a.Merge(b)
+
+	// Then
+	require.NoError(t, err)
+	require.InDelta(t, 1500, a.Count(), 1500*0.05)
+
+	c, _ := hll.New(10)
+	require.ErrorIs(t, a.Merge(c), hll.ErrPrecisionMismatch)
+}
+
+func TestSketch_MarshalBinary(t *testing.T) {
+	// Given
+	sketch, _ := hll.New(8)
+	for i := 0; i < 100; i++ {
+		sketch.Add(hash(strconv.Itoa(i)))
+	}
+
+	// When
+	data, err := sketch.MarshalBinary()
+	require.NoError(t, err)
+
+	var decoded hll.Sketch
+	err = decoded.UnmarshalBinary(data)
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, sketch.Count(), decoded.Count())
+	require.Error(t, decoded.UnmarshalBinary(data[:10]))
+}
+
+func TestNew_InvalidPrecision(t *testing.T) {
+	_, err := hll.New(hll.MaxPrecision + 1)
+	require.Error(t, err)
+}
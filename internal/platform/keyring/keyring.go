@@ -0,0 +1,135 @@
+// Package keyring holds the secret keys used to sign data, identified by an ID so they can be
+// rotated without invalidating what was signed with the previous ones, and revoked one by one.
+package keyring
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/filewatch"
+)
+
+// MinSecretSize is the minimum number of bytes of a secret.
+const MinSecretSize = 32
+
+// ErrNoKey is returned when signing with a Ring that has no usable key.
+var ErrNoKey = errors.New("keyring: no current key")
+
+// Key is a secret identified by ID.
+type Key struct {
+	ID string `json:"id"`
+	// Secret is encoded in base64 in the JSON file.
+	Secret []byte `json:"secret"`
+	// Revoked keys are kept in the file to make explicit that what they signed is no longer valid.
+	Revoked bool `json:"revoked"`
+}
+
+// Keys is the content of a key ring file.
+type Keys struct {
+	// Current is the ID of the key used to sign. The last key that is not revoked if empty.
+	Current string `json:"current"`
+	Keys    []Key  `json:"keys"`
+}
+
+// Ring is a set of keys safe for concurrent use.
+type Ring struct {
+	mu      sync.RWMutex
+	keys    map[string]Key
+	current string
+	file    *filewatch.File
+}
+
+// New creates a Ring with the given keys.
+func New(k Keys) (*Ring, error) {
+	r := &Ring{}
+	r.file = filewatch.New(func(data []byte) error {
+		var k Keys
+		if err := json.Unmarshal(data, &k); err != nil {
+			return err
+		}
+
+		return r.Set(k)
+	})
+
+	if err := r.Set(k); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Set replaces the keys of the ring.
+func (r *Ring) Set(k Keys) error {
+	keys := make(map[string]Key, len(k.Keys))
+	current := k.Current
+
+	for _, key := range k.Keys {
+		if key.ID == "" {
+			return errors.New("keyring: key without id")
+		}
+
+		if _, ok := keys[key.ID]; ok {
+			return fmt.Errorf("keyring: duplicated key %q", key.ID)
+		}
+
+		if len(key.Secret) < MinSecretSize {
+			return fmt.Errorf("keyring: secret of key %q must have at least %d bytes", key.ID, MinSecretSize)
+		}
+
+		keys[key.ID] = key
+		if k.Current == "" && !key.Revoked {
+			current = key.ID
+		}
+	}
+
+	if current != "" {
+		if key, ok := keys[current]; !ok || key.Revoked {
+			return fmt.Errorf("keyring: current key %q is missing or revoked", current)
+		}
+	}
+
+	r.mu.Lock()
+	r.keys = keys
+	r.current = current
+	r.mu.Unlock()
+	return nil
+}
+
+// Current returns the key used to sign.
+func (r *Ring) Current() (Key, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if r.current == "" {
+		return Key{}, ErrNoKey
+	}
+
+	return r.keys[r.current], nil
+}
+
+// Get returns the key identified by id, unless it does not exist or it was revoked.
+func (r *Ring) Get(id string) (Key, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	key, ok := r.keys[id]
+	if !ok || key.Revoked {
+		return Key{}, false
+	}
+
+	return key, true
+}
+
+// Load replaces the keys of the ring with the ones in the JSON file at path.
+func (r *Ring) Load(path string) error {
+	return r.file.Load(path)
+}
+
+// Watch reloads the keys from path every time the file is modified, as described by filewatch.File.Watch.
+func (r *Ring) Watch(ctx context.Context, path string, interval time.Duration) {
+	r.file.Watch(ctx, path, interval)
+}
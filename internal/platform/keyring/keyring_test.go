@@ -0,0 +1,126 @@
+package keyring_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/keyring"
+	"github.com/stretchr/testify/require"
+)
+
+func secret(b byte) []byte {
+	return bytes.Repeat([]byte{b}, keyring.MinSecretSize)
+}
+
+func TestRing_Current(t *testing.T) {
+	tt := []struct {
+		name        string
+		keys        keyring.Keys
+		wantCurrent string
+		wantErr     bool
+	}{
+		{
+			name:        "last key that is not revoked",
+			keys:        keyring.Keys{Keys: []keyring.Key{{ID: "a", Secret: secret(1)}, {ID: "b", Secret: secret(2)}, {ID: "c", Secret: secret(3), Revoked: true}}},
+			wantCurrent: "b",
+		},
+		{
+			name:        "explicit",
+			keys:        keyring.Keys{Current: "a", Keys: []keyring.Key{{ID: "a", Secret: secret(1)}, {ID: "b", Secret: secret(2)}}},
+			wantCurrent: "a",
+		},
+		{
+			name:    "revoked current",
+			keys:    keyring.Keys{Current: "a", Keys: []keyring.Key{{ID: "a", Secret: secret(1), Revoked: true}}},
+			wantErr: true,
+		},
+		{
+			name:    "short secret",
+			keys:    keyring.Keys{Keys: []keyring.Key{{ID: "a", Secret: []byte("short")}}},
+			wantErr: true,
+		},
+		{
+			name:    "duplicated id",
+			keys:    keyring.Keys{Keys: []keyring.Key{{ID: "a", Secret: secret(1)}, {ID: "a", Secret: secret(2)}}},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// When
+			ring, err := keyring.New(tc.keys)
+
+			// Then
+			if tc.wantErr {
+				require.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			key, err := ring.Current()
+			require.NoError(t, err)
+			require.Equal(t, tc.wantCurrent, key.ID)
+		})
+	}
+}
+
+func TestRing_Get(t *testing.T) {
+	// Given
+	ring, err := keyring.New(keyring.Keys{Keys: []keyring.Key{{ID: "old", Secret: secret(1), Revoked: true}, {ID: "new", Secret: secret(2)}}})
+	require.NoError(t, err)
+
+	// When
+	_, oldFound := ring.Get("old")
+	newKey, newFound := ring.Get("new")
+	_, missingFound := ring.Get("missing")
+
+	// Then
+	require.False(t, oldFound)
+	require.True(t, newFound)
+	require.Equal(t, secret(2), newKey.Secret)
+	require.False(t, missingFound)
+}
+
+func TestRing_Current_Empty(t *testing.T) {
+	ring, err := keyring.New(keyring.Keys{})
+	require.NoError(t, err)
+
+	_, err = ring.Current()
+	require.ErrorIs(t, err, keyring.ErrNoKey)
+}
+
+func TestRing_Watch(t *testing.T) {
+	// Given
+	path := filepath.Join(t.TempDir(), "keys.json")
+	first := `{"keys":[{"id":"first","secret":"AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE="}]}`
+	require.NoError(t, ioutil.WriteFile(path, []byte(first), 0o600))
+
+	ring, err := keyring.New(keyring.Keys{})
+	require.NoError(t, err)
+	require.NoError(t, ring.Load(path))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ring.Watch(ctx, path, time.Millisecond)
+
+	// When
+	rotated := `{"keys":[{"id":"first","secret":"AQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQEBAQE=","revoked":true},
+		{"id":"second","secret":"AgICAgICAgICAgICAgICAgICAgICAgICAgICAgICAgI="}]}`
+	require.NoError(t, ioutil.WriteFile(path, []byte(rotated), 0o600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	// Then
+	require.Eventually(t, func() bool {
+		key, err := ring.Current()
+		return err == nil && key.ID == "second"
+	}, time.Second, time.Millisecond)
+
+	_, ok := ring.Get("first")
+	require.False(t, ok)
+}
@@ -0,0 +1,159 @@
+// Package qr renders QR codes as PNG or SVG images.
+package qr
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// Level is the error correction level of a QR code. Higher levels can be
+// read even if part of the code is damaged, at the cost of a denser code.
+type Level string
+
+const (
+	// LevelL recovers up to 7% of the code.
+	LevelL Level = "L"
+	// LevelM recovers up to 15% of the code.
+	LevelM Level = "M"
+	// LevelQ recovers up to 25% of the code.
+	LevelQ Level = "Q"
+	// LevelH recovers up to 30% of the code.
+	LevelH Level = "H"
+)
+
+// ErrSizeTooSmall is returned when the requested size cannot fit a pixel per module of the code.
+var ErrSizeTooSmall = errors.New("size is too small for the content")
+
+// Options controls how a QR code is rendered.
+type Options struct {
+	// Size is the width and height of the image in pixels.
+	Size int
+	// Level is the error correction level.
+	Level Level
+	// Margin is the width of the quiet zone around the code, measured in modules.
+	Margin int
+}
+
+// ParseLevel returns the Level represented by s.
+func ParseLevel(s string) (Level, error) {
+	switch l := Level(strings.ToUpper(s)); l {
+	case LevelL, LevelM, LevelQ, LevelH:
+		return l, nil
+	default:
+		return "", fmt.Errorf("unknown error correction level %q", s)
+	}
+}
+
+// PNG encodes content as a QR code in a PNG image.
+func PNG(content string, opts Options) ([]byte, error) {
+	bitmap, err := encode(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	scale, offset, err := layout(len(bitmap), opts)
+	if err != nil {
+		return nil, err
+	}
+
+	palette := color.Palette{color.White, color.Black}
+	img := image.NewPaletted(image.Rect(0, 0, opts.Size, opts.Size), palette)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex(offset+x*scale+dx, offset+y*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// SVG encodes content as a QR code in an SVG image. The image scales without
+// losing quality, so opts.Size only sets its default width and height.
+func SVG(content string, opts Options) ([]byte, error) {
+	bitmap, err := encode(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(bitmap)
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, opts.Size, opts.Size, n, n)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#ffffff"/><path fill="#000000" d="`, n, n)
+	for y, row := range bitmap {
+		for x, dark := range row {
+			if dark {
+				fmt.Fprintf(&buf, "M%d %dh1v1h-1z", x, y)
+			}
+		}
+	}
+	buf.WriteString(`"/></svg>`)
+
+	return buf.Bytes(), nil
+}
+
+// encode returns the modules of the code surrounded by the quiet zone.
+func encode(content string, opts Options) ([][]bool, error) {
+	var level qrcode.RecoveryLevel
+	switch opts.Level {
+	case LevelL:
+		level = qrcode.Low
+	case LevelM, "":
+		level = qrcode.Medium
+	case LevelQ:
+		level = qrcode.High
+	case LevelH:
+		level = qrcode.Highest
+	default:
+		return nil, fmt.Errorf("unknown error correction level %q", opts.Level)
+	}
+
+	code, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, err
+	}
+
+	code.DisableBorder = true
+	modules := code.Bitmap()
+
+	n := len(modules) + 2*opts.Margin
+	bitmap := make([][]bool, n)
+	for y := range bitmap {
+		bitmap[y] = make([]bool, n)
+	}
+
+	for y, row := range modules {
+		copy(bitmap[y+opts.Margin][opts.Margin:], row)
+	}
+
+	return bitmap, nil
+}
+
+// layout returns the pixels per module and the offset needed to center a code of n modules in the image.
+func layout(n int, opts Options) (scale, offset int, err error) {
+	scale = opts.Size / n
+	if scale < 1 {
+		return 0, 0, ErrSizeTooSmall
+	}
+
+	return scale, (opts.Size - scale*n) / 2, nil
+}
@@ -0,0 +1,60 @@
+package qr_test
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/internal/platform/qr"
+	"github.com/stretchr/testify/require"
+)
+
+func isDark(img image.Image, x, y int) bool {
+	r, g, b, _ := img.At(x, y).RGBA()
+	return r == 0 && g == 0 && b == 0
+}
+
+func TestPNG(t *testing.T) {
+	// Given
+	opts := qr.Options{Size: 290, Level: qr.LevelL, Margin: 4}
+
+	// When
+	data, err := qr.PNG("http://localhost:8080/link/1", opts)
+	require.NoError(t, err)
+
+	// Then
+	img, err := png.Decode(bytes.NewReader(data))
+	require.NoError(t, err)
+	require.Equal(t, image.Rect(0, 0, 290, 290), img.Bounds())
+
+	// A version 2 code has 25 modules, plus 4 on each side: 33 modules of 8 pixels centered in the image.
+	offset := (290 - 33*8) / 2
+	require.False(t, isDark(img, offset+4*8-1, offset+4*8-1), "quiet zone must be light")
+	require.True(t, isDark(img, offset+4*8, offset+4*8), "finder pattern must start after the margin")
+}
+
+func TestPNG_SizeTooSmall(t *testing.T) {
+	_, err := qr.PNG("http://localhost:8080/link/1", qr.Options{Size: 20, Level: qr.LevelH, Margin: 4})
+	require.ErrorIs(t, err, qr.ErrSizeTooSmall)
+}
+
+func TestSVG(t *testing.T) {
+	// When
+	data, err := qr.SVG("http://localhost:8080/link/1", qr.Options{Size: 128, Level: qr.LevelL, Margin: 0})
+	require.NoError(t, err)
+
+	// Then
+	svg := string(data)
+	require.Contains(t, svg, `width="128" height="128" viewBox="0 0 25 25"`)
+	require.Contains(t, svg, "M0 0h1v1h-1z")
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := qr.ParseLevel("q")
+	require.NoError(t, err)
+	require.Equal(t, qr.LevelQ, level)
+
+	_, err = qr.ParseLevel("X")
+	require.Error(t, err)
+}
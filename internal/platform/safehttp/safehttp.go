@@ -0,0 +1,127 @@
+// Package safehttp provides an HTTP client to request URLs given by the users, which
+// refuses to connect to addresses that are not reachable from the Internet.
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrPrivateAddress is returned when the destination resolves to an address that is not
+// reachable from the Internet, to prevent using the service to scan the internal network.
+var ErrPrivateAddress = errors.New("destination address is not public")
+
+// Config limits the requests made by the client.
+type Config struct {
+	// Timeout is the maximum amount of time of a request, including redirects.
+	Timeout time.Duration
+	// MaxRedirects is the maximum number of redirects followed.
+	MaxRedirects int
+	// AllowPrivate disables the protection against requesting private addresses. It is meant for tests.
+	AllowPrivate bool
+}
+
+// NewClient creates a client that refuses to connect to private addresses unless cfg allows it.
+// The check is done on the resolved address of every connection, so it also covers redirects
+// and host names that resolve to private addresses.
+func NewClient(cfg Config) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: cfg.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if cfg.AllowPrivate {
+				return nil
+			}
+
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if ip := net.ParseIP(host); ip == nil || !IsPublic(ip) {
+				return ErrPrivateAddress
+			}
+
+			return nil
+		},
+	}
+
+	transport := &http.Transport{
+		// Proxies are not used, otherwise the address checked would be the one of the proxy.
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   cfg.Timeout,
+		ResponseHeaderTimeout: cfg.Timeout,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   cfg.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) > cfg.MaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", cfg.MaxRedirects)
+			}
+
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("unsupported redirect to %q", req.URL.Scheme)
+			}
+
+			return nil
+		},
+	}
+}
+
+var privateNetworks = mustParseCIDRs(
+	"0.0.0.0/8",       // "This" network
+	"10.0.0.0/8",      // Private-use
+	"100.64.0.0/10",   // Shared address space
+	"127.0.0.0/8",     // Loopback
+	"169.254.0.0/16",  // Link local
+	"172.16.0.0/12",   // Private-use
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // Documentation
+	"192.168.0.0/16",  // Private-use
+	"198.18.0.0/15",   // Benchmarking
+	"198.51.100.0/24", // Documentation
+	"203.0.113.0/24",  // Documentation
+	"224.0.0.0/4",     // Multicast
+	"240.0.0.0/4",     // Reserved
+	"::/128",          // Unspecified
+	"::1/128",         // Loopback
+	"64:ff9b::/96",    // IPv4-IPv6 translation
+	"100::/64",        // Discard-only
+	"2001:db8::/32",   // Documentation
+	"fc00::/7",        // Unique local
+	"fe80::/10",       // Link local
+	"ff00::/8",        // Multicast
+)
+
+// IsPublic tells whether ip is reachable from the Internet.
+func IsPublic(ip net.IP) bool {
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, c := range cidrs {
+		_, n, err := net.ParseCIDR(c)
+		if err != nil {
+			panic(err)
+		}
+
+		networks = append(networks, n)
+	}
+
+	return networks
+}
@@ -0,0 +1,117 @@
+package safehttp_test
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/safehttp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsPublic(t *testing.T) {
+	tt := []struct {
+		ip   string
+		want bool
+	}{
+		{ip: "127.0.0.1", want: false},
+		{ip: "127.1.2.3", want: false},
+		{ip: "::1", want: false},
+		{ip: "10.1.2.3", want: false},
+		{ip: "172.16.0.1", want: false},
+		{ip: "172.31.255.255", want: false},
+		{ip: "192.168.1.1", want: false},
+		{ip: "169.254.169.254", want: false},
+		{ip: "fe80::1", want: false},
+		{ip: "100.64.0.1", want: false},
+		{ip: "0.0.0.0", want: false},
+		{ip: "::", want: false},
+		{ip: "::ffff:127.0.0.1", want: false},
+		{ip: "::ffff:10.0.0.1", want: false},
+		{ip: "::ffff:169.254.169.254", want: false},
+		{ip: "fd00::1", want: false},
+		{ip: "8.8.8.8", want: true},
+		{ip: "172.32.0.1", want: true},
+		{ip: "::ffff:8.8.8.8", want: true},
+		{ip: "2001:4860:4860::8888", want: true},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.ip, func(t *testing.T) {
+			ip := net.ParseIP(tc.ip)
+			require.NotNil(t, ip)
+			require.Equal(t, tc.want, safehttp.IsPublic(ip))
+		})
+	}
+}
+
+func TestNewClient_ResolvedAddress(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	_, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+
+	tt := []struct {
+		name string
+		url  string
+	}{
+		{name: "ip", url: server.URL},
+		// The name is not private by itself: the address it resolves to is checked when dialing.
+		{name: "host name", url: "http://localhost:" + port},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// When
+			_, err := safehttp.NewClient(safehttp.Config{Timeout: time.Second}).Get(tc.url)
+			resp, allowedErr := safehttp.NewClient(safehttp.Config{Timeout: time.Second, AllowPrivate: true}).Get(tc.url)
+
+			// Then
+			require.ErrorIs(t, err, safehttp.ErrPrivateAddress)
+			var opErr *net.OpError
+			require.True(t, errors.As(err, &opErr), "the address must be refused when dialing")
+			require.Equal(t, "dial", opErr.Op)
+
+			require.NoError(t, allowedErr)
+			resp.Body.Close()
+			require.Equal(t, http.StatusNoContent, resp.StatusCode)
+		})
+	}
+}
+
+func TestNewClient_Redirects(t *testing.T) {
+	tt := []struct {
+		name     string
+		location string
+		wantErr  string
+	}{
+		{name: "unsupported scheme", location: "ftp://example.com/file", wantErr: `unsupported redirect to "ftp"`},
+		{name: "file scheme", location: "file:///etc/passwd", wantErr: `unsupported redirect to "file"`},
+		{name: "too many", location: "/loop", wantErr: "stopped after 2 redirects"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, tc.location, http.StatusFound)
+			}))
+			defer server.Close()
+			client := safehttp.NewClient(safehttp.Config{Timeout: time.Second, MaxRedirects: 2, AllowPrivate: true})
+
+			// When
+			_, err := client.Get(server.URL)
+
+			// Then
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// RealIP returns a middleware that resolves the IP of the client and stores it in the context of the request,
+// where ClientIP finds it. The Forwarded, X-Forwarded-For and X-Real-IP headers are only honored when the
+// peer is one of the trusted proxies; they are read from right to left, and the first address that is not
+// a trusted proxy is the client.
+func RealIP(trusted ...*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := resolveIP(r, trusted)
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey, ip)))
+		})
+	}
+}
+
+func resolveIP(r *http.Request, trusted []*net.IPNet) string {
+	ip := peerIP(r)
+	if !isTrusted(net.ParseIP(ip), trusted) {
+		return ip
+	}
+
+	hops := forwardedFor(r.Header)
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(hops[i])
+		if hop == nil {
+			break
+		}
+
+		ip = hop.String()
+		if !isTrusted(hop, trusted) {
+			break
+		}
+	}
+
+	return ip
+}
+
+// forwardedFor returns the addresses that forwarded the request, from the client to the last proxy.
+func forwardedFor(h http.Header) []string {
+	var hops []string
+	if values := h.Values("Forwarded"); len(values) > 0 {
+		for _, element := range strings.Split(strings.Join(values, ","), ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) == 2 && strings.EqualFold(kv[0], "for") {
+					hops = append(hops, forwardedNode(kv[1]))
+				}
+			}
+		}
+		return hops
+	}
+
+	if values := h.Values("X-Forwarded-For"); len(values) > 0 {
+		for _, hop := range strings.Split(strings.Join(values, ","), ",") {
+			hops = append(hops, strings.TrimSpace(hop))
+		}
+		return hops
+	}
+
+	if ip := h.Get("X-Real-IP"); ip != "" {
+		hops = append(hops, strings.TrimSpace(ip))
+	}
+
+	return hops
+}
+
+// forwardedNode returns the IP of a node of the Forwarded header, such as "192.0.2.60",
+// "\"192.0.2.60:8080\"" or "\"[2001:db8::1]:8080\"".
+func forwardedNode(node string) string {
+	node = strings.Trim(node, `"`)
+	if host, _, err := net.SplitHostPort(node); err == nil {
+		return host
+	}
+
+	return strings.Trim(node, "[]")
+}
+
+func peerIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}
+
+func isTrusted(ip net.IP, trusted []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, n := range trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
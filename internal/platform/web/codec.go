@@ -0,0 +1,147 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Codec decodes the bodies of the requests and encodes the bodies of the responses of a media type.
+type Codec struct {
+	ContentType string
+	// Decode is nil if the requests can't have this media type.
+	Decode func(r io.Reader, val interface{}) error
+	// Encode is nil if the responses can't have this media type.
+	Encode func(w io.Writer, val interface{}) error
+}
+
+var jsonCodec = Codec{
+	ContentType: "application/json",
+	Decode: func(r io.Reader, val interface{}) error {
+		decoder := json.NewDecoder(r)
+		decoder.DisallowUnknownFields()
+		return decoder.Decode(val)
+	},
+	Encode: func(w io.Writer, val interface{}) error {
+		data, err := json.Marshal(val)
+		if err != nil {
+			return err
+		}
+
+		_, err = w.Write(data)
+		return err
+	},
+}
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = make(map[string]Codec)
+)
+
+func init() {
+	for _, c := range []Codec{jsonCodec, formCodec, xmlCodec, msgpackCodec} {
+		RegisterCodec(c)
+	}
+
+	RegisterCodec(Codec{ContentType: "text/xml", Decode: xmlCodec.Decode, Encode: xmlCodec.Encode})
+	RegisterCodec(Codec{ContentType: "application/x-msgpack", Decode: msgpackCodec.Decode, Encode: msgpackCodec.Encode})
+}
+
+// RegisterCodec makes Decode and Respond support the media type of c, replacing the codec registered
+// for it, if any. JSON, form-encoded, XML and MessagePack are supported by default.
+func RegisterCodec(c Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[c.ContentType] = c
+}
+
+func codecOf(mediaType string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	c, ok := codecs[mediaType]
+	return c, ok
+}
+
+// requestCodec returns the codec of the Content-Type of r. Requests without Content-Type are JSON.
+func requestCodec(r *http.Request) (Codec, error) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		return jsonCodec, nil
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return Codec{}, NewErrorf(http.StatusUnsupportedMediaType, "invalid content type %q", contentType)
+	}
+
+	c, ok := codecOf(mediaType)
+	if !ok || c.Decode == nil {
+		return Codec{}, NewErrorf(http.StatusUnsupportedMediaType, "unsupported content type %q", mediaType)
+	}
+
+	return c, nil
+}
+
+// responseCodec returns the codec of the media type preferred by the Accept header. Wildcards and
+// an empty header select JSON.
+func responseCodec(accept string) (Codec, error) {
+	if strings.TrimSpace(accept) == "" {
+		return jsonCodec, nil
+	}
+
+	type acceptedType struct {
+		mediaType string
+		q         float64
+	}
+
+	var accepted []acceptedType
+	for _, value := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+
+		if q > 0 {
+			accepted = append(accepted, acceptedType{mediaType: mediaType, q: q})
+		}
+	}
+
+	sort.SliceStable(accepted, func(i, j int) bool {
+		return accepted[i].q > accepted[j].q
+	})
+
+	for _, a := range accepted {
+		if a.mediaType == "*/*" || a.mediaType == "application/*" {
+			return jsonCodec, nil
+		}
+
+		if c, ok := codecOf(a.mediaType); ok && c.Encode != nil {
+			return c, nil
+		}
+	}
+
+	return Codec{}, NewErrorf(http.StatusNotAcceptable, "none of the accepted media types %q is supported", accept)
+}
+
+// encode returns val encoded by c.
+func encode(c Codec, val interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := c.Encode(&buf, val); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
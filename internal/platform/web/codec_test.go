@@ -0,0 +1,338 @@
+package web_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+type code struct {
+	Name      string     `json:"name"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type payload struct {
+	Link    string            `json:"link" validate:"required"`
+	Visits  int               `json:"visits,omitempty"`
+	Public  bool              `json:"public"`
+	Tags    []string          `json:"tags,omitempty"`
+	Codes   []code            `json:"codes,omitempty"`
+	Daily   map[string]uint64 `json:"daily,omitempty"`
+	Comment string            `json:"comment,omitempty"`
+}
+
+var expiresAt = time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+
+var want = payload{
+	Link:   "https://www.google.com",
+	Visits: 3,
+	Public: true,
+	Tags:   []string{"a", "b"},
+	Codes:  []code{{Name: "alice", ExpiresAt: &expiresAt}, {Name: "bob"}},
+	Daily:  map[string]uint64{"2021-06-01": 7},
+}
+
+func mustMsgpack(t *testing.T, v interface{}) string {
+	data, err := msgpack.Marshal(v)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestDecode_Codecs(t *testing.T) {
+	tt := []struct {
+		name        string
+		contentType string
+		body        string
+	}{
+		{
+			name:        "json",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"link":"https://www.google.com","visits":3,"public":true,"tags":["a","b"],"codes":[{"name":"alice","expires_at":"2021-06-01T10:00:00Z"},{"name":"bob"}],"daily":{"2021-06-01":7}}`,
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "link=https%3A%2F%2Fwww.google.com&visits=3&public=on&tags=a&tags=b&codes[0].name=alice&codes[0].expires_at=2021-06-01T10:00:00Z&codes[1].name=bob&daily.2021-06-01=7",
+		},
+		{
+			name:        "form with a json body",
+			contentType: "application/x-www-form-urlencoded",
+			body:        `{"link":"https://www.google.com","visits":3,"public":true,"tags":["a","b"],"codes":[{"name":"alice","expires_at":"2021-06-01T10:00:00Z"},{"name":"bob"}],"daily":{"2021-06-01":7}}`,
+		},
+		{
+			name:        "xml",
+			contentType: "application/xml",
+			body: `<?xml version="1.0"?>
+<request>
+	<link>https://www.google.com</link>
+	<visits>3</visits>
+	<public>true</public>
+	<tags>a</tags>
+	<tags>b</tags>
+	<codes><name>alice</name><expires_at>2021-06-01T10:00:00Z</expires_at></codes>
+	<codes><name>bob</name></codes>
+	<daily><entry key="2021-06-01">7</entry></daily>
+</request>`,
+		},
+		{
+			name:        "msgpack",
+			contentType: "application/msgpack",
+			body: mustMsgpack(t, map[string]interface{}{
+				"link": "https://www.google.com", "visits": 3, "public": true, "tags": []string{"a", "b"},
+				"codes": []map[string]string{{"name": "alice", "expires_at": "2021-06-01T10:00:00Z"}, {"name": "bob"}},
+				"daily": map[string]int{"2021-06-01": 7},
+			}),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+
+			// When
+			var got payload
+			err := web.Decode(r, &got)
+
+			// Then
+			require.NoError(t, err)
+			require.Equal(t, want, got)
+		})
+	}
+}
+
+func TestDecode_Errors(t *testing.T) {
+	tt := []struct {
+		name        string
+		contentType string
+		body        string
+		wantStatus  int
+		wantMessage string
+	}{
+		{
+			name:        "unsupported content type",
+			contentType: "text/plain",
+			body:        "https://www.google.com",
+			wantStatus:  http.StatusUnsupportedMediaType,
+			wantMessage: `unsupported content type "text/plain"`,
+		},
+		{
+			name:        "unknown form field",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "link=https%3A%2F%2Fwww.google.com&owner=alice",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: `unknown field "owner"`,
+		},
+		{
+			name:        "invalid form number",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "link=https%3A%2F%2Fwww.google.com&visits=three",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: `invalid integer "three" for visits`,
+		},
+		{
+			name:        "conflicting form keys",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "codes=alice&codes[0].name=bob",
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: `conflicting key`,
+		},
+		{
+			name:        "unknown xml field",
+			contentType: "text/xml",
+			body:        `<request><link>https://www.google.com</link><codes><owner>alice</owner></codes></request>`,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: `unknown field "codes[0].owner"`,
+		},
+		{
+			name:        "invalid",
+			contentType: "application/xml",
+			body:        `<request></request>`,
+			wantStatus:  http.StatusBadRequest,
+			wantMessage: "link is missing",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+			r.Header.Set("Content-Type", tc.contentType)
+
+			// When
+			var got payload
+			err := web.Decode(r, &got)
+
+			// Then
+			var webErr *web.Error
+			require.ErrorAs(t, err, &webErr)
+			require.Equal(t, tc.wantStatus, webErr.Status)
+			require.Contains(t, webErr.Message, tc.wantMessage)
+		})
+	}
+}
+
+func TestRespond_Codecs(t *testing.T) {
+	tt := []struct {
+		accept          string
+		wantContentType string
+		wantBody        func(t *testing.T, body []byte)
+	}{
+		{
+			accept:          "",
+			wantContentType: "application/json",
+			wantBody: func(t *testing.T, body []byte) {
+				require.JSONEq(t, `{"link":"https://www.google.com","visits":3,"public":true,"tags":["a","b"],
+					"codes":[{"name":"alice","expires_at":"2021-06-01T10:00:00Z"},{"name":"bob"}],"daily":{"2021-06-01":7}}`, string(body))
+			},
+		},
+		{
+			accept:          "text/html, application/xml;q=0.9, */*;q=0.8",
+			wantContentType: "application/xml",
+			wantBody: func(t *testing.T, body []byte) {
+				require.Equal(t, `<response><link>https://www.google.com</link><visits>3</visits><public>true</public>`+
+					`<tags>a</tags><tags>b</tags><codes><name>alice</name><expires_at>2021-06-01T10:00:00Z</expires_at></codes>`+
+					`<codes><name>bob</name></codes><daily><entry key="2021-06-01">7</entry></daily></response>`, string(body))
+			},
+		},
+		{
+			accept:          "application/msgpack",
+			wantContentType: "application/msgpack",
+			wantBody: func(t *testing.T, body []byte) {
+				var got map[string]interface{}
+				require.NoError(t, msgpack.Unmarshal(body, &got))
+				require.Equal(t, "https://www.google.com", got["link"])
+				require.EqualValues(t, 3, got["visits"])
+				require.Equal(t, "2021-06-01T10:00:00Z", got["codes"].([]interface{})[0].(map[string]interface{})["expires_at"])
+			},
+		},
+		{
+			accept:          "application/*",
+			wantContentType: "application/json",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.accept, func(t *testing.T) {
+			// Given
+			h := web.Handler(func(w http.ResponseWriter, r *http.Request) error {
+				return web.Respond(r.Context(), w, want, http.StatusOK)
+			})
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", tc.accept)
+			w := httptest.NewRecorder()
+
+			// When
+			h.ServeHTTP(w, r)
+
+			// Then
+			require.Equal(t, http.StatusOK, w.Code)
+			require.Equal(t, tc.wantContentType, w.Header().Get("Content-Type"))
+			require.Equal(t, "Accept", w.Header().Get("Vary"))
+			if tc.wantBody != nil {
+				tc.wantBody(t, w.Body.Bytes())
+			}
+		})
+	}
+}
+
+func TestRespond_List(t *testing.T) {
+	// Given
+	h := web.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(r.Context(), w, []payload{{Link: "a"}, {Link: "b"}}, http.StatusOK)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/xml")
+	w := httptest.NewRecorder()
+
+	// When
+	h.ServeHTTP(w, r)
+
+	// Then
+	require.Equal(t, `<response><item><link>a</link><public>false</public></item><item><link>b</link><public>false</public></item></response>`, w.Body.String())
+}
+
+func TestRespond_NotAcceptable(t *testing.T) {
+	// Given
+	h := web.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(r.Context(), w, want, http.StatusOK)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "image/png")
+	w := httptest.NewRecorder()
+
+	// When
+	h.ServeHTTP(w, r)
+
+	// Then
+	require.Equal(t, http.StatusNotAcceptable, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"code":"not_acceptable","message":"none of the accepted media types \"image/png\" is supported"}`, w.Body.String())
+}
+
+func TestNegotiate(t *testing.T) {
+	// Given
+	called := false
+	h := web.Negotiate(web.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		called = true
+		return web.Respond(r.Context(), w, want, http.StatusOK)
+	}))
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Accept", "text/html")
+	w := httptest.NewRecorder()
+
+	// When
+	h.ServeHTTP(w, r)
+
+	// Then
+	require.False(t, called)
+	require.Equal(t, http.StatusNotAcceptable, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
+func TestRespond_WithoutHandler(t *testing.T) {
+	w := httptest.NewRecorder()
+	require.NoError(t, web.Respond(context.Background(), w, want, http.StatusOK))
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+}
+
+func TestRegisterCodec(t *testing.T) {
+	// Given
+	web.RegisterCodec(web.Codec{
+		ContentType: "text/plain",
+		Encode: func(w io.Writer, val interface{}) error {
+			_, err := fmt.Fprint(w, val.(payload).Link)
+			return err
+		},
+	})
+	h := web.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(r.Context(), w, want, http.StatusOK)
+	})
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Accept", "text/plain")
+	w := httptest.NewRecorder()
+
+	// When
+	h.ServeHTTP(w, r)
+
+	// Then
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "text/plain", w.Header().Get("Content-Type"))
+	require.Equal(t, "https://www.google.com", w.Body.String())
+
+	// The codec can't decode requests.
+	r = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("https://www.google.com"))
+	r.Header.Set("Content-Type", "text/plain")
+	var got payload
+	require.Error(t, web.Decode(r, &got))
+}
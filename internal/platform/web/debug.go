@@ -0,0 +1,29 @@
+package web
+
+import (
+	"expvar"
+	"fmt"
+	"net/http"
+)
+
+// VarsHandler serves the variables published with expvar as a JSON object, like expvar.Handler, but
+// leaves out cmdline: it holds the arguments of the program, and with them the secrets given in its
+// flags. It must only be served to the operators, as the variables describe the internals of the server.
+func VarsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		fmt.Fprintf(w, "{\n")
+		first := true
+		expvar.Do(func(kv expvar.KeyValue) {
+			if kv.Key == "cmdline" {
+				return
+			}
+			if !first {
+				fmt.Fprintf(w, ",\n")
+			}
+			first = false
+			fmt.Fprintf(w, "%q: %s", kv.Key, kv.Value)
+		})
+		fmt.Fprintf(w, "\n}\n")
+	})
+}
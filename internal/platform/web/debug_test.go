@@ -0,0 +1,25 @@
+package web_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVarsHandler(t *testing.T) {
+	expvar.NewInt("debug_test_counter").Set(3)
+
+	rec := httptest.NewRecorder()
+	web.VarsHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/vars", nil))
+
+	var vars map[string]json.RawMessage
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &vars))
+	require.Equal(t, json.RawMessage("3"), vars["debug_test_counter"])
+	require.Contains(t, vars, "memstats")
+	require.NotContains(t, vars, "cmdline", "the arguments may hold secrets")
+}
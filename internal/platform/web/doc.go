@@ -0,0 +1,48 @@
+package web
+
+// Route is a route added to an Application.
+type Route struct {
+	Method  string
+	Pattern string
+	// Doc is nil if the route is not documented.
+	Doc *Doc
+	// RateLimited is true if the route has rate limit policies.
+	RateLimited bool
+	// Version is the version of the API the route belongs to, empty if it is not versioned.
+	Version string
+	// Deprecated is true if the route will be removed.
+	Deprecated bool
+}
+
+// Doc describes a route in the OpenAPI document of the Application.
+type Doc struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Query       []QueryParam
+	// Request is a value of the type of the body of the requests, nil if they have none.
+	Request interface{}
+	// Status is the status of a successful response. It is 200 if zero.
+	Status int
+	// Response is a value of the type of the body of a successful response, nil if it has none. The
+	// media type of the response is negotiated before calling the handler of the route.
+	Response interface{}
+	// ContentType is the media type of a successful response that is not sent by Respond, such as
+	// an image or a stream of events.
+	ContentType string
+	// Errors are the errors the route may answer, besides the ones of its request and its rate limits.
+	Errors []ErrorDoc
+}
+
+// QueryParam is a query parameter of a route.
+type QueryParam struct {
+	Name        string
+	Description string
+	Required    bool
+}
+
+// ErrorDoc is an Error a route may answer.
+type ErrorDoc struct {
+	Status int
+	Code   string
+}
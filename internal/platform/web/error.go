@@ -7,7 +7,19 @@ import (
 )
 
 type Error struct {
-	Status  int    `json:"-"`
+	Status int `json:"-"`
+	// Code is a machine readable identifier of the error. Unless given, it is derived from the status.
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	// Fields are the errors of each field of an invalid request.
+	Fields []FieldError `json:"fields,omitempty"`
+	// Header is added to the response, such as the Retry-After of a rate limited request.
+	Header http.Header `json:"-"`
+}
+
+// FieldError describes why a field of a request is not valid.
+type FieldError struct {
+	Field   string `json:"field"`
 	Code    string `json:"code"`
 	Message string `json:"message"`
 }
@@ -26,9 +38,33 @@ func NewError(statusCode int, message string) error {
 // NewErrorf creates a new error with the given status code and the message
 // formatted according to args and format.
 func NewErrorf(status int, format string, args ...interface{}) error {
+	return NewCodedError(status, statusCode(status), fmt.Sprintf(format, args...))
+}
+
+// NewCodedError creates a new error with the given status code, machine readable code and message.
+func NewCodedError(status int, code, message string) error {
 	return &Error{
-		Code:    strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_"),
-		Message: fmt.Sprintf(format, args...),
+		Code:    code,
+		Message: message,
 		Status:  status,
 	}
 }
+
+// NewFieldError creates a 400 error for a request whose fields are not valid.
+func NewFieldError(fields ...FieldError) error {
+	messages := make([]string, len(fields))
+	for i, f := range fields {
+		messages[i] = f.Message
+	}
+
+	return &Error{
+		Code:    "invalid_request",
+		Message: strings.Join(messages, "; "),
+		Status:  http.StatusBadRequest,
+		Fields:  fields,
+	}
+}
+
+func statusCode(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
@@ -0,0 +1,144 @@
+package web
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// formCodec decodes application/x-www-form-urlencoded bodies. The keys are the JSON names of the
+// fields; nested fields are separated by dots and the elements of lists are indexed, like
+// "schedule.windows[0].start". Repeated keys are lists too.
+var formCodec = Codec{
+	ContentType: "application/x-www-form-urlencoded",
+	Decode:      decodeForm,
+}
+
+func decodeForm(r io.Reader, val interface{}) error {
+	body, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	// curl -d sends this content type by default, even when the body is JSON.
+	if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return jsonCodec.Decode(bytes.NewReader(body), val)
+	}
+
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return err
+	}
+
+	var root interface{} = make(map[string]interface{})
+	for key, vs := range values {
+		path, err := parseFormKey(key)
+		if err != nil {
+			return err
+		}
+
+		var leaf interface{} = vs[0]
+		if len(vs) > 1 {
+			list := make([]interface{}, len(vs))
+			for i, v := range vs {
+				list[i] = v
+			}
+			leaf = list
+		}
+
+		if root, err = setFormValue(root, path, leaf, key); err != nil {
+			return err
+		}
+	}
+
+	return populate(root, reflect.ValueOf(val), "")
+}
+
+// parseFormKey splits a key like "codes[0].name" into "codes", 0 and "name".
+func parseFormKey(key string) ([]interface{}, error) {
+	var path []interface{}
+	for _, part := range strings.Split(key, ".") {
+		name := part
+		var indexes []interface{}
+		for strings.HasSuffix(name, "]") {
+			open := strings.LastIndex(name, "[")
+			if open < 0 {
+				return nil, fmt.Errorf("invalid key %q", key)
+			}
+
+			i, err := strconv.Atoi(name[open+1 : len(name)-1])
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid index in key %q", key)
+			}
+
+			indexes = append([]interface{}{i}, indexes...)
+			name = name[:open]
+		}
+
+		if name == "" {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+
+		path = append(path, name)
+		path = append(path, indexes...)
+	}
+
+	return path, nil
+}
+
+// maxFormIndex limits the lists of a form, which are allocated up to their greatest index.
+const maxFormIndex = 1000
+
+// setFormValue returns node with leaf set at path, creating the objects and lists on the way.
+func setFormValue(node interface{}, path []interface{}, leaf interface{}, key string) (interface{}, error) {
+	if len(path) == 0 {
+		if node != nil {
+			return nil, fmt.Errorf("conflicting key %q", key)
+		}
+		return leaf, nil
+	}
+
+	switch step := path[0].(type) {
+	case string:
+		obj, ok := node.(map[string]interface{})
+		if node == nil {
+			obj, ok = make(map[string]interface{}), true
+		}
+		if !ok {
+			return nil, fmt.Errorf("conflicting key %q", key)
+		}
+
+		child, err := setFormValue(obj[step], path[1:], leaf, key)
+		if err != nil {
+			return nil, err
+		}
+		obj[step] = child
+		return obj, nil
+	default:
+		i := step.(int)
+		if i > maxFormIndex {
+			return nil, fmt.Errorf("index of key %q is greater than %d", key, maxFormIndex)
+		}
+
+		list, ok := node.([]interface{})
+		if !ok && node != nil {
+			return nil, fmt.Errorf("conflicting key %q", key)
+		}
+
+		for len(list) <= i {
+			list = append(list, nil)
+		}
+
+		child, err := setFormValue(list[i], path[1:], leaf, key)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = child
+		return list, nil
+	}
+}
@@ -1,6 +1,7 @@
 package web
 
 import (
+	"context"
 	"errors"
 	"log"
 	"net/http"
@@ -10,6 +11,9 @@ import (
 type Handler func(w http.ResponseWriter, r *http.Request) error
 
 func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Respond finds the media types accepted by the client in the context.
+	r = r.WithContext(context.WithValue(r.Context(), acceptKey, r.Header.Get("Accept")))
+
 	if err := h(w, r); err != nil {
 		// If the error was of the type *Error, the handler has a specific status code and error to return.
 		var webErr *Error
@@ -17,8 +21,27 @@ func (h Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 			webErr = NewErrorf(500, err.Error()).(*Error)
 		}
 
-		if err := Respond(r.Context(), w, webErr, webErr.Status); err != nil {
+		for k, v := range webErr.Header {
+			w.Header()[k] = v
+		}
+
+		if err := respondError(w, r, webErr); err != nil {
 			log.Printf("writing http response : %v", err)
 		}
 	}
 }
+
+// respondError sends the problem details of err if the client accepts them, or err in the accepted
+// media type otherwise. JSON is used when the accepted media types are not supported.
+func respondError(w http.ResponseWriter, r *http.Request, err *Error) error {
+	if AcceptsProblem(r) {
+		return respond(w, Codec{ContentType: ProblemContentType, Encode: jsonCodec.Encode}, NewProblem(r, err), err.Status)
+	}
+
+	c, negotiateErr := responseCodec(r.Header.Get("Accept"))
+	if negotiateErr != nil {
+		c = jsonCodec
+	}
+
+	return respond(w, c, err, err.Status)
+}
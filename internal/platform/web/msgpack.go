@@ -0,0 +1,72 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// msgpackCodec goes through JSON, so the names of the fields, their omitempty options and the custom
+// JSON encoding of types like time.Time are the same in MessagePack.
+var msgpackCodec = Codec{
+	ContentType: "application/msgpack",
+	Decode:      decodeMsgpack,
+	Encode:      encodeMsgpack,
+}
+
+func encodeMsgpack(w io.Writer, val interface{}) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+
+	enc := msgpack.NewEncoder(w)
+	enc.UseCompactInts(true)
+	return enc.Encode(fromJSONNumbers(v))
+}
+
+// fromJSONNumbers replaces the json.Number in v by integers, or floats if they have decimals.
+func fromJSONNumbers(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if i, err := t.Int64(); err == nil {
+			return i
+		}
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		for k, e := range t {
+			t[k] = fromJSONNumbers(e)
+		}
+	case []interface{}:
+		for i, e := range t {
+			t[i] = fromJSONNumbers(e)
+		}
+	}
+
+	return v
+}
+
+func decodeMsgpack(r io.Reader, val interface{}) error {
+	v, err := msgpack.NewDecoder(r).DecodeInterface()
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	return jsonCodec.Decode(bytes.NewReader(data), val)
+}
@@ -0,0 +1,420 @@
+package web
+
+import (
+	_ "embed"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Info describes the API in its OpenAPI document.
+type Info struct {
+	Title       string
+	Version     string
+	Description string
+}
+
+// OpenAPI returns the OpenAPI 3 document of the documented routes of the application. The request and
+// response bodies are described in every media type supported by Decode and Respond.
+func (app *Application) OpenAPI(info Info) map[string]interface{} {
+	g := openAPIGenerator{schemas: make(map[string]interface{}), names: make(map[reflect.Type]string)}
+	g.schemaName(reflect.TypeOf(Error{}))
+	g.schemaName(reflect.TypeOf(Problem{}))
+
+	paths := make(map[string]interface{})
+	for _, route := range app.routes {
+		if route.Doc == nil {
+			continue
+		}
+
+		path := openAPIPath(route.Pattern)
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[path] = item
+		}
+		item[strings.ToLower(route.Method)] = g.operation(route)
+	}
+
+	apiInfo := map[string]interface{}{"title": info.Title, "version": info.Version}
+	if info.Description != "" {
+		apiInfo["description"] = info.Description
+	}
+
+	return map[string]interface{}{
+		"openapi":    "3.0.3",
+		"info":       apiInfo,
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": g.schemas},
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document of the application as JSON.
+func (app *Application) OpenAPIHandler(info Info) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := json.Marshal(app.OpenAPI(info))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+}
+
+// pathParam matches the parameters of a route pattern, with their optional regular expression.
+var pathParam = regexp.MustCompile(`{([^}:]+)(:[^}]+)?}`)
+
+// openAPIPath removes the regular expressions of the parameters of a route pattern.
+func openAPIPath(pattern string) string {
+	return pathParam.ReplaceAllString(pattern, "{$1}")
+}
+
+type openAPIGenerator struct {
+	// schemas are the components of the document, by name.
+	schemas map[string]interface{}
+	// names are the names of the schemas of the named struct types.
+	names map[reflect.Type]string
+}
+
+func (g *openAPIGenerator) operation(route Route) map[string]interface{} {
+	doc := route.Doc
+	op := map[string]interface{}{
+		"operationId": operationID(route.Method, route.Pattern),
+		"summary":     doc.Summary,
+	}
+	if doc.Description != "" {
+		op["description"] = doc.Description
+	}
+	if len(doc.Tags) > 0 {
+		op["tags"] = doc.Tags
+	}
+	if route.Deprecated {
+		op["deprecated"] = true
+	}
+
+	var params []interface{}
+	for _, m := range pathParam.FindAllStringSubmatch(route.Pattern, -1) {
+		params = append(params, map[string]interface{}{
+			"name": m[1], "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"},
+		})
+	}
+	for _, p := range doc.Query {
+		param := map[string]interface{}{
+			"name": p.Name, "in": "query", "required": p.Required, "schema": map[string]interface{}{"type": "string"},
+		}
+		if p.Description != "" {
+			param["description"] = p.Description
+		}
+		params = append(params, param)
+	}
+	if len(params) > 0 {
+		op["parameters"] = params
+	}
+
+	// The errors of doc may be shared by several routes, so they are copied before adding the others.
+	errs := append([]ErrorDoc(nil), doc.Errors...)
+	if doc.Request != nil {
+		schema := g.schema(reflect.TypeOf(doc.Request))
+		op["requestBody"] = map[string]interface{}{"required": true, "content": content(mediaTypes(true), schema)}
+		errs = append(errs,
+			ErrorDoc{Status: http.StatusBadRequest, Code: "invalid_request"},
+			ErrorDoc{Status: http.StatusUnsupportedMediaType, Code: statusCode(http.StatusUnsupportedMediaType)})
+	}
+
+	status := doc.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := map[string]interface{}{"description": http.StatusText(status)}
+	switch {
+	case doc.ContentType != "":
+		success["content"] = map[string]interface{}{
+			doc.ContentType: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+		}
+	case doc.Response != nil:
+		success["content"] = content(mediaTypes(false), g.schema(reflect.TypeOf(doc.Response)))
+		errs = append(errs, ErrorDoc{Status: http.StatusNotAcceptable, Code: statusCode(http.StatusNotAcceptable)})
+	}
+
+	if route.RateLimited {
+		errs = append(errs, ErrorDoc{Status: http.StatusTooManyRequests, Code: statusCode(http.StatusTooManyRequests)})
+	}
+
+	responses := map[string]interface{}{strconv.Itoa(status): success}
+	for status, codes := range errorCodes(errs) {
+		errContent := content(mediaTypes(false), map[string]interface{}{"$ref": "#/components/schemas/Error"})
+		errContent[ProblemContentType] = map[string]interface{}{
+			"schema": map[string]interface{}{"$ref": "#/components/schemas/Problem"},
+		}
+		responses[strconv.Itoa(status)] = map[string]interface{}{
+			"description": fmt.Sprintf("%s: %s", http.StatusText(status), strings.Join(codes, ", ")),
+			"content":     errContent,
+		}
+	}
+	op["responses"] = responses
+
+	return op
+}
+
+// operationID derives the identifier of an operation from its method and pattern, such as
+// getLinkIdMetrics for GET /link/{id}/metrics.
+func operationID(method, pattern string) string {
+	id := strings.ToLower(method)
+	for _, word := range strings.FieldsFunc(openAPIPath(pattern), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		id += strings.ToUpper(word[:1]) + word[1:]
+	}
+
+	return id
+}
+
+// errorCodes groups the codes of the errors by status, without duplicates.
+func errorCodes(errs []ErrorDoc) map[int][]string {
+	codes := make(map[int][]string)
+	seen := make(map[ErrorDoc]bool)
+	for _, e := range errs {
+		if !seen[e] {
+			seen[e] = true
+			codes[e.Status] = append(codes[e.Status], e.Code)
+		}
+	}
+
+	return codes
+}
+
+// mediaTypes returns the media types supported by Decode, or by Respond, with JSON first.
+func mediaTypes(decode bool) []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+
+	var types []string
+	for mediaType, c := range codecs {
+		if (decode && c.Decode != nil) || (!decode && c.Encode != nil) {
+			types = append(types, mediaType)
+		}
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		if (types[i] == jsonCodec.ContentType) != (types[j] == jsonCodec.ContentType) {
+			return types[i] == jsonCodec.ContentType
+		}
+		return types[i] < types[j]
+	})
+
+	return types
+}
+
+func content(mediaTypes []string, schema interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(mediaTypes))
+	for _, mediaType := range mediaTypes {
+		c[mediaType] = map[string]interface{}{"schema": schema}
+	}
+
+	return c
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	durationType      = reflect.TypeOf(time.Duration(0))
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// schema returns the schema of the JSON representation of t. Named structs are added to the components
+// and referenced.
+func (g *openAPIGenerator) schema(t reflect.Type) map[string]interface{} {
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t == durationType:
+		return map[string]interface{}{"type": "integer", "format": "int64", "description": "nanoseconds"}
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	case t.Kind() != reflect.Ptr && t.Implements(textMarshalerType),
+		t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(textMarshalerType):
+		return map[string]interface{}{"type": "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		s := g.schema(t.Elem())
+		if _, ref := s["$ref"]; ref {
+			return s
+		}
+		s["nullable"] = true
+		return s
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Int64, reflect.Uint64:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + g.schemaName(t)}
+	}
+
+	return map[string]interface{}{}
+}
+
+// schemaName returns the name of the component of a named struct, adding it if needed. The name is
+// prefixed by the package if another type has the same one.
+func (g *openAPIGenerator) schemaName(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+
+	name := strings.ToUpper(t.Name()[:1]) + t.Name()[1:]
+	if _, taken := g.schemas[name]; taken {
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.ToUpper(pkg[:1]) + pkg[1:] + name
+	}
+
+	g.names[t] = name
+	// The placeholder stops the recursion of types that reference themselves.
+	g.schemas[name] = nil
+	g.schemas[name] = g.structSchema(t)
+	return name
+}
+
+func (g *openAPIGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	var required []string
+	g.addFields(t, properties, &required)
+
+	s := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+
+	return s
+}
+
+// addFields adds the properties of the exported fields of t, including the ones of its embedded structs.
+func (g *openAPIGenerator) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		if f.Anonymous && strings.Split(tag, ",")[0] == "" && indirectType(f.Type).Kind() == reflect.Struct {
+			g.addFields(indirectType(f.Type), properties, required)
+			continue
+		}
+
+		if f.PkgPath != "" {
+			continue
+		}
+
+		name := fieldName(f)
+		s := g.schema(f.Type)
+		if strings.Contains(tag, ",string") {
+			s = map[string]interface{}{"type": "string"}
+		}
+		if isRequired := applyRules(s, f.Tag.Get("validate")); isRequired {
+			*required = append(*required, name)
+		}
+		properties[name] = s
+	}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t
+}
+
+// applyRules adds the constraints of the rules of a validate tag to the schema of the field, and
+// reports whether the field is required. The rules after dive constrain the items of an array.
+func applyRules(s map[string]interface{}, tag string) bool {
+	var isRequired, dive bool
+	target := s
+	for _, r := range splitRules(tag) {
+		kv := strings.SplitN(r, "=", 2)
+		name, arg := kv[0], ""
+		if len(kv) == 2 {
+			arg = kv[1]
+		}
+
+		if _, ref := target["$ref"]; ref {
+			continue
+		}
+
+		switch name {
+		case "dive":
+			if items, ok := target["items"].(map[string]interface{}); ok {
+				target, dive = items, true
+			}
+		case "required":
+			if !dive {
+				isRequired = true
+			}
+			if target["type"] == "array" {
+				target["minItems"] = 1
+			}
+		case "url":
+			target["format"] = "uri"
+		case "min", "max":
+			n, _ := strconv.ParseFloat(arg, 64)
+			key := map[string]string{"min": "minimum", "max": "maximum"}[name]
+			switch target["type"] {
+			case "string":
+				key = map[string]string{"min": "minLength", "max": "maxLength"}[name]
+			case "array":
+				key = map[string]string{"min": "minItems", "max": "maxItems"}[name]
+			}
+			target[key] = n
+		case "oneof":
+			target["enum"] = strings.Fields(arg)
+		case "regex":
+			target["pattern"] = arg
+		}
+	}
+
+	return isRequired
+}
+
+//go:embed docs.html
+var docsPage string
+
+var docsTemplate = template.Must(template.New("docs").Parse(docsPage))
+
+// DocsHandler serves a page that renders the OpenAPI document served at specURL. The page is
+// self-contained, so it works without access to the internet.
+func DocsHandler(specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if err := docsTemplate.Execute(w, specURL); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package web_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	Name string `json:"name" validate:"required,max=10"`
+}
+
+type order struct {
+	Customer string     `json:"customer" validate:"required,min=2"`
+	Website  string     `json:"website" validate:"url"`
+	Size     string     `json:"size" validate:"oneof=s m l"`
+	Code     string     `json:"code" validate:"regex=^[a-z]{2,4}$"`
+	Quantity int        `json:"quantity" validate:"min=1,max=99"`
+	Items    []item     `json:"items" validate:"required,max=5"`
+	Tags     []string   `json:"tags" validate:"dive,max=20"`
+	Due      *time.Time `json:"due,omitempty"`
+	internal string
+}
+
+// document returns the OpenAPI document of app as it is marshalled.
+func document(t *testing.T, app *web.Application) map[string]interface{} {
+	data, err := json.Marshal(app.OpenAPI(web.Info{Title: "Shop", Version: "1.0"}))
+	require.NoError(t, err)
+
+	var spec map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &spec))
+	return spec
+}
+
+func TestApplication_OpenAPI(t *testing.T) {
+	// Given
+	app := web.New()
+	app.Method(http.MethodPost, "/orders/{id:[0-9]+}", http.NotFoundHandler(), web.Doc{
+		Summary:  "Update an order",
+		Tags:     []string{"orders"},
+		Query:    []web.QueryParam{{Name: "dry_run", Description: "Validate only.", Required: true}},
+		Request:  order{},
+		Status:   http.StatusAccepted,
+		Response: []order{},
+		Errors:   []web.ErrorDoc{{Status: http.StatusNotFound, Code: "order_not_found"}},
+	})
+	app.Method(http.MethodGet, "/undocumented", http.NotFoundHandler())
+
+	// When
+	spec := document(t, app)
+
+	// Then
+	require.Equal(t, "3.0.3", spec["openapi"])
+	require.Equal(t, map[string]interface{}{"title": "Shop", "version": "1.0"}, spec["info"])
+
+	paths := spec["paths"].(map[string]interface{})
+	require.Len(t, paths, 1)
+	op := paths["/orders/{id}"].(map[string]interface{})["post"].(map[string]interface{})
+	require.Equal(t, "postOrdersId", op["operationId"])
+	require.Equal(t, "Update an order", op["summary"])
+	require.Equal(t, []interface{}{
+		map[string]interface{}{"name": "id", "in": "path", "required": true, "schema": map[string]interface{}{"type": "string"}},
+		map[string]interface{}{"name": "dry_run", "in": "query", "required": true, "description": "Validate only.", "schema": map[string]interface{}{"type": "string"}},
+	}, op["parameters"])
+
+	content := op["requestBody"].(map[string]interface{})["content"].(map[string]interface{})
+	for _, mediaType := range []string{"application/json", "application/x-www-form-urlencoded", "application/xml", "application/msgpack"} {
+		require.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/Order"}, content[mediaType].(map[string]interface{})["schema"], mediaType)
+	}
+
+	responses := op["responses"].(map[string]interface{})
+	success := responses["202"].(map[string]interface{})["content"].(map[string]interface{})
+	require.NotContains(t, success, "application/x-www-form-urlencoded")
+	require.Equal(t, map[string]interface{}{
+		"type": "array", "items": map[string]interface{}{"$ref": "#/components/schemas/Order"},
+	}, success["application/json"].(map[string]interface{})["schema"])
+	require.Equal(t, "Bad Request: invalid_request", responses["400"].(map[string]interface{})["description"])
+	require.Equal(t, "Not Found: order_not_found", responses["404"].(map[string]interface{})["description"])
+	require.Contains(t, responses, "406")
+	require.Contains(t, responses, "415")
+	require.NotContains(t, responses, "429")
+	problem := responses["404"].(map[string]interface{})["content"].(map[string]interface{})[web.ProblemContentType]
+	require.Equal(t, map[string]interface{}{"$ref": "#/components/schemas/Problem"}, problem.(map[string]interface{})["schema"])
+
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	require.Contains(t, schemas, "Error")
+	require.Contains(t, schemas, "Problem")
+	require.Equal(t, map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"customer", "items"},
+		"properties": map[string]interface{}{
+			"customer": map[string]interface{}{"type": "string", "minLength": float64(2)},
+			"website":  map[string]interface{}{"type": "string", "format": "uri"},
+			"size":     map[string]interface{}{"type": "string", "enum": []interface{}{"s", "m", "l"}},
+			"code":     map[string]interface{}{"type": "string", "pattern": "^[a-z]{2,4}$"},
+			"quantity": map[string]interface{}{"type": "integer", "minimum": float64(1), "maximum": float64(99)},
+			"items": map[string]interface{}{
+				"type": "array", "minItems": float64(1), "maxItems": float64(5),
+				"items": map[string]interface{}{"$ref": "#/components/schemas/Item"},
+			},
+			"tags": map[string]interface{}{
+				"type": "array", "items": map[string]interface{}{"type": "string", "maxLength": float64(20)},
+			},
+			"due": map[string]interface{}{"type": "string", "format": "date-time", "nullable": true},
+		},
+	}, schemas["Order"])
+	require.Equal(t, map[string]interface{}{
+		"type":       "object",
+		"required":   []interface{}{"name"},
+		"properties": map[string]interface{}{"name": map[string]interface{}{"type": "string", "maxLength": float64(10)}},
+	}, schemas["Item"])
+}
+
+func TestApplication_OpenAPI_SharedErrors(t *testing.T) {
+	// Given
+	errs := make([]web.ErrorDoc, 1, 4)
+	errs[0] = web.ErrorDoc{Status: http.StatusNotFound, Code: "order_not_found"}
+
+	app := web.New()
+	app.Method(http.MethodPost, "/orders", http.NotFoundHandler(), web.Doc{Summary: "Create an order", Request: order{}, Errors: errs})
+	app.Method(http.MethodGet, "/orders", http.NotFoundHandler(), web.Doc{Summary: "List the orders", Response: []order{}, Errors: errs})
+
+	// When
+	document(t, app)
+
+	// Then
+	require.Equal(t, make([]web.ErrorDoc, 3), errs[1:4], "the errors added to a route must not be written to the shared ones")
+}
+
+func TestApplication_OpenAPI_RateLimited(t *testing.T) {
+	// Given
+	limiter := web.NewRateLimiter(web.NewMemoryRateStore())
+	limiter.Limit(http.MethodGet, "/limited", web.Policy{Name: "per client", Key: web.ByIP(), Limit: web.Limit{Requests: 1, Per: time.Minute}})
+
+	app := web.New()
+	app.RateLimit(limiter)
+	app.Method(http.MethodGet, "/limited", http.NotFoundHandler(), web.Doc{Summary: "Limited", ContentType: "text/plain"})
+	app.Method(http.MethodGet, "/free", http.NotFoundHandler(), web.Doc{Summary: "Free", ContentType: "text/plain"})
+
+	// When
+	spec := document(t, app)
+
+	// Then
+	paths := spec["paths"].(map[string]interface{})
+	limited := paths["/limited"].(map[string]interface{})["get"].(map[string]interface{})["responses"].(map[string]interface{})
+	free := paths["/free"].(map[string]interface{})["get"].(map[string]interface{})["responses"].(map[string]interface{})
+	require.Equal(t, "Too Many Requests: too_many_requests", limited["429"].(map[string]interface{})["description"])
+	require.Equal(t, map[string]interface{}{
+		"text/plain": map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+	}, limited["200"].(map[string]interface{})["content"])
+	require.NotContains(t, free, "429")
+}
+
+func TestApplication_Routes(t *testing.T) {
+	// Given
+	app := web.New()
+	doc := web.Doc{Summary: "First"}
+	app.Method(http.MethodGet, "/first", http.NotFoundHandler(), doc)
+	app.Method(http.MethodPost, "/second", http.NotFoundHandler())
+
+	// When
+	routes := app.Routes()
+
+	// Then
+	require.Equal(t, []web.Route{
+		{Method: http.MethodGet, Pattern: "/first", Doc: &doc},
+		{Method: http.MethodPost, Pattern: "/second"},
+	}, routes)
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ProblemContentType is the media type of the problem details defined by RFC 7807.
+const ProblemContentType = "application/problem+json"
+
+// Problem is the representation of an Error defined by RFC 7807. It is sent instead of the Error
+// when the client accepts ProblemContentType.
+type Problem struct {
+	// Type identifies the kind of problem. It is a relative URI made of the code of the error.
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	// Code is the code of the Error, the same one of its Type.
+	Code   string       `json:"code"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem returns the problem details of an error that happened while serving r. The instance is
+// the path of r without its query, which may hold passwords and signatures.
+func NewProblem(r *http.Request, e *Error) Problem {
+	return Problem{
+		Type:     "/problems/" + e.Code,
+		Title:    http.StatusText(e.Status),
+		Status:   e.Status,
+		Detail:   e.Message,
+		Instance: r.URL.EscapedPath(),
+		Code:     e.Code,
+		Errors:   e.Fields,
+	}
+}
+
+// AcceptsProblem reports whether the Accept header of r includes ProblemContentType.
+func AcceptsProblem(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for _, accepted := range strings.Split(value, ",") {
+			mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+			if err != nil || mediaType != ProblemContentType {
+				continue
+			}
+
+			if q, err := strconv.ParseFloat(params["q"], 64); err == nil && q == 0 {
+				continue
+			}
+
+			return true
+		}
+	}
+
+	return false
+}
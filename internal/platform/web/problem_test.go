@@ -0,0 +1,64 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAcceptsProblem(t *testing.T) {
+	tt := []struct {
+		accept string
+		want   bool
+	}{
+		{accept: "", want: false},
+		{accept: "*/*", want: false},
+		{accept: "application/json", want: false},
+		{accept: "application/problem+json", want: true},
+		{accept: "application/json, application/problem+json; q=0.5", want: true},
+		{accept: "application/problem+json;q=0", want: false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.accept, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			r.Header.Set("Accept", tc.accept)
+			require.Equal(t, tc.want, web.AcceptsProblem(r))
+		})
+	}
+}
+
+func TestHandler_FieldErrorProblem(t *testing.T) {
+	// Given
+	h := web.Handler(func(w http.ResponseWriter, r *http.Request) error {
+		return web.NewFieldError(
+			web.FieldError{Field: "link", Code: "required", Message: "link is missing"},
+			web.FieldError{Field: "owner", Code: "max", Message: "owner is too long"},
+		)
+	})
+	r := httptest.NewRequest(http.MethodPost, "/link", nil)
+	r.Header.Set("Accept", web.ProblemContentType)
+	w := httptest.NewRecorder()
+
+	// When
+	h.ServeHTTP(w, r)
+
+	// Then
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	require.Equal(t, web.ProblemContentType, w.Header().Get("Content-Type"))
+	require.JSONEq(t, `{
+		"type": "/problems/invalid_request",
+		"title": "Bad Request",
+		"status": 400,
+		"detail": "link is missing; owner is too long",
+		"instance": "/link",
+		"code": "invalid_request",
+		"errors": [
+			{"field": "link", "code": "required", "message": "link is missing"},
+			{"field": "owner", "code": "max", "message": "owner is too long"}
+		]
+	}`, w.Body.String())
+}
@@ -0,0 +1,207 @@
+package web
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Limit is a token bucket that allows Requests every Per, with bursts of up to Burst requests.
+type Limit struct {
+	Requests int
+	Per      time.Duration
+	// Burst is Requests if zero.
+	Burst int
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+
+	return l.Requests
+}
+
+// interval returns the time it takes to refill one token.
+func (l Limit) interval() time.Duration {
+	return l.Per / time.Duration(l.Requests)
+}
+
+// RateDecision is the result of taking a token from a bucket.
+type RateDecision struct {
+	Allowed   bool
+	Remaining int
+	// RetryAfter is the time until the next token is available when the request is not allowed.
+	RetryAfter time.Duration
+}
+
+// RateStore keeps the token buckets. Implementations backed by a shared store let several instances
+// of the application enforce the same limits.
+type RateStore interface {
+	Take(ctx context.Context, key string, limit Limit) (RateDecision, error)
+}
+
+// KeyFunc returns the key that identifies who is limited by a policy. The policy is skipped if the key is empty.
+type KeyFunc func(r *http.Request) string
+
+// ByIP limits each client IP, as returned by ClientIP.
+func ByIP() KeyFunc {
+	return ClientIP
+}
+
+// ByHeader limits each value of the header, such as an API key.
+func ByHeader(name string) KeyFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+// ByParam limits each value of the route parameter, such as the ID of a link.
+func ByParam(key string) KeyFunc {
+	return func(r *http.Request) string {
+		return Param(r, key)
+	}
+}
+
+// Policy limits the requests that share the same key.
+type Policy struct {
+	// Name identifies the policy in the keys of the store and in the errors.
+	Name  string
+	Key   KeyFunc
+	Limit Limit
+}
+
+// RateLimiter applies policies to the routes of an Application.
+type RateLimiter struct {
+	store  RateStore
+	routes map[string][]Policy
+}
+
+// NewRateLimiter creates a RateLimiter that keeps its buckets in store.
+func NewRateLimiter(store RateStore) *RateLimiter {
+	return &RateLimiter{
+		store:  store,
+		routes: make(map[string][]Policy),
+	}
+}
+
+// Limit applies the policies to the route `pattern` that matches `method`. A request is rejected if any
+// of them is exceeded.
+func (rl *RateLimiter) Limit(method, pattern string, policies ...Policy) {
+	route := method + " " + pattern
+	rl.routes[route] = append(rl.routes[route], policies...)
+}
+
+// Wrap returns a handler that applies the policies of the route before calling h.
+func (rl *RateLimiter) Wrap(method, pattern string, h http.Handler) http.Handler {
+	route := method + " " + pattern
+	return Handler(func(w http.ResponseWriter, r *http.Request) error {
+		for _, p := range rl.routes[route] {
+			key := p.Key(r)
+			if key == "" {
+				continue
+			}
+
+			d, err := rl.store.Take(r.Context(), route+"|"+p.Name+"|"+key, p.Limit)
+			if err != nil {
+				return err
+			}
+
+			if !d.Allowed {
+				return newRateLimitError(p.Name, d.RetryAfter)
+			}
+		}
+
+		h.ServeHTTP(w, r)
+		return nil
+	})
+}
+
+func newRateLimitError(policy string, retryAfter time.Duration) error {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	err := NewErrorf(http.StatusTooManyRequests, "rate limit %q exceeded, retry in %d seconds", policy, seconds).(*Error)
+	err.Header = http.Header{"Retry-After": []string{strconv.Itoa(seconds)}}
+	return err
+}
+
+// MemoryRateStore is a RateStore that keeps the buckets in memory. It is safe for concurrent use.
+type MemoryRateStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket is full again, after which it can be forgotten.
+	full time.Time
+}
+
+// sweepInterval is how often the buckets that are full again are removed.
+const sweepInterval = time.Minute
+
+// NewMemoryRateStore creates an empty MemoryRateStore.
+func NewMemoryRateStore() *MemoryRateStore {
+	return &MemoryRateStore{
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Take implements RateStore.
+func (s *MemoryRateStore) Take(_ context.Context, key string, limit Limit) (RateDecision, error) {
+	if limit.Requests <= 0 || limit.Per <= 0 {
+		return RateDecision{}, fmt.Errorf("invalid limit of %d requests per %s", limit.Requests, limit.Per)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	burst := float64(limit.burst())
+	interval := limit.interval()
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+float64(now.Sub(b.last))/float64(interval))
+	b.last = now
+
+	if b.tokens < 1 {
+		return RateDecision{RetryAfter: time.Duration((1 - b.tokens) * float64(interval))}, nil
+	}
+
+	b.tokens--
+	b.full = now.Add(time.Duration((burst - b.tokens) * float64(interval)))
+	return RateDecision{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+// Len returns the number of buckets in memory.
+func (s *MemoryRateStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+func (s *MemoryRateStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+
+	s.lastSweep = now
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
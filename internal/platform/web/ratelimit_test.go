@@ -0,0 +1,135 @@
+package web_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryRateStore_Take(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	store := web.NewMemoryRateStore()
+	limit := web.Limit{Requests: 2, Per: 100 * time.Millisecond}
+
+	// When
+	first, err := store.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	second, err := store.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	third, err := store.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	other, err := store.Take(ctx, "other", limit)
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, web.RateDecision{Allowed: true, Remaining: 1}, first)
+	require.Equal(t, web.RateDecision{Allowed: true, Remaining: 0}, second)
+	require.False(t, third.Allowed)
+	require.True(t, third.RetryAfter > 0 && third.RetryAfter <= 50*time.Millisecond)
+	require.True(t, other.Allowed)
+
+	// The bucket is refilled after RetryAfter.
+	time.Sleep(third.RetryAfter)
+	fourth, err := store.Take(ctx, "key", limit)
+	require.NoError(t, err)
+	require.True(t, fourth.Allowed)
+}
+
+func TestMemoryRateStore_Take_InvalidLimit(t *testing.T) {
+	_, err := web.NewMemoryRateStore().Take(context.Background(), "key", web.Limit{Per: time.Second})
+	require.Error(t, err)
+}
+
+func TestRateLimiter(t *testing.T) {
+	_, proxies, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	tt := []struct {
+		name    string
+		policy  web.Policy
+		request func(i int) *http.Request
+		// wantLimited is the status of every request, true if it is rejected.
+		wantLimited []bool
+	}{
+		{
+			name:   "by ip",
+			policy: web.Policy{Name: "ip", Key: web.ByIP(), Limit: web.Limit{Requests: 1, Per: time.Hour}},
+			request: func(i int) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/link/1", nil)
+				r.RemoteAddr = "192.0.2.1:1234"
+				// The header is ignored because the peer is not a trusted proxy.
+				r.Header.Set("X-Forwarded-For", net.IPv4(198, 51, 100, byte(i)).String())
+				return r
+			},
+			wantLimited: []bool{false, true, true},
+		},
+		{
+			name:   "by ip behind a trusted proxy",
+			policy: web.Policy{Name: "ip", Key: web.ByIP(), Limit: web.Limit{Requests: 1, Per: time.Hour}},
+			request: func(i int) *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/link/1", nil)
+				r.RemoteAddr = "10.0.0.1:1234"
+				r.Header.Set("X-Forwarded-For", "203.0.113.7, "+net.IPv4(198, 51, 100, byte(i/2)).String()+", 10.0.0.2")
+				return r
+			},
+			wantLimited: []bool{false, true, false, true},
+		},
+		{
+			name:   "by link",
+			policy: web.Policy{Name: "link", Key: web.ByParam("id"), Limit: web.Limit{Requests: 1, Per: time.Hour, Burst: 2}},
+			request: func(i int) *http.Request {
+				if i < 3 {
+					return httptest.NewRequest(http.MethodGet, "/link/1", nil)
+				}
+				return httptest.NewRequest(http.MethodGet, "/link/2", nil)
+			},
+			wantLimited: []bool{false, false, true, false},
+		},
+		{
+			name:   "without api key",
+			policy: web.Policy{Name: "api key", Key: web.ByHeader("X-API-Key"), Limit: web.Limit{Requests: 1, Per: time.Hour}},
+			request: func(i int) *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/link/1", nil)
+			},
+			wantLimited: []bool{false, false, false},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			limiter := web.NewRateLimiter(web.NewMemoryRateStore())
+			limiter.Limit(http.MethodGet, "/link/{id}", tc.policy)
+
+			app := web.New()
+			app.Use(web.RealIP(proxies))
+			app.RateLimit(limiter)
+			app.Method(http.MethodGet, "/link/{id}", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			}))
+
+			for i, limited := range tc.wantLimited {
+				// When
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, tc.request(i))
+
+				// Then
+				if !limited {
+					require.Equal(t, http.StatusNoContent, w.Code, "request %d", i)
+					continue
+				}
+
+				require.Equal(t, http.StatusTooManyRequests, w.Code, "request %d", i)
+				require.Equal(t, "3600", w.Header().Get("Retry-After"))
+				require.JSONEq(t, `{"code":"too_many_requests","message":"rate limit \"`+tc.policy.Name+`\" exceeded, retry in 3600 seconds"}`, w.Body.String())
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package web
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
@@ -12,16 +11,31 @@ func Param(r *http.Request, key string) string {
 	return chi.URLParam(r, key)
 }
 
-// Decode reads the body of an HTTP request looking for a JSON document. The
-// body is decoded into the provided value.
+// ClientIP returns the IP of the client that sent the request, as resolved by the RealIP middleware.
+// It is the IP of the peer if the middleware is not used.
+func ClientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey).(string); ok {
+		return ip
+	}
+
+	return peerIP(r)
+}
+
+// Decode reads the body of an HTTP request in the media type of its Content-Type, JSON if it has
+// none. The body is decoded into the provided value.
 //
-// If the provided value is a struct then it is checked for validation tags.
+// If the provided value is a struct then it is checked for validation tags, see Validate.
+// The returned error is always an *Error, with status 415 if the media type is not supported
+// and 400 otherwise.
 func Decode(r *http.Request, val interface{}) error {
-	decoder := json.NewDecoder(r.Body)
-	decoder.DisallowUnknownFields()
-	if err := decoder.Decode(val); err != nil {
+	c, err := requestCodec(r)
+	if err != nil {
 		return err
 	}
 
-	return nil
+	if err := c.Decode(r.Body, val); err != nil {
+		return NewError(http.StatusBadRequest, err.Error())
+	}
+
+	return Validate(val)
 }
@@ -2,32 +2,65 @@ package web
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 )
 
-// Respond converts a Go value to JSON and sends it to the client.
-func Respond(_ context.Context, w http.ResponseWriter, data interface{}, statusCode int) error {
+// Negotiate answers 406 to the requests that accept none of the media types Respond supports, before
+// calling next, so that a handler never acts on a request whose response could not be sent.
+func Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := responseCodec(r.Header.Get("Accept")); err != nil {
+			var webErr *Error
+			errors.As(err, &webErr)
+
+			w.Header().Add("Vary", "Accept")
+			if err := respondError(w, r, webErr); err != nil {
+				log.Printf("writing http response : %v", err)
+			}
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Respond converts a Go value to the media type accepted by the client and sends it. The Accept
+// header is read from ctx, where Handler stores it; it is JSON if there is none. It returns a 406
+// Error if no accepted media type is supported, which routes wrapped by Negotiate have already rejected.
+func Respond(ctx context.Context, w http.ResponseWriter, data interface{}, statusCode int) error {
 	// If there is nothing to marshal then set status code and return.
 	if statusCode == http.StatusNoContent {
 		w.WriteHeader(statusCode)
 		return nil
 	}
 
-	// Convert the response value to JSON.
-	jsonData, err := json.Marshal(data)
+	accept, _ := ctx.Value(acceptKey).(string)
+	c, err := responseCodec(accept)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Add("Vary", "Accept")
+	return respond(w, c, data, statusCode)
+}
+
+func respond(w http.ResponseWriter, c Codec, data interface{}, statusCode int) error {
+	// Convert the response value to the media type.
+	body, err := encode(c, data)
 	if err != nil {
 		return err
 	}
 
 	// Set the content type and headers once we know marshaling has succeeded.
-	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Type", c.ContentType)
 
 	// Write the status code to the response.
 	w.WriteHeader(statusCode)
 
 	// Send the result back to the client.
-	if _, err := w.Write(jsonData); err != nil {
+	if _, err := w.Write(body); err != nil {
 		return err
 	}
 
@@ -0,0 +1,169 @@
+package web
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CSRFField is the name of the form field that carries the CSRF token of a session.
+const CSRFField = "csrf_token"
+
+// Session is the session of a browser, kept in a signed cookie.
+type Session struct {
+	// ID identifies the session. The CSRF token of its forms is derived from it.
+	ID string `json:"id"`
+	// User is who logged in, empty until someone does.
+	User    string    `json:"user,omitempty"`
+	Expires time.Time `json:"expires"`
+}
+
+// SessionConfig configures Sessions.
+type SessionConfig struct {
+	// Name is the name of the cookie.
+	Name string
+	// Path limits the cookie to the pages under it.
+	Path string
+	// TTL is how long a session lasts after it starts.
+	TTL time.Duration
+	// Secure only sends the cookie over HTTPS.
+	Secure bool
+}
+
+// DefaultSessionConfig is the SessionConfig of the sessions of the whole site for 12 hours.
+var DefaultSessionConfig = SessionConfig{
+	Name: "session",
+	Path: "/",
+	TTL:  12 * time.Hour,
+}
+
+// Sessions keeps the sessions of the browsers in cookies signed with a key, and protects their forms from
+// cross-site request forgery with tokens derived from the sessions. Nothing is stored in the server, so
+// a session cannot be revoked before it expires, but by changing the key, which ends every session.
+type Sessions struct {
+	key    []byte
+	config SessionConfig
+	now    func() time.Time
+}
+
+// NewSessions creates Sessions whose cookies are signed with key, which must have at least 32 bytes.
+func NewSessions(key []byte, config SessionConfig) (*Sessions, error) {
+	if len(key) < 32 {
+		return nil, errors.New("the key of the sessions must have at least 32 bytes")
+	}
+
+	return &Sessions{key: key, config: config, now: time.Now}, nil
+}
+
+// Get returns the session of the request. A new session without user is started if the request has none,
+// or if it expired.
+func (s *Sessions) Get(w http.ResponseWriter, r *http.Request) (Session, error) {
+	if c, err := r.Cookie(s.config.Name); err == nil {
+		if sess, ok := s.decode(c.Value); ok && s.now().Before(sess.Expires) {
+			return sess, nil
+		}
+	}
+
+	return s.Start(w, "")
+}
+
+// Start replaces the session of the browser with a new one of user. The ID changes, so that a session
+// known before logging in cannot be used afterwards.
+func (s *Sessions) Start(w http.ResponseWriter, user string) (Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return Session{}, err
+	}
+
+	sess := Session{
+		ID:      base64.RawURLEncoding.EncodeToString(id),
+		User:    user,
+		Expires: s.now().Add(s.config.TTL).UTC().Truncate(time.Second),
+	}
+
+	value, err := s.encode(sess)
+	if err != nil {
+		return Session{}, err
+	}
+
+	http.SetCookie(w, s.cookie(value, sess.Expires))
+	return sess, nil
+}
+
+// End removes the session of the browser.
+func (s *Sessions) End(w http.ResponseWriter) {
+	c := s.cookie("", time.Unix(0, 0))
+	c.MaxAge = -1
+	http.SetCookie(w, c)
+}
+
+// CSRFToken returns the token that the forms of sess must send in the CSRFField.
+func (s *Sessions) CSRFToken(sess Session) string {
+	return base64.RawURLEncoding.EncodeToString(s.sign("csrf:" + sess.ID))
+}
+
+// VerifyCSRF reports whether the form sent in r carries the CSRF token of sess.
+func (s *Sessions) VerifyCSRF(r *http.Request, sess Session) bool {
+	token := r.PostFormValue(CSRFField)
+	return token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.CSRFToken(sess))) == 1
+}
+
+func (s *Sessions) cookie(value string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     s.config.Name,
+		Value:    value,
+		Path:     s.config.Path,
+		Expires:  expires,
+		Secure:   s.config.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// encode returns the session in base64, followed by a dot and its signature.
+func (s *Sessions) encode(sess Session) (string, error) {
+	data, err := json.Marshal(sess)
+	if err != nil {
+		return "", err
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(s.sign(payload)), nil
+}
+
+func (s *Sessions) decode(value string) (Session, bool) {
+	i := strings.LastIndexByte(value, '.')
+	if i < 0 {
+		return Session{}, false
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(value[i+1:])
+	if err != nil || !hmac.Equal(sig, s.sign(value[:i])) {
+		return Session{}, false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(value[:i])
+	if err != nil {
+		return Session{}, false
+	}
+
+	var sess Session
+	if err := json.Unmarshal(data, &sess); err != nil || sess.ID == "" {
+		return Session{}, false
+	}
+
+	return sess, true
+}
+
+func (s *Sessions) sign(msg string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
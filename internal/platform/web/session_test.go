@@ -0,0 +1,156 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+)
+
+var sessionKey = []byte("0123456789abcdef0123456789abcdef")
+
+func newSessions(t *testing.T, ttl time.Duration) *web.Sessions {
+	config := web.DefaultSessionConfig
+	config.TTL = ttl
+	s, err := web.NewSessions(sessionKey, config)
+	require.NoError(t, err)
+	return s
+}
+
+// requestWith returns a request with the cookies set in rec.
+func requestWith(rec *httptest.ResponseRecorder) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, c := range rec.Result().Cookies() {
+		req.AddCookie(c)
+	}
+
+	return req
+}
+
+func TestNewSessions_ShortKey(t *testing.T) {
+	_, err := web.NewSessions([]byte("short"), web.DefaultSessionConfig)
+	require.Error(t, err)
+}
+
+func TestSessions_Get(t *testing.T) {
+	// Given
+	s := newSessions(t, time.Hour)
+	rec := httptest.NewRecorder()
+	started, err := s.Start(rec, "alice")
+	require.NoError(t, err)
+
+	// When
+	got, err := s.Get(httptest.NewRecorder(), requestWith(rec))
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, started, got)
+	require.Equal(t, "alice", got.User)
+
+	cookie := rec.Result().Cookies()[0]
+	require.Equal(t, "session", cookie.Name)
+	require.True(t, cookie.HttpOnly)
+	require.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+}
+
+func TestSessions_Get_New(t *testing.T) {
+	tt := []struct {
+		name   string
+		cookie func(s *web.Sessions) *http.Cookie
+	}{
+		{name: "no cookie", cookie: func(*web.Sessions) *http.Cookie { return nil }},
+		{name: "tampered", cookie: func(s *web.Sessions) *http.Cookie {
+			rec := httptest.NewRecorder()
+			s.Start(rec, "alice")
+			c := rec.Result().Cookies()[0]
+			c.Value = "x" + c.Value
+			return c
+		}},
+		{name: "signed with another key", cookie: func(*web.Sessions) *http.Cookie {
+			other, _ := web.NewSessions([]byte("another key of at least 32 bytes!"), web.DefaultSessionConfig)
+			rec := httptest.NewRecorder()
+			other.Start(rec, "alice")
+			return rec.Result().Cookies()[0]
+		}},
+		{name: "expired", cookie: func(*web.Sessions) *http.Cookie {
+			expired := newSessions(t, -time.Minute)
+			rec := httptest.NewRecorder()
+			expired.Start(rec, "alice")
+			return rec.Result().Cookies()[0]
+		}},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			s := newSessions(t, time.Hour)
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if c := tc.cookie(s); c != nil {
+				req.AddCookie(c)
+			}
+			rec := httptest.NewRecorder()
+
+			// When
+			sess, err := s.Get(rec, req)
+
+			// Then
+			require.NoError(t, err)
+			require.Empty(t, sess.User)
+			require.NotEmpty(t, sess.ID)
+			require.Len(t, rec.Result().Cookies(), 1, "the new session must be set")
+		})
+	}
+}
+
+func TestSessions_End(t *testing.T) {
+	// Given
+	s := newSessions(t, time.Hour)
+	rec := httptest.NewRecorder()
+
+	// When
+	s.End(rec)
+
+	// Then
+	cookie := rec.Result().Cookies()[0]
+	require.Equal(t, "session", cookie.Name)
+	require.Empty(t, cookie.Value)
+	require.Equal(t, -1, cookie.MaxAge)
+}
+
+func TestSessions_VerifyCSRF(t *testing.T) {
+	s := newSessions(t, time.Hour)
+	sess, err := s.Start(httptest.NewRecorder(), "alice")
+	require.NoError(t, err)
+	other, err := s.Start(httptest.NewRecorder(), "alice")
+	require.NoError(t, err)
+
+	tt := []struct {
+		name  string
+		token string
+		want  bool
+	}{
+		{name: "token of the session", token: s.CSRFToken(sess), want: true},
+		{name: "token of another session", token: s.CSRFToken(other)},
+		{name: "no token"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			form := url.Values{web.CSRFField: {tc.token}}
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			// When
+			ok := s.VerifyCSRF(req, sess)
+
+			// Then
+			require.Equal(t, tc.want, ok)
+		})
+	}
+}
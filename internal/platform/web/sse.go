@@ -0,0 +1,85 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// EventStream writes Server-Sent Events to the client.
+type EventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewEventStream sends the headers of a Server-Sent Events response and returns an EventStream to write the events.
+func NewEventStream(w http.ResponseWriter) (*EventStream, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("streaming is not supported")
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &EventStream{
+		w:       w,
+		flusher: flusher,
+	}, nil
+}
+
+// Send converts data to JSON and writes it as an event with the given id and name.
+// Both id and event are omitted when empty.
+func (s *EventStream) Send(id, event string, data interface{}) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if id != "" {
+		fmt.Fprintf(&b, "id: %s\n", id)
+	}
+
+	if event != "" {
+		fmt.Fprintf(&b, "event: %s\n", event)
+	}
+
+	fmt.Fprintf(&b, "data: %s\n\n", jsonData)
+	return s.write(b.String())
+}
+
+// Comment writes a comment line, which clients ignore. It is used as heartbeat to keep idle connections open.
+func (s *EventStream) Comment(text string) error {
+	return s.write(": " + text + "\n\n")
+}
+
+// Retry tells the client how long to wait, in milliseconds, before reconnecting.
+func (s *EventStream) Retry(millis int) error {
+	return s.write(fmt.Sprintf("retry: %d\n\n", millis))
+}
+
+func (s *EventStream) write(msg string) error {
+	if _, err := s.w.Write([]byte(msg)); err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+	return nil
+}
+
+// LastEventID returns the ID of the last event received by a reconnecting client.
+// Browsers send it in the Last-Event-ID header; the lastEventId query parameter is accepted as a fallback.
+func LastEventID(r *http.Request) string {
+	if id := r.Header.Get("Last-Event-ID"); id != "" {
+		return id
+	}
+
+	return r.URL.Query().Get("lastEventId")
+}
@@ -0,0 +1,289 @@
+package web
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Validate checks the fields of the struct pointed by val against the rules of their `validate` tags,
+// and returns a 400 Error with every field that breaks them. The rules are separated by commas:
+//
+//	required      the field is not its zero value, or not empty for slices and maps
+//	url           the field is an absolute http or https URL
+//	min=n, max=n  the length of strings and slices, or the value of numbers, is at least or at most n
+//	oneof=a b c   the field is one of the values separated by spaces
+//	regex=expr    the field matches the regular expression; it must be the last rule
+//	dive          the rules that follow apply to each element of the slice
+//
+// Every rule but required is skipped for zero values. Fields are named after their JSON names, and
+// structs, pointers to structs and slices of structs are validated recursively.
+func Validate(val interface{}) error {
+	var fields []FieldError
+	validateValue(reflect.ValueOf(val), "", &fields)
+	if len(fields) > 0 {
+		return NewFieldError(fields...)
+	}
+
+	return nil
+}
+
+func validateValue(v reflect.Value, path string, fields *[]FieldError) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		for _, f := range structRules(v.Type()) {
+			fv := v.Field(f.index)
+			name := joinPath(path, f.name)
+			for _, r := range f.rules {
+				if fe, ok := r.check(fv, name); !ok {
+					*fields = append(*fields, fe)
+					break
+				}
+			}
+
+			if f.dive != nil && fv.Kind() == reflect.Slice {
+				for i := 0; i < fv.Len(); i++ {
+					elem := fmt.Sprintf("%s[%d]", name, i)
+					for _, r := range f.dive {
+						if fe, ok := r.check(fv.Index(i), elem); !ok {
+							*fields = append(*fields, fe)
+							break
+						}
+					}
+				}
+			}
+
+			validateValue(fv, name, fields)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			validateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fields)
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+
+	return path + "." + name
+}
+
+type fieldRules struct {
+	index int
+	name  string
+	rules []rule
+	// dive are the rules of each element of a slice.
+	dive []rule
+}
+
+// rules caches the fieldRules of each struct type.
+var rules sync.Map
+
+func structRules(t reflect.Type) []fieldRules {
+	if fr, ok := rules.Load(t); ok {
+		return fr.([]fieldRules)
+	}
+
+	var frs []fieldRules
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+
+		fr := fieldRules{index: i, name: fieldName(f)}
+		target := &fr.rules
+		for _, r := range splitRules(f.Tag.Get("validate")) {
+			if r == "dive" {
+				target = &fr.dive
+				continue
+			}
+
+			*target = append(*target, parseRule(t, f, r))
+		}
+
+		frs = append(frs, fr)
+	}
+
+	rules.Store(t, frs)
+	return frs
+}
+
+// splitRules returns the rules of a validate tag. The regex rule takes the rest of the tag, as
+// its expression may have commas.
+func splitRules(tag string) []string {
+	var rules []string
+	for tag != "" {
+		if strings.HasPrefix(tag, "regex=") {
+			return append(rules, tag)
+		}
+
+		r := tag
+		if j := strings.Index(tag, ","); j >= 0 {
+			r, tag = tag[:j], tag[j+1:]
+		} else {
+			tag = ""
+		}
+		rules = append(rules, r)
+	}
+
+	return rules
+}
+
+func fieldName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return f.Name
+	}
+
+	return name
+}
+
+// rule checks a value. It returns false and the FieldError if the value breaks the rule.
+type rule struct {
+	check func(v reflect.Value, name string) (FieldError, bool)
+}
+
+// parseRule panics if the rule is not valid, as the tags are fixed when the program is compiled.
+func parseRule(t reflect.Type, f reflect.StructField, r string) rule {
+	kv := strings.SplitN(r, "=", 2)
+	name, arg := kv[0], ""
+	if len(kv) == 2 {
+		arg = kv[1]
+	}
+
+	fail := func(code, format string, args ...interface{}) (FieldError, bool) {
+		return FieldError{Code: code, Message: fmt.Sprintf(format, args...)}, false
+	}
+
+	named := func(check func(v reflect.Value, name string) (FieldError, bool)) rule {
+		return rule{check: func(v reflect.Value, name string) (FieldError, bool) {
+			fe, ok := check(v, name)
+			fe.Field = name
+			return fe, ok
+		}}
+	}
+
+	// skipZero makes the rule pass for zero values, which are only checked by required.
+	skipZero := func(check func(v reflect.Value, name string) (FieldError, bool)) rule {
+		return named(func(v reflect.Value, name string) (FieldError, bool) {
+			if isZero(v) {
+				return FieldError{}, true
+			}
+			return check(indirect(v), name)
+		})
+	}
+
+	switch name {
+	case "required":
+		return named(func(v reflect.Value, name string) (FieldError, bool) {
+			if isZero(v) {
+				return fail("required", "%s is missing", name)
+			}
+			return FieldError{}, true
+		})
+	case "url":
+		return skipZero(func(v reflect.Value, name string) (FieldError, bool) {
+			u, err := url.Parse(v.String())
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fail("url", "%s must be an absolute http or https URL", name)
+			}
+			return FieldError{}, true
+		})
+	case "min", "max":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic(fmt.Sprintf("web: invalid %s rule of %s.%s: %v", name, t, f.Name, err))
+		}
+
+		return skipZero(func(v reflect.Value, field string) (FieldError, bool) {
+			size, length := measure(v)
+			if (name == "min" && size >= n) || (name == "max" && size <= n) {
+				return FieldError{}, true
+			}
+
+			bound := map[string]string{"min": "at least", "max": "at most"}[name]
+			if length {
+				return fail(name, "%s must have %s %s elements", field, bound, arg)
+			}
+			if v.Kind() == reflect.String {
+				return fail(name, "%s must have %s %s characters", field, bound, arg)
+			}
+			return fail(name, "%s must be %s %s", field, bound, arg)
+		})
+	case "oneof":
+		values := strings.Fields(arg)
+		return skipZero(func(v reflect.Value, name string) (FieldError, bool) {
+			s := fmt.Sprint(v.Interface())
+			for _, value := range values {
+				if s == value {
+					return FieldError{}, true
+				}
+			}
+			return fail("oneof", "%s must be one of %s", name, strings.Join(values, ", "))
+		})
+	case "regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			panic(fmt.Sprintf("web: invalid regex rule of %s.%s: %v", t, f.Name, err))
+		}
+
+		return skipZero(func(v reflect.Value, name string) (FieldError, bool) {
+			if !re.MatchString(v.String()) {
+				return fail("regex", "%s must match %s", name, arg)
+			}
+			return FieldError{}, true
+		})
+	}
+
+	panic(fmt.Sprintf("web: unknown validation rule %q of %s.%s", r, t, f.Name))
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+
+	return v.IsZero()
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+
+	return v
+}
+
+// measure returns the size of v compared by min and max, and whether it is the number of its elements.
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), false
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	}
+
+	return 0, false
+}
@@ -0,0 +1,138 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+)
+
+type window struct {
+	Start string `json:"start" validate:"required,regex=^\\d{2}:\\d{2}$"`
+}
+
+type schedule struct {
+	Windows []window `json:"windows" validate:"max=2"`
+}
+
+type request struct {
+	Link     string    `json:"link" validate:"required,url"`
+	Owner    string    `json:"owner" validate:"min=3,max=5"`
+	Visits   int       `json:"visits" validate:"min=0,max=10"`
+	Format   string    `json:"format" validate:"oneof=png svg"`
+	Tags     []string  `json:"tags" validate:"max=2,dive,required,max=3"`
+	Schedule *schedule `json:"schedule"`
+	Internal string
+}
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		name string
+		req  request
+		want []web.FieldError
+	}{
+		{
+			name: "valid",
+			req: request{
+				Link:     "https://www.google.com",
+				Owner:    "alice",
+				Visits:   10,
+				Format:   "svg",
+				Tags:     []string{"a", "b"},
+				Schedule: &schedule{Windows: []window{{Start: "09:00"}}},
+			},
+		},
+		{
+			name: "zero values only check required",
+			req:  request{Link: "http://example.com"},
+		},
+		{
+			name: "required",
+			req:  request{},
+			want: []web.FieldError{{Field: "link", Code: "required", Message: "link is missing"}},
+		},
+		{
+			name: "every field",
+			req: request{
+				Link:     "ftp://example.com",
+				Owner:    "al",
+				Visits:   -1,
+				Format:   "gif",
+				Tags:     []string{"", "long"},
+				Schedule: &schedule{Windows: []window{{}, {Start: "9am"}, {Start: "10:00"}}},
+			},
+			want: []web.FieldError{
+				{Field: "link", Code: "url", Message: "link must be an absolute http or https URL"},
+				{Field: "owner", Code: "min", Message: "owner must have at least 3 characters"},
+				{Field: "visits", Code: "min", Message: "visits must be at least 0"},
+				{Field: "format", Code: "oneof", Message: "format must be one of png, svg"},
+				{Field: "tags[0]", Code: "required", Message: "tags[0] is missing"},
+				{Field: "tags[1]", Code: "max", Message: "tags[1] must have at most 3 characters"},
+				{Field: "schedule.windows", Code: "max", Message: "schedule.windows must have at most 2 elements"},
+				{Field: "schedule.windows[0].start", Code: "required", Message: "schedule.windows[0].start is missing"},
+				{Field: "schedule.windows[1].start", Code: "regex", Message: `schedule.windows[1].start must match ^\d{2}:\d{2}$`},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// When
+			err := web.Validate(&tc.req)
+
+			// Then
+			if tc.want == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			var webErr *web.Error
+			require.ErrorAs(t, err, &webErr)
+			require.Equal(t, http.StatusBadRequest, webErr.Status)
+			require.Equal(t, "invalid_request", webErr.Code)
+			require.Equal(t, tc.want, webErr.Fields)
+		})
+	}
+}
+
+func TestValidate_InvalidRule(t *testing.T) {
+	var req struct {
+		Name string `validate:"lowercase"`
+	}
+
+	require.Panics(t, func() { web.Validate(&req) })
+}
+
+func TestDecode(t *testing.T) {
+	tt := []struct {
+		name       string
+		body       string
+		wantCode   string
+		wantFields int
+	}{
+		{name: "malformed", body: `{"link":`, wantCode: "bad_request"},
+		{name: "unknown field", body: `{"link":"https://www.google.com","unknown":1}`, wantCode: "bad_request"},
+		{name: "invalid", body: `{"owner":"a"}`, wantCode: "invalid_request", wantFields: 2},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tc.body))
+
+			// When
+			var req request
+			err := web.Decode(r, &req)
+
+			// Then
+			var webErr *web.Error
+			require.ErrorAs(t, err, &webErr)
+			require.Equal(t, http.StatusBadRequest, webErr.Status)
+			require.Equal(t, tc.wantCode, webErr.Code)
+			require.Len(t, webErr.Fields, tc.wantFields)
+		})
+	}
+}
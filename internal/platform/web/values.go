@@ -0,0 +1,131 @@
+package web
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// populate sets v from a tree of values whose leaves are strings, as decoded from forms and XML.
+// Objects are map[string]interface{} keyed by the JSON names of the fields, and lists are
+// []interface{}. A single value is accepted for a slice.
+func populate(node interface{}, v reflect.Value, path string) error {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return populate(node, v.Elem(), path)
+	}
+
+	if s, ok := node.(string); ok && v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			if err := u.UnmarshalText([]byte(s)); err != nil {
+				return fmt.Errorf("invalid value %q for %s: %w", s, path, err)
+			}
+			return nil
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%s must be an object", path)
+		}
+
+		fields := make(map[string]int)
+		for i := 0; i < v.NumField(); i++ {
+			if f := v.Type().Field(i); f.PkgPath == "" {
+				fields[fieldName(f)] = i
+			}
+		}
+
+		for key, child := range obj {
+			i, ok := fields[key]
+			if !ok {
+				return fmt.Errorf("unknown field %q", joinPath(path, key))
+			}
+
+			if err := populate(child, v.Field(i), joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		obj, ok := node.(map[string]interface{})
+		if !ok || v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("%s must be an object", path)
+		}
+
+		m := reflect.MakeMapWithSize(v.Type(), len(obj))
+		for key, child := range obj {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := populate(child, elem, joinPath(path, key)); err != nil {
+				return err
+			}
+			m.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+		v.Set(m)
+		return nil
+	case reflect.Slice:
+		list, ok := node.([]interface{})
+		if !ok {
+			list = []interface{}{node}
+		}
+
+		s := reflect.MakeSlice(v.Type(), len(list), len(list))
+		for i, child := range list {
+			if err := populate(child, s.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(node))
+		return nil
+	}
+
+	s, ok := node.(string)
+	if !ok {
+		return fmt.Errorf("%s must be a single value", path)
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if s == "on" {
+			// The value sent by HTML checkboxes.
+			b, err = true, nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q for %s", s, path)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q for %s", s, path)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q for %s", s, path)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q for %s", s, path)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s for %s", v.Type(), path)
+	}
+
+	return nil
+}
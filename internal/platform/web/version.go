@@ -0,0 +1,93 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Deprecation announces that the routes of a version will be removed.
+type Deprecation struct {
+	// Since is when the routes were deprecated. The Deprecation header is "true" if it is zero.
+	Since time.Time
+	// Sunset is when the routes will be removed, sent in the Sunset header unless it is zero.
+	Sunset time.Time
+	// Successor is the prefix of the routes that replace them, such as /v2. Each response links
+	// to the same path under it, unless it is empty.
+	Successor string
+}
+
+// header sets the headers that announce d in the response to the request for path.
+func (d Deprecation) header(h http.Header, path string) {
+	if d.Since.IsZero() {
+		h.Set("Deprecation", "true")
+	} else {
+		h.Set("Deprecation", d.Since.UTC().Format(http.TimeFormat))
+	}
+
+	if !d.Sunset.IsZero() {
+		h.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+
+	if d.Successor != "" {
+		h.Add("Link", "<"+d.Successor+path+`>; rel="successor-version"`)
+	}
+}
+
+// Version is a version of the API of an Application, whose routes are mounted under a prefix.
+type Version struct {
+	app         *Application
+	name        string
+	prefix      string
+	deprecation *Deprecation
+}
+
+// Version returns the version `name` of the API, whose routes are mounted under /name.
+func (app *Application) Version(name string) *Version {
+	return &Version{app: app, name: name, prefix: "/" + name}
+}
+
+// Alias returns a version that serves the routes of v under prefix, which may be empty to keep the
+// routes that were added before the API was versioned. Its responses announce the deprecation d.
+func (v *Version) Alias(prefix string, d Deprecation) *Version {
+	return &Version{app: v.app, name: v.name, prefix: prefix, deprecation: &d}
+}
+
+// Name returns the name of the version, as returned by APIVersion.
+func (v *Version) Name() string {
+	return v.name
+}
+
+// Method adds the route `pattern`, under the prefix of the version, that matches `method` http method
+// to execute the `handler` http.Handler. The rate limits of `pattern` apply to the route, sharing
+// their buckets with the other versions.
+func (v *Version) Method(method, pattern string, h http.Handler, doc ...Doc) {
+	route := Route{
+		Method:     method,
+		Pattern:    v.prefix + pattern,
+		Version:    v.name,
+		Deprecated: v.deprecation != nil,
+	}
+
+	// The version wraps the rate limits, so that the rejected requests are told about the deprecation too.
+	v.app.add(route, pattern, h, v.middleware, doc)
+}
+
+func (v *Version) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v.deprecation != nil {
+			v.deprecation.header(w.Header(), strings.TrimPrefix(r.URL.Path, v.prefix))
+		}
+
+		ctx := context.WithValue(r.Context(), versionKey, v.name)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// APIVersion returns the name of the version of the API of the route that matched r, or an empty
+// string if the route is not versioned.
+func APIVersion(r *http.Request) string {
+	version, _ := r.Context().Value(versionKey).(string)
+	return version
+}
@@ -0,0 +1,101 @@
+package web_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+)
+
+// versionHandler answers the version of the API of the request.
+var versionHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(web.APIVersion(r)))
+})
+
+func TestVersion_Method(t *testing.T) {
+	// Given
+	app := web.New()
+	v1 := app.Version("v1")
+	v1.Method(http.MethodGet, "/items/{id}", versionHandler)
+	v1.Alias("", web.Deprecation{
+		Since:     time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC),
+		Sunset:    time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+		Successor: "/v1",
+	}).Method(http.MethodGet, "/items/{id}", versionHandler)
+	app.Version("v2").Method(http.MethodGet, "/items/{id}", versionHandler)
+	app.Method(http.MethodGet, "/health", versionHandler)
+
+	tt := []struct {
+		target      string
+		wantVersion string
+		wantHeader  http.Header
+	}{
+		{target: "/v1/items/1", wantVersion: "v1"},
+		{target: "/v2/items/1", wantVersion: "v2"},
+		{target: "/health", wantVersion: ""},
+		{
+			target:      "/items/1",
+			wantVersion: "v1",
+			wantHeader: http.Header{
+				"Deprecation": {"Tue, 01 Jun 2021 00:00:00 GMT"},
+				"Sunset":      {"Sat, 01 Jan 2022 00:00:00 GMT"},
+				"Link":        {`</v1/items/1>; rel="successor-version"`},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.target, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+
+			// When
+			app.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tc.target, nil))
+
+			// Then
+			require.Equal(t, http.StatusOK, rec.Code)
+			require.Equal(t, tc.wantVersion, rec.Body.String())
+			for _, name := range []string{"Deprecation", "Sunset", "Link"} {
+				require.Equal(t, tc.wantHeader.Get(name), rec.Header().Get(name), name)
+			}
+		})
+	}
+
+	require.Equal(t, []web.Route{
+		{Method: http.MethodGet, Pattern: "/v1/items/{id}", Version: "v1"},
+		{Method: http.MethodGet, Pattern: "/items/{id}", Version: "v1", Deprecated: true},
+		{Method: http.MethodGet, Pattern: "/v2/items/{id}", Version: "v2"},
+		{Method: http.MethodGet, Pattern: "/health"},
+	}, app.Routes())
+}
+
+func TestVersion_Method_SharesRateLimits(t *testing.T) {
+	// Given
+	limiter := web.NewRateLimiter(web.NewMemoryRateStore())
+	limiter.Limit(http.MethodGet, "/items", web.Policy{Name: "per client", Key: web.ByIP(), Limit: web.Limit{Requests: 2, Per: time.Minute}})
+
+	app := web.New()
+	app.RateLimit(limiter)
+	v1 := app.Version("v1")
+	v1.Method(http.MethodGet, "/items", versionHandler)
+	v1.Alias("", web.Deprecation{}).Method(http.MethodGet, "/items", versionHandler)
+	app.Version("v2").Method(http.MethodGet, "/items", versionHandler)
+
+	// When
+	codes := make([]int, 0, 3)
+	var last *httptest.ResponseRecorder
+	for _, target := range []string{"/v1/items", "/v2/items", "/items"} {
+		last = httptest.NewRecorder()
+		app.ServeHTTP(last, httptest.NewRequest(http.MethodGet, target, nil))
+		codes = append(codes, last.Code)
+	}
+
+	// Then
+	require.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests}, codes)
+	require.Equal(t, "true", last.Header().Get("Deprecation"))
+	for _, route := range app.Routes() {
+		require.True(t, route.RateLimited, route.Pattern)
+	}
+}
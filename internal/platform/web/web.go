@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -16,18 +17,51 @@ import (
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+type ctxKey int
+
+const (
+	clientIPKey ctxKey = iota
+	acceptKey
+	versionKey
+	shutdownKey
+)
+
 // DefaultShutdownTimeout sets the maximum amount of time to wait for the server to shutdown gracefully.
 const DefaultShutdownTimeout = 10 * time.Second
 
 // Application is contains all required base components for building web applications.
 type Application struct {
-	mux *chi.Mux
+	mux     *chi.Mux
+	limiter *RateLimiter
+	routes  []Route
+}
+
+// Option configures the Application returned by New.
+type Option func(*options)
+
+type options struct {
+	quiet bool
+}
+
+// WithoutRequestLog stops the Application from logging every request to the standard output, for the
+// programs that serve their own requests in process.
+func WithoutRequestLog() Option {
+	return func(o *options) {
+		o.quiet = true
+	}
 }
 
 // New creates an Application that handles a set of routes for the application.
-func New() *Application {
+func New(opts ...Option) *Application {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	mux := chi.NewMux()
-	mux.Use(middleware.Logger)
+	if !o.quiet {
+		mux.Use(middleware.Logger)
+	}
 	mux.Use(middleware.Recoverer)
 
 	return &Application{
@@ -35,10 +69,50 @@ func New() *Application {
 	}
 }
 
+// Use appends middlewares that run before routing the requests. It must be called before adding any route.
+func (app *Application) Use(middlewares ...func(http.Handler) http.Handler) {
+	app.mux.Use(middlewares...)
+}
+
 // Method adds the route `pattern` that matches `method` http method to
-// execute the `handler` http.Handler.
-func (app *Application) Method(method, pattern string, h http.Handler) {
-	app.mux.Method(method, pattern, h)
+// execute the `handler` http.Handler. The route is documented by doc, if given.
+func (app *Application) Method(method, pattern string, h http.Handler, doc ...Doc) {
+	app.add(Route{Method: method, Pattern: pattern}, pattern, h, nil, doc)
+}
+
+// add adds route, limited by the policies of limitPattern, which is its pattern without the prefix of its
+// version. The middleware, if any, wraps the rate limits. A documented response is negotiated by Negotiate.
+func (app *Application) add(route Route, limitPattern string, h http.Handler, middleware func(http.Handler) http.Handler, doc []Doc) {
+	if len(doc) > 0 {
+		route.Doc = &doc[0]
+	}
+
+	// The routes that send their response with Respond reject what they can't answer before acting.
+	if route.Doc != nil && route.Doc.Response != nil && route.Doc.ContentType == "" {
+		h = Negotiate(h)
+	}
+
+	if app.limiter != nil {
+		route.RateLimited = len(app.limiter.routes[route.Method+" "+limitPattern]) > 0
+		h = app.limiter.Wrap(route.Method, limitPattern, h)
+	}
+
+	if middleware != nil {
+		h = middleware(h)
+	}
+
+	app.routes = append(app.routes, route)
+	app.mux.Method(route.Method, route.Pattern, h)
+}
+
+// Routes returns the routes added to the application, in the order they were added.
+func (app *Application) Routes() []Route {
+	return append([]Route(nil), app.routes...)
+}
+
+// RateLimit applies the policies of rl to the routes added afterwards.
+func (app *Application) RateLimit(rl *RateLimiter) {
+	app.limiter = rl
 }
 
 // ServeHTTP implements the http.Handler interface.
@@ -46,13 +120,21 @@ func (app *Application) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	app.mux.ServeHTTP(w, r)
 }
 
-// Run is called to start the web service.
+// Run is called to start the web service on :8080. It stops gracefully on SIGINT or SIGTERM.
 func (app *Application) Run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	return app.Serve(ctx, ":8080")
+}
+
+// Serve starts the web service on addr and blocks until ctx is done, when it stops gracefully.
+func (app *Application) Serve(ctx context.Context, addr string) error {
 	if err := app.printRoutes(); err != nil {
 		return err
 	}
 
-	return app.listenAndServe()
+	return app.listenAndServe(ctx, addr)
 }
 
 func (app *Application) printRoutes() error {
@@ -100,10 +182,24 @@ func (app *Application) printRoutes() error {
 	return w.Flush()
 }
 
-func (app *Application) listenAndServe() error {
+// ShuttingDown returns a channel that is closed when the server of r starts shutting down, so that the
+// handlers that would never finish, such as event streams, end and let it stop gracefully. It is nil, and
+// so never closed, for the requests that are not served by Serve.
+func ShuttingDown(r *http.Request) <-chan struct{} {
+	done, _ := r.Context().Value(shutdownKey).(chan struct{})
+	return done
+}
+
+func (app *Application) listenAndServe(ctx context.Context, addr string) error {
+	// shuttingDown is closed before shutting down the server. Unlike cancelling the context of the
+	// requests, it lets the others finish.
+	shuttingDown := make(chan struct{})
 	server := http.Server{
-		Addr:    ":8080",
+		Addr:    addr,
 		Handler: app,
+		BaseContext: func(net.Listener) context.Context {
+			return context.WithValue(context.Background(), shutdownKey, shuttingDown)
+		},
 	}
 
 	// Make a channel to listen for errors coming from the listener. Use a
@@ -116,16 +212,13 @@ func (app *Application) listenAndServe() error {
 		serverErrors <- server.ListenAndServe()
 	}()
 
-	// Make a channel to listen for an interrupt or terminate signal from the OS.
-	// Use a buffered channel because the signal package requires it.
-	shutdown := make(chan os.Signal, 1)
-	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
-
 	// Blocking main and waiting for shutdown.
 	select {
 	case err := <-serverErrors:
 		return fmt.Errorf("error in ListenAndServe: %w", err)
-	case <-shutdown:
+	case <-ctx.Done():
+		close(shuttingDown)
+
 		// Give outstanding requests a deadline for completion.
 		ctx, cancel := context.WithTimeout(context.Background(), DefaultShutdownTimeout)
 		defer cancel()
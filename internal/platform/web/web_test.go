@@ -0,0 +1,59 @@
+package web_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplication_Serve_ShuttingDown(t *testing.T) {
+	// Given
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := lis.Addr().String()
+	require.NoError(t, lis.Close())
+
+	streaming := make(chan struct{})
+	app := web.New(web.WithoutRequestLog())
+	app.Method(http.MethodGet, "/events", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		close(streaming)
+
+		// A stream never ends by itself.
+		select {
+		case <-r.Context().Done():
+		case <-web.ShuttingDown(r):
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- app.Serve(ctx, addr)
+	}()
+
+	var resp *http.Response
+	require.Eventually(t, func() bool {
+		resp, err = http.Get("http://" + addr + "/events")
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+	defer resp.Body.Close()
+	<-streaming
+
+	// When
+	cancel()
+
+	// Then
+	select {
+	case err := <-served:
+		require.NoError(t, err)
+	case <-time.After(web.DefaultShutdownTimeout / 2):
+		t.Fatal("the open stream held up the shutdown")
+	}
+}
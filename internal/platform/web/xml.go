@@ -0,0 +1,206 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// xmlCodec maps JSON documents to XML so both share the names of the fields. Objects are elements
+// with a child element for each field, lists repeat the element of the field, and keys that are not
+// valid XML names are <entry key="..."> elements. The root element is <response>, with an <item>
+// for each element when it is a list.
+var xmlCodec = Codec{
+	ContentType: "application/xml",
+	Decode:      decodeXML,
+	Encode:      encodeXML,
+}
+
+func encodeXML(w io.Writer, val interface{}) error {
+	data, err := json.Marshal(val)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	enc := xml.NewEncoder(w)
+
+	root := xml.StartElement{Name: xml.Name{Local: "response"}}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		if err := enc.EncodeToken(root); err != nil {
+			return err
+		}
+		if err := encodeXMLValue(enc, dec, "item"); err != nil {
+			return err
+		}
+		if err := enc.EncodeToken(root.End()); err != nil {
+			return err
+		}
+	} else if err := encodeXMLValue(enc, dec, "response"); err != nil {
+		return err
+	}
+
+	return enc.Flush()
+}
+
+func encodeXMLValue(enc *xml.Encoder, dec *json.Decoder, name string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return err
+	}
+
+	switch t := tok.(type) {
+	case json.Delim:
+		if t == '[' {
+			for dec.More() {
+				if err := encodeXMLValue(enc, dec, name); err != nil {
+					return err
+				}
+			}
+			_, err := dec.Token()
+			return err
+		}
+
+		start := xmlElement(name)
+		if err := enc.EncodeToken(start); err != nil {
+			return err
+		}
+
+		for dec.More() {
+			key, err := dec.Token()
+			if err != nil {
+				return err
+			}
+
+			if err := encodeXMLValue(enc, dec, key.(string)); err != nil {
+				return err
+			}
+		}
+
+		if _, err := dec.Token(); err != nil {
+			return err
+		}
+		return enc.EncodeToken(start.End())
+	case nil:
+		return nil
+	case json.Number:
+		// Numbers are written as they were encoded, without losing precision nor using exponents.
+		return enc.EncodeElement(t.String(), xmlElement(name))
+	default:
+		return enc.EncodeElement(fmt.Sprint(t), xmlElement(name))
+	}
+}
+
+func xmlElement(name string) xml.StartElement {
+	if isXMLName(name) {
+		return xml.StartElement{Name: xml.Name{Local: name}}
+	}
+
+	return xml.StartElement{
+		Name: xml.Name{Local: "entry"},
+		Attr: []xml.Attr{{Name: xml.Name{Local: "key"}, Value: name}},
+	}
+}
+
+func isXMLName(name string) bool {
+	if name == "" || strings.HasPrefix(strings.ToLower(name), "xml") {
+		return false
+	}
+
+	for i, r := range name {
+		if unicode.IsLetter(r) || r == '_' {
+			continue
+		}
+
+		if i > 0 && (unicode.IsDigit(r) || r == '-' || r == '.') {
+			continue
+		}
+
+		return false
+	}
+
+	return true
+}
+
+func decodeXML(r io.Reader, val interface{}) error {
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return errors.New("empty XML document")
+			}
+			return err
+		}
+
+		if _, ok := tok.(xml.StartElement); ok {
+			node, err := decodeXMLElement(dec)
+			if err != nil {
+				return err
+			}
+
+			// An empty root element is an empty object.
+			if s, ok := node.(string); ok && strings.TrimSpace(s) == "" {
+				node = map[string]interface{}{}
+			}
+
+			return populate(node, reflect.ValueOf(val), "")
+		}
+	}
+}
+
+// decodeXMLElement returns the content of the element that was just opened: the text of a leaf, or
+// an object with its children.
+func decodeXMLElement(dec *xml.Decoder) (interface{}, error) {
+	var text strings.Builder
+	var children map[string]interface{}
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			for _, attr := range t.Attr {
+				if t.Name.Local == "entry" && attr.Name.Local == "key" {
+					name = attr.Value
+				}
+			}
+
+			child, err := decodeXMLElement(dec)
+			if err != nil {
+				return nil, err
+			}
+
+			if children == nil {
+				children = make(map[string]interface{})
+			}
+
+			switch existing := children[name].(type) {
+			case nil:
+				children[name] = child
+			case []interface{}:
+				children[name] = append(existing, child)
+			default:
+				children[name] = []interface{}{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if children != nil {
+				return children, nil
+			}
+			return text.String(), nil
+		}
+	}
+}
@@ -0,0 +1,194 @@
+// Package unfurl fetches the title, description and image of the pages the links point to.
+package unfurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/platform/safehttp"
+	"golang.org/x/net/html"
+)
+
+// ErrPrivateAddress is returned when the destination resolves to an address that is not
+// reachable from the Internet, to prevent using the service to scan the internal network.
+var ErrPrivateAddress = safehttp.ErrPrivateAddress
+
+// ErrNotHTML is returned when the destination is not an HTML page.
+var ErrNotHTML = errors.New("destination is not an HTML page")
+
+// Page is the metadata found in an HTML page.
+type Page struct {
+	Title       string
+	Description string
+	Image       string
+}
+
+// Config limits the resources used to fetch a page.
+type Config struct {
+	// Timeout is the maximum amount of time to fetch a page, including redirects.
+	Timeout time.Duration
+	// MaxBodySize is the maximum number of bytes read from a page. The metadata is
+	// expected to be in the head of the page, so large pages are truncated.
+	MaxBodySize int64
+	// MaxRedirects is the maximum number of redirects followed.
+	MaxRedirects int
+	// AllowPrivate disables the protection against fetching private addresses. It is meant for tests.
+	AllowPrivate bool
+	UserAgent    string
+}
+
+// DefaultConfig is the Config used by the server.
+var DefaultConfig = Config{
+	Timeout:      5 * time.Second,
+	MaxBodySize:  512 << 10,
+	MaxRedirects: 5,
+	UserAgent:    "link-tracker-unfurl/1.0",
+}
+
+// Fetcher retrieves the metadata of HTML pages.
+type Fetcher struct {
+	cfg    Config
+	client *http.Client
+}
+
+// NewFetcher creates a Fetcher that refuses to connect to private addresses unless cfg allows it.
+func NewFetcher(cfg Config) *Fetcher {
+	return &Fetcher{
+		cfg: cfg,
+		client: safehttp.NewClient(safehttp.Config{
+			Timeout:      cfg.Timeout,
+			MaxRedirects: cfg.MaxRedirects,
+			AllowPrivate: cfg.AllowPrivate,
+		}),
+	}
+}
+
+// Fetch retrieves rawURL and extracts its metadata. The Open Graph properties are preferred over
+// the title and description of the page. Relative image URLs are resolved against the page URL.
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (Page, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return Page{}, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return Page{}, fmt.Errorf("unsupported scheme %q", u.Scheme)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return Page{}, err
+	}
+
+	req.Header.Set("User-Agent", f.cfg.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Page{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Page{}, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Page{}, ErrNotHTML
+	}
+
+	page := parse(io.LimitReader(resp.Body, f.cfg.MaxBodySize))
+	if page.Image != "" {
+		if img, err := resp.Request.URL.Parse(page.Image); err == nil {
+			page.Image = img.String()
+		}
+	}
+
+	return page, nil
+}
+
+// parse extracts the metadata from the head of an HTML document.
+func parse(r io.Reader) Page {
+	var title, description, ogTitle, ogDescription, ogImage, twitterImage string
+	var inTitle bool
+
+	z := html.NewTokenizer(r)
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return newPage(title, description, ogTitle, ogDescription, ogImage, twitterImage)
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "title":
+				inTitle = tt == html.StartTagToken
+			case "body":
+				return newPage(title, description, ogTitle, ogDescription, ogImage, twitterImage)
+			case "meta":
+				var key, content string
+				for _, a := range t.Attr {
+					switch strings.ToLower(a.Key) {
+					case "property", "name":
+						key = strings.ToLower(a.Val)
+					case "content":
+						content = strings.TrimSpace(a.Val)
+					}
+				}
+
+				switch key {
+				case "og:title":
+					ogTitle = content
+				case "og:description":
+					ogDescription = content
+				case "og:image", "og:image:url":
+					if ogImage == "" {
+						ogImage = content
+					}
+				case "twitter:image":
+					twitterImage = content
+				case "description":
+					description = content
+				}
+			}
+		case html.EndTagToken:
+			t := z.Token()
+			switch t.Data {
+			case "title":
+				inTitle = false
+			case "head":
+				return newPage(title, description, ogTitle, ogDescription, ogImage, twitterImage)
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = strings.TrimSpace(string(z.Text()))
+			}
+		}
+	}
+}
+
+func newPage(title, description, ogTitle, ogDescription, ogImage, twitterImage string) Page {
+	return Page{
+		Title:       firstNonEmpty(ogTitle, title),
+		Description: firstNonEmpty(ogDescription, description),
+		Image:       firstNonEmpty(ogImage, twitterImage),
+	}
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
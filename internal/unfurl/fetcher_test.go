@@ -0,0 +1,156 @@
+package unfurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/unfurl"
+	"github.com/stretchr/testify/require"
+)
+
+const page = `<!DOCTYPE html>
+<html>
+<head>
+<title> Plain title </title>
+<meta name="description" content="Plain description">
+<meta property="og:title" content="Open Graph title">
+<meta property="og:image" content="/images/cover.png">
+</head>
+<body><meta property="og:description" content="Ignored, it is in the body"></body>
+</html>`
+
+func testConfig() unfurl.Config {
+	cfg := unfurl.DefaultConfig
+	cfg.AllowPrivate = true
+	cfg.Timeout = time.Second
+	return cfg
+}
+
+func TestFetcher_Fetch(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	fetcher := unfurl.NewFetcher(testConfig())
+
+	// When
+	p, err := fetcher.Fetch(context.Background(), server.URL+"/article")
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, "Open Graph title", p.Title)
+	require.Equal(t, "Plain description", p.Description)
+	require.Equal(t, server.URL+"/images/cover.png", p.Image)
+}
+
+func TestFetcher_Fetch_PrivateAddress(t *testing.T) {
+	// Given
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	fetcher := unfurl.NewFetcher(unfurl.DefaultConfig)
+
+	// When
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+
+	// Then
+	require.ErrorIs(t, err, unfurl.ErrPrivateAddress)
+	require.False(t, called)
+}
+
+func TestFetcher_Fetch_Errors(t *testing.T) {
+	tt := []struct {
+		name    string
+		handler http.HandlerFunc
+		wantErr string
+	}{
+		{
+			name: "not html",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/pdf")
+			},
+			wantErr: unfurl.ErrNotHTML.Error(),
+		},
+		{
+			name: "not found",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			},
+			wantErr: "unexpected status 404",
+		},
+		{
+			name: "too many redirects",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "/loop", http.StatusFound)
+			},
+			wantErr: "stopped after 5 redirects",
+		},
+		{
+			name: "timeout",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(2 * time.Second):
+				}
+			},
+			wantErr: "Client.Timeout",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+
+			cfg := testConfig()
+			cfg.Timeout = 100 * time.Millisecond
+			fetcher := unfurl.NewFetcher(cfg)
+
+			// When
+			_, err := fetcher.Fetch(context.Background(), server.URL)
+
+			// Then
+			require.Error(t, err)
+			require.Contains(t, err.Error(), tc.wantErr)
+		})
+	}
+}
+
+func TestFetcher_Fetch_MaxBodySize(t *testing.T) {
+	// Given
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><head><title>Big page</title>"))
+		w.Write([]byte(strings.Repeat("<!-- padding -->", 1000)))
+		w.Write([]byte(`<meta property="og:image" content="https://cdn.example.com/late.png"></head></html>`))
+	}))
+	defer server.Close()
+
+	cfg := testConfig()
+	cfg.MaxBodySize = 1024
+	fetcher := unfurl.NewFetcher(cfg)
+
+	// When
+	p, err := fetcher.Fetch(context.Background(), server.URL)
+
+	// Then
+	require.NoError(t, err)
+	require.Equal(t, "Big page", p.Title)
+	require.Empty(t, p.Image)
+}
+
+func TestFetcher_Fetch_UnsupportedScheme(t *testing.T) {
+	_, err := unfurl.NewFetcher(testConfig()).Fetch(context.Background(), "file:///etc/passwd")
+	require.Error(t, err)
+}
@@ -0,0 +1,145 @@
+package unfurl
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+)
+
+// Store is the subset of link.Service used by the Worker to keep the metadata of the links.
+type Store interface {
+	List(ctx context.Context, owner string) ([]link.Link, error)
+	SetMetadata(ctx context.Context, ID int, m link.Metadata) error
+}
+
+// WorkerConfig controls when and how many pages are fetched.
+type WorkerConfig struct {
+	// Concurrency is the number of pages fetched at the same time.
+	Concurrency int
+	// QueueSize is the number of links waiting to be fetched. Links created while the queue
+	// is full are fetched in the next refresh.
+	QueueSize int
+	// RefreshInterval is how often the links are checked for stale metadata.
+	RefreshInterval time.Duration
+	// MaxAge is how long the metadata of a link is considered fresh.
+	MaxAge time.Duration
+}
+
+// DefaultWorkerConfig is the WorkerConfig used by the server.
+var DefaultWorkerConfig = WorkerConfig{
+	Concurrency:     4,
+	QueueSize:       256,
+	RefreshInterval: 10 * time.Minute,
+	MaxAge:          24 * time.Hour,
+}
+
+// Worker fetches the metadata of the links in the background: right after they are
+// created and periodically afterwards, to keep it up to date.
+type Worker struct {
+	fetcher *Fetcher
+	store   Store
+	cfg     WorkerConfig
+	queue   chan link.Link
+	now     func() time.Time
+}
+
+func NewWorker(f *Fetcher, s Store, cfg WorkerConfig) *Worker {
+	return &Worker{
+		fetcher: f,
+		store:   s,
+		cfg:     cfg,
+		queue:   make(chan link.Link, cfg.QueueSize),
+		now:     time.Now,
+	}
+}
+
+// Handle enqueues the links as they are created, except the secret ones. It never blocks, so it can be subscribed directly to a link.Bus.
+func (w *Worker) Handle(_ context.Context, e link.Event) {
+	if e.Type != link.EventCreated || e.Secret {
+		return
+	}
+
+	select {
+	case w.queue <- link.Link{ID: e.LinkID, URL: e.URL}:
+	default:
+	}
+}
+
+// Run fetches the enqueued links and refreshes the stale ones until ctx is done.
+func (w *Worker) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < w.cfg.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case l := <-w.queue:
+					w.fetch(ctx, l)
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(w.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		case <-ticker.C:
+			w.refresh(ctx)
+		}
+	}
+}
+
+// refresh enqueues the active, not secret, links whose metadata is older than MaxAge.
+func (w *Worker) refresh(ctx context.Context) {
+	links, err := w.store.List(ctx, "")
+	if err != nil {
+		log.Printf("unfurl: listing links: %v", err)
+		return
+	}
+
+	deadline := w.now().Add(-w.cfg.MaxAge)
+	for _, l := range links {
+		if l.Inactive || l.Secret() || l.Metadata.FetchedAt.After(deadline) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case w.queue <- l:
+		}
+	}
+}
+
+// fetch updates the metadata of l. If the page cannot be fetched, the previous metadata is kept along with the error.
+func (w *Worker) fetch(ctx context.Context, l link.Link) {
+	page, err := w.fetcher.Fetch(ctx, l.URL)
+
+	m := link.Metadata{
+		Title:       page.Title,
+		Description: page.Description,
+		Image:       page.Image,
+		FetchedAt:   w.now(),
+	}
+
+	if err != nil {
+		m = l.Metadata
+		m.FetchedAt = w.now()
+		m.Err = err.Error()
+	}
+
+	if err := w.store.SetMetadata(ctx, l.ID, m); err != nil {
+		log.Printf("unfurl: storing metadata of link %d: %v", l.ID, err)
+	}
+}
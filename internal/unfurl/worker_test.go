@@ -0,0 +1,124 @@
+package unfurl_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/unfurl"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorker_Run(t *testing.T) {
+	// Given
+	var updated int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if atomic.LoadInt32(&updated) == 0 {
+			w.Write([]byte(page))
+			return
+		}
+
+		w.Write([]byte(`<html><head><title>Updated title</title></head></html>`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := link.NewBus()
+	service := link.NewService(link.NewInMemoryRepository(), link.WithPublisher(bus))
+	worker := unfurl.NewWorker(unfurl.NewFetcher(testConfig()), service, unfurl.WorkerConfig{
+		Concurrency:     1,
+		QueueSize:       10,
+		RefreshInterval: 10 * time.Millisecond,
+		MaxAge:          50 * time.Millisecond,
+	})
+	bus.Subscribe(worker.Handle)
+
+	done := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(done)
+	}()
+
+	// When
+	l, err := service.Create(ctx, link.NewLink{URL: server.URL, Password: "1234"})
+	require.NoError(t, err)
+
+	// Then
+	require.Eventually(t, func() bool {
+		l, _ = service.FindByID(ctx, l.ID)
+		return l.Metadata.Title == "Open Graph title"
+	}, 2*time.Second, 5*time.Millisecond)
+	require.Equal(t, server.URL+"/images/cover.png", l.Metadata.Image)
+
+	// The metadata is refreshed once it is older than MaxAge.
+	atomic.StoreInt32(&updated, 1)
+	require.Eventually(t, func() bool {
+		l, _ = service.FindByID(ctx, l.ID)
+		return l.Metadata.Title == "Updated title"
+	}, 2*time.Second, 5*time.Millisecond)
+
+	cancel()
+	<-done
+}
+
+func TestWorker_Run_KeepsMetadataOnError(t *testing.T) {
+	// Given
+	var fail int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	bus := link.NewBus()
+	service := link.NewService(link.NewInMemoryRepository(), link.WithPublisher(bus))
+	worker := unfurl.NewWorker(unfurl.NewFetcher(testConfig()), service, unfurl.WorkerConfig{
+		Concurrency:     1,
+		QueueSize:       10,
+		RefreshInterval: 10 * time.Millisecond,
+		MaxAge:          20 * time.Millisecond,
+	})
+	bus.Subscribe(worker.Handle)
+
+	done := make(chan struct{})
+	go func() {
+		worker.Run(ctx)
+		close(done)
+	}()
+
+	l, err := service.Create(ctx, link.NewLink{URL: server.URL, Password: "1234"})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		l, _ = service.FindByID(ctx, l.ID)
+		return l.Metadata.Title != ""
+	}, 2*time.Second, 5*time.Millisecond)
+
+	// When
+	atomic.StoreInt32(&fail, 1)
+
+	// Then
+	require.Eventually(t, func() bool {
+		l, _ = service.FindByID(ctx, l.ID)
+		return l.Metadata.Err != ""
+	}, 2*time.Second, 5*time.Millisecond)
+	require.Equal(t, "Open Graph title", l.Metadata.Title)
+	require.Contains(t, l.Metadata.Err, "503")
+
+	cancel()
+	<-done
+}
@@ -0,0 +1,107 @@
+package webhook
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+)
+
+// Delivery records a single attempt to deliver an event to a Subscription.
+type Delivery struct {
+	// ID identifies the event being delivered. It is the same for every attempt,
+	// so receivers can use it to discard duplicates.
+	ID             string
+	SubscriptionID int
+	EventType      link.EventType
+	Attempt        int
+	StatusCode     int
+	Err            string
+	Succeeded      bool
+	Time           time.Time
+	Duration       time.Duration
+}
+
+// DeliveryLog keeps the most recent delivery attempts of every Subscription.
+type DeliveryLog struct {
+	mu   sync.RWMutex
+	size int
+	m    map[int][]Delivery
+}
+
+// NewDeliveryLog creates a DeliveryLog that keeps up to size attempts per Subscription.
+func NewDeliveryLog(size int) *DeliveryLog {
+	return &DeliveryLog{
+		size: size,
+		m:    make(map[int][]Delivery),
+	}
+}
+
+// Add records d, discarding the oldest attempt of the Subscription if the log is full.
+func (l *DeliveryLog) Add(d Delivery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	deliveries := append(l.m[d.SubscriptionID], d)
+	if len(deliveries) > l.size {
+		deliveries = deliveries[len(deliveries)-l.size:]
+	}
+
+	l.m[d.SubscriptionID] = deliveries
+}
+
+// List returns the recorded attempts of a Subscription, the most recent first.
+func (l *DeliveryLog) List(subscriptionID int) []Delivery {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	deliveries := l.m[subscriptionID]
+	result := make([]Delivery, len(deliveries))
+	for i, d := range deliveries {
+		result[len(deliveries)-1-i] = d
+	}
+
+	return result
+}
+
+// DeadLetter is an event that could not be delivered after exhausting all the attempts.
+type DeadLetter struct {
+	Delivery Delivery
+	URL      string
+	Payload  []byte
+	Reason   string
+}
+
+// DeadLetterStore encapsulates the storage of the events that could not be delivered.
+type DeadLetterStore interface {
+	Add(ctx context.Context, d DeadLetter) error
+	List(ctx context.Context) ([]DeadLetter, error)
+}
+
+// InMemoryDeadLetterStore is a DeadLetterStore safe for concurrent use.
+type InMemoryDeadLetterStore struct {
+	mu          sync.RWMutex
+	deadLetters []DeadLetter
+}
+
+func NewInMemoryDeadLetterStore() *InMemoryDeadLetterStore {
+	return &InMemoryDeadLetterStore{}
+}
+
+func (s *InMemoryDeadLetterStore) Add(ctx context.Context, d DeadLetter) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deadLetters = append(s.deadLetters, d)
+	return nil
+}
+
+func (s *InMemoryDeadLetterStore) List(ctx context.Context) ([]DeadLetter, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deadLetters := make([]DeadLetter, len(s.deadLetters))
+	copy(deadLetters, s.deadLetters)
+	return deadLetters, nil
+}
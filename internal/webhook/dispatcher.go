@@ -0,0 +1,312 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/safehttp"
+)
+
+// Config controls how the Dispatcher delivers events.
+type Config struct {
+	// Workers is the number of deliveries performed concurrently.
+	Workers int
+	// QueueSize is the number of deliveries waiting for a worker. When the queue is full,
+	// new deliveries are sent straight to the dead-letter store.
+	QueueSize int
+	// MaxAttempts is the number of times a delivery is tried before giving up.
+	MaxAttempts int
+	// BaseDelay is the wait before the first retry. It doubles on every subsequent retry up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+	// Timeout is the maximum amount of time to wait for a receiver to respond.
+	Timeout time.Duration
+	// AllowPrivate disables the protection against delivering to private addresses. It is meant for tests.
+	AllowPrivate bool
+}
+
+// DefaultConfig is the Config used by the server.
+var DefaultConfig = Config{
+	Workers:     4,
+	QueueSize:   1024,
+	MaxAttempts: 5,
+	BaseDelay:   time.Second,
+	MaxDelay:    time.Minute,
+	Timeout:     10 * time.Second,
+}
+
+// Dispatcher delivers link events to the matching subscriptions as signed HTTP POST requests.
+type Dispatcher struct {
+	repository  Repository
+	log         *DeliveryLog
+	deadLetters DeadLetterStore
+	cfg         Config
+	client      *http.Client
+	queue       chan delivery
+	now         func() time.Time
+	// mu guards stopped, which is set once Run drains the queue, after which Handle stops enqueueing.
+	mu      sync.RWMutex
+	stopped bool
+}
+
+type delivery struct {
+	id           string
+	subscription Subscription
+	eventType    link.EventType
+	payload      []byte
+}
+
+type payload struct {
+	ID        string         `json:"id"`
+	Type      link.EventType `json:"type"`
+	CreatedAt time.Time      `json:"created_at"`
+	Data      payloadData    `json:"data"`
+}
+
+type payloadData struct {
+	ID int `json:"id"`
+	// URL is left out for secret links, whose destination is only revealed by redirecting.
+	URL       string `json:"url,omitempty"`
+	Count     int    `json:"count"`
+	Threshold int    `json:"threshold,omitempty"`
+	Code      string `json:"code,omitempty"`
+}
+
+// NewDispatcher creates a Dispatcher that reads the subscriptions from r, records every attempt
+// in l and stores the events that could not be delivered in dl. It refuses to deliver to private
+// addresses unless cfg allows it, since the URLs of the subscriptions are given by the users.
+func NewDispatcher(r Repository, l *DeliveryLog, dl DeadLetterStore, cfg Config) *Dispatcher {
+	return &Dispatcher{
+		repository:  r,
+		log:         l,
+		deadLetters: dl,
+		cfg:         cfg,
+		client: safehttp.NewClient(safehttp.Config{
+			Timeout:      cfg.Timeout,
+			MaxRedirects: maxRedirects,
+			AllowPrivate: cfg.AllowPrivate,
+		}),
+		queue: make(chan delivery, cfg.QueueSize),
+		now:   time.Now,
+	}
+}
+
+// maxRedirects is the number of redirects followed by a delivery.
+const maxRedirects = 10
+
+// Handle enqueues e for every matching subscription. It never blocks, so it can be
+// subscribed directly to a link.Bus. Once Run stopped, the deliveries go straight to the dead-letter store.
+func (d *Dispatcher) Handle(ctx context.Context, e link.Event) {
+	subscriptions, err := d.repository.List(ctx)
+	if err != nil {
+		log.Printf("webhook: listing subscriptions: %v", err)
+		return
+	}
+
+	var id string
+	var body []byte
+	for _, s := range subscriptions {
+		if !s.Matches(e) {
+			continue
+		}
+
+		if body == nil {
+			id = newID()
+			data := payloadData{
+				ID:        e.LinkID,
+				URL:       e.URL,
+				Count:     e.Count,
+				Threshold: e.Threshold,
+				Code:      e.Code,
+			}
+			if e.Secret {
+				data.URL = ""
+			}
+
+			body, err = json.Marshal(payload{
+				ID:        id,
+				Type:      e.Type,
+				CreatedAt: e.Time.UTC(),
+				Data:      data,
+			})
+			if err != nil {
+				log.Printf("webhook: encoding event: %v", err)
+				return
+			}
+		}
+
+		d.enqueue(ctx, delivery{id: id, subscription: s, eventType: e.Type, payload: body})
+	}
+}
+
+// enqueue queues dlv for the workers, unless the queue is full or Run stopped.
+func (d *Dispatcher) enqueue(ctx context.Context, dlv delivery) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	last := Delivery{ID: dlv.id, SubscriptionID: dlv.subscription.ID, EventType: dlv.eventType, Time: d.now()}
+	if d.stopped {
+		d.deadLetter(ctx, dlv, last, "dispatcher stopped")
+		return
+	}
+
+	select {
+	case d.queue <- dlv:
+	default:
+		d.deadLetter(ctx, dlv, last, "queue is full")
+	}
+}
+
+// Run starts the workers and blocks until ctx is done.
+// Deliveries waiting for a retry or still in the queue are sent to the dead-letter store when ctx is done.
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for i := 0; i < d.cfg.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case dlv := <-d.queue:
+					d.deliver(ctx, dlv)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	// Once stopped is set no delivery is enqueued, so none is left in the queue after draining it.
+	d.mu.Lock()
+	d.stopped = true
+	d.mu.Unlock()
+
+	for {
+		select {
+		case dlv := <-d.queue:
+			d.deadLetter(context.Background(), dlv, Delivery{ID: dlv.id, SubscriptionID: dlv.subscription.ID, EventType: dlv.eventType, Time: d.now()}, "dispatcher stopped")
+		default:
+			return
+		}
+	}
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, dlv delivery) {
+	var last Delivery
+	for attempt := 1; attempt <= d.cfg.MaxAttempts; attempt++ {
+		var retry bool
+		last, retry = d.attempt(ctx, dlv, attempt)
+		d.log.Add(last)
+
+		if last.Succeeded {
+			return
+		}
+
+		if !retry || attempt == d.cfg.MaxAttempts {
+			break
+		}
+
+		timer := time.NewTimer(d.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			d.deadLetter(context.Background(), dlv, last, "dispatcher stopped")
+			return
+		case <-timer.C:
+		}
+	}
+
+	d.deadLetter(ctx, dlv, last, fmt.Sprintf("giving up after %d attempts", last.Attempt))
+}
+
+// attempt performs a single delivery and reports whether it is worth retrying it in case of failure.
+func (d *Dispatcher) attempt(ctx context.Context, dlv delivery, attempt int) (Delivery, bool) {
+	start := d.now()
+	result := Delivery{
+		ID:             dlv.id,
+		SubscriptionID: dlv.subscription.ID,
+		EventType:      dlv.eventType,
+		Attempt:        attempt,
+		Time:           start,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, dlv.subscription.URL, bytes.NewReader(dlv.payload))
+	if err != nil {
+		result.Err = err.Error()
+		return result, false
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-ID", dlv.id)
+	req.Header.Set("X-Webhook-Event", string(dlv.eventType))
+	req.Header.Set(SignatureHeader, Sign(dlv.subscription.Secret, start, dlv.payload))
+
+	resp, err := d.client.Do(req)
+	result.Duration = d.now().Sub(start)
+	if err != nil {
+		result.Err = err.Error()
+		// A private address will not become public by trying again.
+		return result, !errors.Is(err, safehttp.ErrPrivateAddress)
+	}
+	resp.Body.Close()
+
+	result.StatusCode = resp.StatusCode
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		result.Succeeded = true
+		return result, false
+	}
+
+	result.Err = http.StatusText(resp.StatusCode)
+	switch {
+	case resp.StatusCode >= 500, resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests:
+		return result, true
+	default:
+		return result, false
+	}
+}
+
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.cfg.BaseDelay
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= d.cfg.MaxDelay {
+			return d.cfg.MaxDelay
+		}
+	}
+
+	return delay
+}
+
+func (d *Dispatcher) deadLetter(ctx context.Context, dlv delivery, last Delivery, reason string) {
+	dl := DeadLetter{
+		Delivery: last,
+		URL:      dlv.subscription.URL,
+		Payload:  dlv.payload,
+		Reason:   reason,
+	}
+
+	if err := d.deadLetters.Add(ctx, dl); err != nil {
+		log.Printf("webhook: storing dead letter %s: %v", dlv.id, err)
+	}
+}
+
+func newID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	return "evt_" + hex.EncodeToString(b)
+}
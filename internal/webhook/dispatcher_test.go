@@ -0,0 +1,349 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+	received chan struct{}
+}
+
+func newReceiver(failures, status int) *receiver {
+	return &receiver{
+		failures: failures,
+		status:   status,
+		received: make(chan struct{}, 100),
+	}
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	rc.mu.Lock()
+	rc.requests = append(rc.requests, r)
+	rc.bodies = append(rc.bodies, body)
+	fail := len(rc.requests) <= rc.failures
+	rc.mu.Unlock()
+
+	if fail {
+		w.WriteHeader(rc.status)
+	} else {
+		w.WriteHeader(http.StatusNoContent)
+	}
+
+	rc.received <- struct{}{}
+}
+
+func (rc *receiver) wait(t *testing.T, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-rc.received:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timeout waiting for request %d", i+1)
+		}
+	}
+}
+
+func newDispatcher(t *testing.T, subscriptions ...webhook.Subscription) (*webhook.Dispatcher, *webhook.DeliveryLog, *webhook.InMemoryDeadLetterStore) {
+	repository := webhook.NewInMemoryRepository()
+	for _, s := range subscriptions {
+		if _, err := repository.Save(context.Background(), s); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deliveryLog := webhook.NewDeliveryLog(10)
+	deadLetters := webhook.NewInMemoryDeadLetterStore()
+	dispatcher := webhook.NewDispatcher(repository, deliveryLog, deadLetters, webhook.Config{
+		Workers:      1,
+		QueueSize:    10,
+		MaxAttempts:  3,
+		BaseDelay:    time.Millisecond,
+		MaxDelay:     5 * time.Millisecond,
+		Timeout:      time.Second,
+		AllowPrivate: true,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		dispatcher.Run(ctx)
+		close(done)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return dispatcher, deliveryLog, deadLetters
+}
+
+func TestDispatcher_Handle_SignedPayload(t *testing.T) {
+	// Given
+	rc := newReceiver(0, 0)
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	dispatcher, deliveryLog, _ := newDispatcher(t, webhook.Subscription{URL: server.URL, Secret: "secret"})
+	e := link.Event{Type: link.EventVisited, LinkID: 1, URL: "https://www.google.com", Count: 3, Time: time.Now()}
+
+	// When
+	dispatcher.Handle(context.Background(), e)
+	rc.wait(t, 1)
+
+	// Then
+	req, body := rc.requests[0], rc.bodies[0]
+	require.Equal(t, string(link.EventVisited), req.Header.Get("X-Webhook-Event"))
+	require.NoError(t, webhook.Verify("secret", req.Header.Get(webhook.SignatureHeader), body, time.Minute, time.Now()))
+	require.ErrorIs(t, webhook.Verify("other", req.Header.Get(webhook.SignatureHeader), body, time.Minute, time.Now()), webhook.ErrInvalidSignature)
+
+	var payload struct {
+		ID   string `json:"id"`
+		Type string `json:"type"`
+		Data struct {
+			ID    int    `json:"id"`
+			URL   string `json:"url"`
+			Count int    `json:"count"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	require.Equal(t, req.Header.Get("X-Webhook-ID"), payload.ID)
+	require.Equal(t, "link.visited", payload.Type)
+	require.Equal(t, 1, payload.Data.ID)
+	require.Equal(t, 3, payload.Data.Count)
+
+	require.Eventually(t, func() bool {
+		deliveries := deliveryLog.List(1)
+		return len(deliveries) == 1 && deliveries[0].Succeeded
+	}, time.Second, time.Millisecond)
+}
+
+func TestDispatcher_Handle_Secret(t *testing.T) {
+	// Given
+	rc := newReceiver(0, 0)
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	dispatcher, _, _ := newDispatcher(t, webhook.Subscription{URL: server.URL, Secret: "secret"})
+	e := link.Event{Type: link.EventVisited, LinkID: 1, URL: "https://www.google.com/reset?token=1", Count: 1, Secret: true, Time: time.Now()}
+
+	// When
+	dispatcher.Handle(context.Background(), e)
+	rc.wait(t, 1)
+
+	// Then
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	var payload struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rc.bodies[0], &payload))
+	require.Equal(t, float64(1), payload.Data["id"])
+	require.NotContains(t, payload.Data, "url", "the destination of a secret link must not be sent")
+}
+
+func TestDispatcher_Handle_RetriesWithBackoff(t *testing.T) {
+	// Given
+	rc := newReceiver(2, http.StatusServiceUnavailable)
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	dispatcher, deliveryLog, deadLetters := newDispatcher(t, webhook.Subscription{URL: server.URL, Secret: "secret"})
+
+	// When
+	dispatcher.Handle(context.Background(), link.Event{Type: link.EventCreated, LinkID: 1, Time: time.Now()})
+	rc.wait(t, 3)
+
+	// Then
+	require.Eventually(t, func() bool { return len(deliveryLog.List(1)) == 3 }, time.Second, time.Millisecond)
+	deliveries := deliveryLog.List(1)
+	require.True(t, deliveries[0].Succeeded)
+	require.Equal(t, 3, deliveries[0].Attempt)
+	require.Equal(t, http.StatusServiceUnavailable, deliveries[2].StatusCode)
+	require.Equal(t, deliveries[0].ID, deliveries[2].ID)
+
+	dl, err := deadLetters.List(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, dl)
+}
+
+func TestDispatcher_Handle_DeadLetter(t *testing.T) {
+	tt := []struct {
+		name     string
+		status   int
+		attempts int
+	}{
+		{name: "retries exhausted", status: http.StatusInternalServerError, attempts: 3},
+		{name: "permanent failure", status: http.StatusGone, attempts: 1},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			rc := newReceiver(10, tc.status)
+			server := httptest.NewServer(rc)
+			defer server.Close()
+
+			dispatcher, _, deadLetters := newDispatcher(t, webhook.Subscription{URL: server.URL, Secret: "secret"})
+
+			// When
+			dispatcher.Handle(context.Background(), link.Event{Type: link.EventInactivated, LinkID: 1, Time: time.Now()})
+			rc.wait(t, tc.attempts)
+
+			// Then
+			var dl []webhook.DeadLetter
+			require.Eventually(t, func() bool {
+				dl, _ = deadLetters.List(context.Background())
+				return len(dl) == 1
+			}, time.Second, time.Millisecond)
+			require.Equal(t, tc.attempts, dl[0].Delivery.Attempt)
+			require.Equal(t, tc.status, dl[0].Delivery.StatusCode)
+			require.Equal(t, server.URL, dl[0].URL)
+			require.NotEmpty(t, dl[0].Payload)
+		})
+	}
+}
+
+func TestDispatcher_Run_DeadLettersQueueOnShutdown(t *testing.T) {
+	// Given
+	rc := newReceiver(0, 0)
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	repository := webhook.NewInMemoryRepository()
+	_, err := repository.Save(context.Background(), webhook.Subscription{URL: server.URL, Secret: "secret"})
+	require.NoError(t, err)
+	deadLetters := webhook.NewInMemoryDeadLetterStore()
+	dispatcher := webhook.NewDispatcher(repository, webhook.NewDeliveryLog(10), deadLetters, webhook.Config{
+		Workers:      1,
+		QueueSize:    10,
+		MaxAttempts:  3,
+		BaseDelay:    time.Millisecond,
+		MaxDelay:     5 * time.Millisecond,
+		Timeout:      time.Second,
+		AllowPrivate: true,
+	})
+
+	for i := 1; i <= 3; i++ {
+		dispatcher.Handle(context.Background(), link.Event{Type: link.EventCreated, LinkID: i, Time: time.Now()})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// When
+	dispatcher.Run(ctx)
+
+	// Then
+	dl, err := deadLetters.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, dl, 3, "the queued deliveries must not be lost")
+	for _, d := range dl {
+		require.Equal(t, "dispatcher stopped", d.Reason)
+		require.Equal(t, server.URL, d.URL)
+		require.NotEmpty(t, d.Payload)
+	}
+}
+
+func TestDispatcher_Handle_AfterRun(t *testing.T) {
+	// Given
+	repository := webhook.NewInMemoryRepository()
+	_, err := repository.Save(context.Background(), webhook.Subscription{URL: "https://example.com/hook", Secret: "secret"})
+	require.NoError(t, err)
+	deadLetters := webhook.NewInMemoryDeadLetterStore()
+	dispatcher := webhook.NewDispatcher(repository, webhook.NewDeliveryLog(10), deadLetters, webhook.Config{Workers: 1, QueueSize: 10, MaxAttempts: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dispatcher.Run(ctx)
+
+	// When
+	dispatcher.Handle(context.Background(), link.Event{Type: link.EventCreated, LinkID: 1, Time: time.Now()})
+
+	// Then
+	dl, err := deadLetters.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, dl, 1, "the deliveries of a stopped dispatcher must not be lost")
+	require.Equal(t, "dispatcher stopped", dl[0].Reason)
+}
+
+func TestDispatcher_Handle_PrivateAddress(t *testing.T) {
+	// Given
+	rc := newReceiver(0, 0)
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	repository := webhook.NewInMemoryRepository()
+	_, err := repository.Save(context.Background(), webhook.Subscription{URL: server.URL, Secret: "secret"})
+	require.NoError(t, err)
+	deliveryLog := webhook.NewDeliveryLog(10)
+	deadLetters := webhook.NewInMemoryDeadLetterStore()
+	dispatcher := webhook.NewDispatcher(repository, deliveryLog, deadLetters, webhook.Config{
+		Workers:     1,
+		QueueSize:   10,
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+		Timeout:     time.Second,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	// When
+	dispatcher.Handle(context.Background(), link.Event{Type: link.EventCreated, LinkID: 1, Time: time.Now()})
+
+	// Then
+	require.Eventually(t, func() bool {
+		dl, err := deadLetters.List(context.Background())
+		return err == nil && len(dl) == 1
+	}, time.Second, time.Millisecond)
+
+	deliveries := deliveryLog.List(1)
+	require.Len(t, deliveries, 1, "a private address must not be retried")
+	require.Contains(t, deliveries[0].Err, "not public")
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	require.Empty(t, rc.requests)
+}
+
+func TestDispatcher_Handle_Filters(t *testing.T) {
+	// Given
+	rc := newReceiver(0, 0)
+	server := httptest.NewServer(rc)
+	defer server.Close()
+
+	dispatcher, deliveryLog, _ := newDispatcher(t,
+		webhook.Subscription{URL: server.URL, Events: []link.EventType{link.EventCreated}},
+		webhook.Subscription{URL: server.URL, URLFilter: "https://example.com/*"},
+	)
+
+	// When
+	dispatcher.Handle(context.Background(), link.Event{Type: link.EventVisited, URL: "https://www.google.com", Time: time.Now()})
+	dispatcher.Handle(context.Background(), link.Event{Type: link.EventVisited, URL: "https://example.com/promo", Time: time.Now()})
+	rc.wait(t, 1)
+
+	// Then
+	require.Eventually(t, func() bool { return len(deliveryLog.List(2)) == 1 }, time.Second, time.Millisecond)
+	require.Empty(t, deliveryLog.List(1))
+}
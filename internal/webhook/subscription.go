@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+)
+
+// ErrNotFound is returned when a Subscription is not found by its ID.
+var ErrNotFound = errors.New("subscription not found")
+
+// Subscription is an HTTP endpoint that wants to be notified about link events.
+type Subscription struct {
+	ID  int
+	URL string
+	// Secret is the key used to sign every payload sent to URL.
+	Secret string
+	// Events restricts the event types delivered to the subscription. If empty, every event is delivered.
+	Events []link.EventType
+	// URLFilter restricts the deliveries to the links whose URL matches the pattern,
+	// where * matches any sequence of characters. If empty, events of every link are delivered.
+	// It never matches secret links, so it cannot be used to guess their destination.
+	URLFilter string
+}
+
+// Matches reports whether e has to be delivered to the subscription.
+func (s Subscription) Matches(e link.Event) bool {
+	if len(s.Events) > 0 {
+		found := false
+		for _, t := range s.Events {
+			if t == e.Type {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	if s.URLFilter == "" {
+		return true
+	}
+
+	return !e.Secret && matchPattern(s.URLFilter, e.URL)
+}
+
+func matchPattern(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	for i := range parts {
+		parts[i] = regexp.QuoteMeta(parts[i])
+	}
+
+	re, err := regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+	if err != nil {
+		return false
+	}
+
+	return re.MatchString(s)
+}
+
+// Repository encapsulates the storage of a Subscription.
+type Repository interface {
+	Save(ctx context.Context, s Subscription) (int, error)
+	Delete(ctx context.Context, ID int) error
+	FindByID(ctx context.Context, ID int) (Subscription, error)
+	List(ctx context.Context) ([]Subscription, error)
+}
+
+// InMemoryRepository is a Repository safe for concurrent use, since subscriptions
+// are read by the Dispatcher while they are managed through the API.
+type InMemoryRepository struct {
+	mu     sync.RWMutex
+	nextID int
+	m      map[int]Subscription
+}
+
+func NewInMemoryRepository() *InMemoryRepository {
+	return &InMemoryRepository{
+		m: make(map[int]Subscription),
+	}
+}
+
+func (r *InMemoryRepository) Save(ctx context.Context, s Subscription) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nextID++
+	s.ID = r.nextID
+	r.m[s.ID] = s
+	return s.ID, nil
+}
+
+func (r *InMemoryRepository) Delete(ctx context.Context, ID int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.m[ID]; !ok {
+		return ErrNotFound
+	}
+
+	delete(r.m, ID)
+	return nil
+}
+
+func (r *InMemoryRepository) FindByID(ctx context.Context, ID int) (Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	s, ok := r.m[ID]
+	if !ok {
+		return Subscription{}, ErrNotFound
+	}
+
+	return s, nil
+}
+
+func (r *InMemoryRepository) List(ctx context.Context) ([]Subscription, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	subscriptions := make([]Subscription, 0, len(r.m))
+	for id := 1; id <= r.nextID; id++ {
+		if s, ok := r.m[id]; ok {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+
+	return subscriptions, nil
+}
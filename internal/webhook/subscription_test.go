@@ -0,0 +1,85 @@
+package webhook_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubscription_Matches(t *testing.T) {
+	tt := []struct {
+		name         string
+		subscription webhook.Subscription
+		event        link.Event
+		want         bool
+	}{
+		{
+			name:         "no filters",
+			subscription: webhook.Subscription{},
+			event:        link.Event{Type: link.EventVisited, URL: "https://www.google.com"},
+			want:         true,
+		},
+		{
+			name:         "event type included",
+			subscription: webhook.Subscription{Events: []link.EventType{link.EventCreated, link.EventVisited}},
+			event:        link.Event{Type: link.EventVisited},
+			want:         true,
+		},
+		{
+			name:         "event type excluded",
+			subscription: webhook.Subscription{Events: []link.EventType{link.EventCreated}},
+			event:        link.Event{Type: link.EventVisited},
+			want:         false,
+		},
+		{
+			name:         "url matches pattern",
+			subscription: webhook.Subscription{URLFilter: "https://*.google.com/*"},
+			event:        link.Event{Type: link.EventVisited, URL: "https://mail.google.com/inbox"},
+			want:         true,
+		},
+		{
+			name:         "url of a secret link",
+			subscription: webhook.Subscription{URLFilter: "https://*.google.com/*"},
+			event:        link.Event{Type: link.EventVisited, URL: "https://mail.google.com/inbox", Secret: true},
+			want:         false,
+		},
+		{
+			name:         "url does not match pattern",
+			subscription: webhook.Subscription{URLFilter: "https://*.google.com/*"},
+			event:        link.Event{Type: link.EventVisited, URL: "https://www.example.com/"},
+			want:         false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, tc.subscription.Matches(tc.event))
+		})
+	}
+}
+
+func TestInMemoryRepository_Delete(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	repository := webhook.NewInMemoryRepository()
+	first, _ := repository.Save(ctx, webhook.Subscription{URL: "https://a.example.com"})
+	second, _ := repository.Save(ctx, webhook.Subscription{URL: "https://b.example.com"})
+
+	// When
+	err := repository.Delete(ctx, first)
+
+	// Then
+	require.NoError(t, err)
+	require.ErrorIs(t, repository.Delete(ctx, first), webhook.ErrNotFound)
+
+	subscriptions, err := repository.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, subscriptions, 1)
+	require.Equal(t, second, subscriptions[0].ID)
+
+	third, _ := repository.Save(ctx, webhook.Subscription{})
+	require.Equal(t, 3, third)
+}
@@ -0,0 +1,89 @@
+// Package webhook notifies external systems about link events through signed HTTP callbacks.
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+)
+
+// NewSubscription contains the information needed to create a Subscription.
+type NewSubscription struct {
+	URL       string
+	Secret    string
+	Events    []link.EventType
+	URLFilter string
+}
+
+// Service encapsulates the management of the subscriptions and the inspection of their deliveries.
+type Service interface {
+	Subscribe(ctx context.Context, ns NewSubscription) (Subscription, error)
+	Unsubscribe(ctx context.Context, ID int) error
+	List(ctx context.Context) ([]Subscription, error)
+	Deliveries(ctx context.Context, subscriptionID int) ([]Delivery, error)
+	DeadLetters(ctx context.Context) ([]DeadLetter, error)
+}
+
+type service struct {
+	repository  Repository
+	log         *DeliveryLog
+	deadLetters DeadLetterStore
+}
+
+func NewService(r Repository, l *DeliveryLog, dl DeadLetterStore) Service {
+	return &service{
+		repository:  r,
+		log:         l,
+		deadLetters: dl,
+	}
+}
+
+// Subscribe stores a new Subscription. If ns does not provide a secret, a random one is generated.
+func (s *service) Subscribe(ctx context.Context, ns NewSubscription) (Subscription, error) {
+	secret := ns.Secret
+	if secret == "" {
+		b := make([]byte, 32)
+		if _, err := rand.Read(b); err != nil {
+			return Subscription{}, err
+		}
+
+		secret = hex.EncodeToString(b)
+	}
+
+	sub := Subscription{
+		URL:       ns.URL,
+		Secret:    secret,
+		Events:    ns.Events,
+		URLFilter: ns.URLFilter,
+	}
+
+	id, err := s.repository.Save(ctx, sub)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	sub.ID = id
+	return sub, nil
+}
+
+func (s *service) Unsubscribe(ctx context.Context, ID int) error {
+	return s.repository.Delete(ctx, ID)
+}
+
+func (s *service) List(ctx context.Context) ([]Subscription, error) {
+	return s.repository.List(ctx)
+}
+
+func (s *service) Deliveries(ctx context.Context, subscriptionID int) ([]Delivery, error) {
+	if _, err := s.repository.FindByID(ctx, subscriptionID); err != nil {
+		return nil, err
+	}
+
+	return s.log.List(subscriptionID), nil
+}
+
+func (s *service) DeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	return s.deadLetters.List(ctx)
+}
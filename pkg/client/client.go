@@ -0,0 +1,230 @@
+// Package client is the Go client of the API of the link tracker. It covers every route of version 1
+// of the API but the streams of clicks.
+//
+// Errors answered by the server are returned as *Error, which matches the errors of this package with
+// errors.Is, such as ErrNotFound.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DefaultUserAgent is sent in the requests unless WithUserAgent is given.
+const DefaultUserAgent = "link-tracker-go-client/1"
+
+// Client calls the API of a link tracker. It is safe for concurrent use.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	userAgent  string
+	retries    int
+	backoff    time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient makes the Client send the requests with hc instead of http.DefaultClient.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) {
+		c.userAgent = ua
+	}
+}
+
+// WithRetries sets how many times a request is retried, and the time to wait before the first retry,
+// which doubles after each one. Requests are retried when they are rate limited, and, unless they are
+// POST requests or Resolve, when they fail to reach the server or it is unavailable. The default is 2
+// retries after 200ms; 0 disables them.
+func WithRetries(n int, backoff time.Duration) Option {
+	return func(c *Client) {
+		c.retries = n
+		c.backoff = backoff
+	}
+}
+
+// New creates a Client of the link tracker at baseURL, such as http://localhost:8080.
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("client: invalid base URL: %w", err)
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("client: base URL %q is not an absolute http or https URL", baseURL)
+	}
+
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	c := &Client{
+		baseURL:    u,
+		httpClient: http.DefaultClient,
+		userAgent:  DefaultUserAgent,
+		retries:    2,
+		backoff:    200 * time.Millisecond,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// apiPrefix is the prefix of the routes of the version of the API the client speaks.
+const apiPrefix = "/v1"
+
+// request describes a call to the API.
+type request struct {
+	method string
+	path   string
+	query  url.Values
+	// in is encoded as the JSON body of the request, unless it is nil.
+	in interface{}
+	// noRedirect returns the redirections instead of following them.
+	noRedirect bool
+	// unsafe requests change the server even though their method is safe, like visiting a link.
+	// Like POST requests, they are only retried when they are rate limited.
+	unsafe bool
+}
+
+// idempotent reports whether sending the request twice has the same effect as sending it once.
+func (r request) idempotent() bool {
+	return r.method != http.MethodPost && !r.unsafe
+}
+
+// call sends the request and decodes the JSON body of the response into out, unless it is nil.
+func (c *Client) call(ctx context.Context, r request, out interface{}) error {
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if out == nil {
+		drain(resp.Body)
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("client: decoding the response of %s %s: %w", r.method, r.path, err)
+	}
+
+	return nil
+}
+
+// send sends the request, retrying it if it can, and returns the response if its status is not an
+// error. The caller must close its body.
+func (c *Client) send(ctx context.Context, r request) (*http.Response, error) {
+	var body []byte
+	if r.in != nil {
+		var err error
+		if body, err = json.Marshal(r.in); err != nil {
+			return nil, fmt.Errorf("client: encoding the request of %s %s: %w", r.method, r.path, err)
+		}
+	}
+
+	hc := c.httpClient
+	if r.noRedirect {
+		noRedirect := *hc
+		noRedirect.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+		hc = &noRedirect
+	}
+
+	u := *c.baseURL
+	u.Path += r.path
+	u.RawQuery = r.query.Encode()
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, r.method, u.String(), bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("client: %w", err)
+		}
+
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set("User-Agent", c.userAgent)
+		if r.in != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+
+		var wait time.Duration
+		resp, err := hc.Do(req)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil || !r.idempotent() {
+				return nil, err
+			}
+		case resp.StatusCode < http.StatusBadRequest:
+			return resp, nil
+		default:
+			e := newError(resp)
+			resp.Body.Close()
+			if !retryable(r, e.Status) {
+				return nil, e
+			}
+			err, wait = e, e.RetryAfter
+		}
+
+		if attempt >= c.retries {
+			return nil, err
+		}
+
+		if backoff := c.backoff << attempt; backoff > wait {
+			wait = backoff
+		}
+
+		if !sleep(ctx, wait) {
+			return nil, err
+		}
+	}
+}
+
+// retryable reports whether a request that was answered with status can be sent again.
+func retryable(r request, status int) bool {
+	switch status {
+	case http.StatusTooManyRequests:
+		// The request was rejected before being handled.
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return r.idempotent()
+	}
+
+	return false
+}
+
+// sleep waits for d, and returns false without waiting if ctx is done before.
+func sleep(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// drain reads the rest of the body, so that the connection can be reused.
+func drain(r io.Reader) {
+	io.Copy(io.Discard, io.LimitReader(r, 1<<16))
+}
@@ -0,0 +1,343 @@
+package client_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/emacampolo/link-tracker/cmd/server/handler"
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/emacampolo/link-tracker/internal/webhook"
+	"github.com/emacampolo/link-tracker/pkg/client"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newServer starts the real application of the server. Its requests go through wrap, if not nil.
+func newServer(t *testing.T, wrap func(http.Handler) http.Handler) *httptest.Server {
+	linkService := link.NewService(link.NewInMemoryRepository(),
+		link.WithHasher(link.BcryptHasher{Cost: bcrypt.MinCost}),
+		link.WithPasswordPolicy(link.PasswordPolicy{MinLength: 8}))
+	webhookService := webhook.NewService(webhook.NewInMemoryRepository(), webhook.NewDeliveryLog(10), webhook.NewInMemoryDeadLetterStore())
+
+	app := web.New()
+	handler.API{
+		Link:    handler.NewLink(linkService),
+		QR:      handler.NewQR(linkService, "http://localhost:8080"),
+		Click:   handler.NewClick(linkService, link.NewClickStream(10), time.Second),
+		Webhook: handler.NewWebhook(webhookService),
+	}.Register(app)
+
+	var h http.Handler = app
+	if wrap != nil {
+		h = wrap(h)
+	}
+
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newClient(t *testing.T, srv *httptest.Server, opts ...client.Option) *client.Client {
+	c, err := client.New(srv.URL, append([]client.Option{client.WithHTTPClient(srv.Client())}, opts...)...)
+	require.NoError(t, err)
+	return c
+}
+
+func TestNew_InvalidBaseURL(t *testing.T) {
+	for _, baseURL := range []string{"", "localhost:8080", "ftp://localhost", "http://"} {
+		_, err := client.New(baseURL)
+		require.Error(t, err, baseURL)
+	}
+}
+
+func TestClient_Links(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	c := newClient(t, newServer(t, nil))
+
+	// When
+	id, err := c.Create(ctx, client.NewLink{URL: "https://www.google.com", Password: "s3cret-pass", Owner: "alice", Title: "Google"})
+	require.NoError(t, err)
+
+	destination, err := c.Resolve(ctx, id, "s3cret-pass")
+	require.NoError(t, err)
+
+	links, err := c.List(ctx, "alice")
+	require.NoError(t, err)
+
+	require.NoError(t, c.Inactivate(ctx, id))
+	metrics, err := c.Metrics(ctx, id)
+	require.NoError(t, err)
+
+	// Then
+	require.Equal(t, 1, id)
+	require.Equal(t, "https://www.google.com", destination)
+	require.Equal(t, []client.Link{{ID: 1, URL: "https://www.google.com", Owner: "alice", Title: "Google", Count: 1}}, links)
+	require.Equal(t, 1, metrics.ID)
+	require.Equal(t, 1, metrics.Count)
+	require.True(t, metrics.Inactive)
+	require.Equal(t, "alice", metrics.Owner)
+}
+
+func TestClient_Links_Schedule(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	c := newClient(t, newServer(t, nil))
+	deactivateAt := time.Now().Add(-time.Hour).UTC().Truncate(time.Second)
+
+	// When
+	id, err := c.Create(ctx, client.NewLink{
+		URL:      "https://www.google.com",
+		Schedule: &client.Schedule{DeactivateAt: &deactivateAt, ClosedMessage: "too late"},
+	})
+	require.NoError(t, err)
+
+	_, resolveErr := c.Resolve(ctx, id, "")
+	metrics, err := c.Metrics(ctx, id)
+	require.NoError(t, err)
+
+	// Then
+	require.True(t, errors.Is(resolveErr, client.ErrClosed), resolveErr)
+	require.NotNil(t, metrics.Schedule)
+	require.False(t, metrics.Schedule.Open)
+	require.Equal(t, deactivateAt, metrics.Schedule.DeactivateAt.UTC())
+	require.Equal(t, "too late", metrics.Schedule.ClosedMessage)
+}
+
+func TestClient_Errors(t *testing.T) {
+	ctx := context.Background()
+	c := newClient(t, newServer(t, nil))
+
+	id, err := c.Create(ctx, client.NewLink{URL: "https://www.google.com", Password: "s3cret-pass"})
+	require.NoError(t, err)
+
+	inactive, err := c.Create(ctx, client.NewLink{URL: "https://www.bing.com"})
+	require.NoError(t, err)
+	require.NoError(t, c.Inactivate(ctx, inactive))
+
+	tt := []struct {
+		name       string
+		call       func() error
+		wantStatus int
+		wantCode   string
+		wantErr    error
+		wantFields []client.FieldError
+	}{
+		{
+			name:       "not found",
+			call:       func() error { _, err := c.Metrics(ctx, 42); return err },
+			wantStatus: http.StatusNotFound,
+			wantCode:   "link_not_found",
+			wantErr:    link.ErrNotFound,
+		},
+		{
+			name:       "password required",
+			call:       func() error { _, err := c.Resolve(ctx, id, ""); return err },
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "password_required",
+			wantErr:    client.ErrAuthentication,
+		},
+		{
+			name:       "invalid password",
+			call:       func() error { _, err := c.Resolve(ctx, id, "wrong-pass"); return err },
+			wantStatus: http.StatusUnauthorized,
+			wantCode:   "invalid_password",
+			wantErr:    client.ErrAuthentication,
+		},
+		{
+			name:       "inactive",
+			call:       func() error { _, err := c.Resolve(ctx, inactive, ""); return err },
+			wantStatus: http.StatusUnprocessableEntity,
+			wantCode:   "link_inactive",
+			wantErr:    client.ErrInactive,
+		},
+		{
+			name: "weak password",
+			call: func() error {
+				_, err := c.Create(ctx, client.NewLink{URL: "https://www.google.com", Password: "short"})
+				return err
+			},
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "weak_password",
+			wantErr:    client.ErrWeakPassword,
+		},
+		{
+			name:       "invalid request",
+			call:       func() error { _, err := c.Create(ctx, client.NewLink{URL: "google"}); return err },
+			wantStatus: http.StatusBadRequest,
+			wantCode:   "invalid_request",
+			wantErr:    client.ErrInvalidRequest,
+			wantFields: []client.FieldError{{Field: "link", Code: "url", Message: "link must be an absolute http or https URL"}},
+		},
+		{
+			name:       "access disabled",
+			call:       func() error { _, err := c.GrantAccess(ctx, id, client.NewAccess{TTL: time.Hour}); return err },
+			wantStatus: http.StatusNotImplemented,
+			wantCode:   "access_disabled",
+			wantErr:    client.ErrAccessDisabled,
+		},
+		{
+			name:       "webhook not found",
+			call:       func() error { return c.Unsubscribe(ctx, 42) },
+			wantStatus: http.StatusNotFound,
+			wantCode:   "webhook_not_found",
+			wantErr:    webhook.ErrNotFound,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// When
+			err := tc.call()
+
+			// Then
+			var e *client.Error
+			require.True(t, errors.As(err, &e), err)
+			require.Equal(t, tc.wantStatus, e.Status)
+			require.Equal(t, tc.wantCode, e.Code)
+			require.Equal(t, tc.wantFields, e.Fields)
+			require.True(t, errors.Is(err, tc.wantErr), err)
+		})
+	}
+}
+
+func TestClient_QR(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	c := newClient(t, newServer(t, nil))
+	id, err := c.Create(ctx, client.NewLink{URL: "https://www.google.com"})
+	require.NoError(t, err)
+
+	// When
+	img, contentType, err := c.QR(ctx, id, client.QROptions{Size: 128})
+	require.NoError(t, err)
+	_, _, sizeErr := c.QR(ctx, id, client.QROptions{Size: 1, Margin: new(int)})
+
+	// Then
+	require.Equal(t, "image/png", contentType)
+	require.True(t, bytes.HasPrefix(img, []byte("\x89PNG")))
+	require.True(t, errors.Is(sizeErr, client.ErrInvalidRequest), sizeErr)
+}
+
+func TestClient_Webhooks(t *testing.T) {
+	// Given
+	ctx := context.Background()
+	c := newClient(t, newServer(t, nil))
+
+	// When
+	s, err := c.Subscribe(ctx, client.NewSubscription{URL: "https://example.com/hook", Events: []string{client.EventVisited}})
+	require.NoError(t, err)
+
+	subscriptions, err := c.Subscriptions(ctx)
+	require.NoError(t, err)
+
+	deliveries, err := c.Deliveries(ctx, s.ID)
+	require.NoError(t, err)
+
+	deadLetters, err := c.DeadLetters(ctx)
+	require.NoError(t, err)
+
+	require.NoError(t, c.Unsubscribe(ctx, s.ID))
+	afterUnsubscribe, err := c.Subscriptions(ctx)
+	require.NoError(t, err)
+
+	// Then
+	require.NotEmpty(t, s.Secret)
+	require.Equal(t, []client.Subscription{{ID: s.ID, URL: "https://example.com/hook", Events: []string{client.EventVisited}}}, subscriptions)
+	require.Empty(t, deliveries)
+	require.Empty(t, deadLetters)
+	require.Empty(t, afterUnsubscribe)
+}
+
+// failFirst answers the first n requests with status, and the rest with next.
+func failFirst(n int32, status int, header http.Header) func(http.Handler) http.Handler {
+	var count int32
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&count, 1) <= n {
+				for k, v := range header {
+					w.Header()[k] = v
+				}
+				w.WriteHeader(status)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestClient_Retries(t *testing.T) {
+	tt := []struct {
+		name       string
+		failures   int32
+		status     int
+		header     http.Header
+		post       bool
+		resolve    bool
+		wantErr    bool
+		wantStatus int
+	}{
+		{name: "unavailable get", failures: 2, status: http.StatusServiceUnavailable},
+		{name: "unavailable get after every retry", failures: 3, status: http.StatusServiceUnavailable, wantErr: true, wantStatus: http.StatusServiceUnavailable},
+		{name: "unavailable post", failures: 1, status: http.StatusServiceUnavailable, post: true, wantErr: true, wantStatus: http.StatusServiceUnavailable},
+		{name: "unavailable resolve", failures: 1, status: http.StatusServiceUnavailable, resolve: true, wantErr: true, wantStatus: http.StatusServiceUnavailable},
+		{name: "rate limited post", failures: 1, status: http.StatusTooManyRequests, header: http.Header{"Retry-After": {"0"}}, post: true},
+		{name: "internal error", failures: 1, status: http.StatusInternalServerError, wantErr: true, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			// Given
+			ctx := context.Background()
+			srv := newServer(t, failFirst(tc.failures, tc.status, tc.header))
+			c := newClient(t, srv, client.WithRetries(2, time.Millisecond))
+
+			// When
+			var err error
+			switch {
+			case tc.post:
+				_, err = c.Create(ctx, client.NewLink{URL: "https://www.google.com"})
+			case tc.resolve:
+				_, err = c.Resolve(ctx, 1, "")
+			default:
+				_, err = c.List(ctx, "")
+			}
+
+			// Then
+			if !tc.wantErr {
+				require.NoError(t, err)
+				return
+			}
+
+			var e *client.Error
+			require.True(t, errors.As(err, &e), err)
+			require.Equal(t, tc.wantStatus, e.Status)
+		})
+	}
+}
+
+func TestClient_Retries_RetryAfter(t *testing.T) {
+	// Given
+	srv := newServer(t, failFirst(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"1"}}))
+	c := newClient(t, srv, client.WithRetries(2, time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// When
+	start := time.Now()
+	_, err := c.List(ctx, "")
+
+	// Then
+	var e *client.Error
+	require.True(t, errors.As(err, &e), err)
+	require.True(t, errors.Is(err, client.ErrRateLimited))
+	require.Equal(t, time.Second, e.RetryAfter)
+	require.Less(t, int64(time.Since(start)), int64(100*time.Millisecond), "the client must not wait past the deadline")
+}
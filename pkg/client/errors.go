@@ -0,0 +1,123 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/emacampolo/link-tracker/internal/link"
+	"github.com/emacampolo/link-tracker/internal/platform/qr"
+	"github.com/emacampolo/link-tracker/internal/platform/web"
+	"github.com/emacampolo/link-tracker/internal/webhook"
+)
+
+// Errors that an *Error matches according to its code. They are the errors of the services of the
+// server, so errors.Is(err, ErrNotFound) and errors.Is(err, link.ErrNotFound) are the same.
+var (
+	ErrNotFound         = link.ErrNotFound
+	ErrAuthentication   = link.ErrAuthentication
+	ErrPasswordRequired = link.ErrPasswordRequired
+	ErrInactive         = link.ErrInactive
+	ErrClosed           = link.ErrClosed
+	ErrSecret           = link.ErrSecret
+	ErrInvalidLink      = link.ErrInvalidLink
+	ErrInvalidSchedule  = link.ErrInvalidSchedule
+	ErrWeakPassword     = link.ErrWeakPassword
+	ErrInvalidAccess    = link.ErrInvalidAccess
+	ErrAccessDisabled   = link.ErrAccessDisabled
+	ErrQRTooSmall       = qr.ErrSizeTooSmall
+	ErrWebhookNotFound  = webhook.ErrNotFound
+
+	// ErrInvalidRequest is matched by the errors of requests that the server could not understand.
+	ErrInvalidRequest = errors.New("invalid request")
+	// ErrRateLimited is matched by the errors of requests that were rejected by the rate limits.
+	ErrRateLimited = errors.New("rate limited")
+)
+
+// codeErrors maps the codes of the errors answered by the server to the errors they match.
+var codeErrors = map[string]error{
+	"link_not_found":    ErrNotFound,
+	"password_required": ErrPasswordRequired,
+	"invalid_password":  ErrAuthentication,
+	"link_inactive":     ErrInactive,
+	"link_closed":       ErrClosed,
+	"secret_link":       ErrSecret,
+	"invalid_link":      ErrInvalidLink,
+	"invalid_schedule":  ErrInvalidSchedule,
+	"weak_password":     ErrWeakPassword,
+	"invalid_access":    ErrInvalidAccess,
+	"access_disabled":   ErrAccessDisabled,
+	"qr_too_small":      ErrQRTooSmall,
+	"webhook_not_found": ErrWebhookNotFound,
+	"invalid_request":   ErrInvalidRequest,
+	"bad_request":       ErrInvalidRequest,
+	"too_many_requests": ErrRateLimited,
+}
+
+// Error is an error answered by the server.
+type Error struct {
+	Status int
+	// Code is a machine readable identifier of the error, stable across releases.
+	Code    string
+	Message string
+	// Fields are the errors of each field of an invalid request.
+	Fields []FieldError
+	// RetryAfter is how long to wait before sending a rate limited request again.
+	RetryAfter time.Duration
+}
+
+// FieldError describes why a field of a request is not valid.
+type FieldError struct {
+	Field   string
+	Code    string
+	Message string
+}
+
+// Error implements the error interface, in the same format as the errors of the server.
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// Unwrap returns the error of this package that matches the code of e, if any.
+func (e *Error) Unwrap() error {
+	return codeErrors[e.Code]
+}
+
+// newError decodes the error answered in resp. Bodies that are not errors of the API, such as the
+// ones of proxies, become the message of an error whose code is derived from the status.
+func newError(resp *http.Response) *Error {
+	e := &Error{
+		Status: resp.StatusCode,
+		Code:   strings.ReplaceAll(strings.ToLower(http.StatusText(resp.StatusCode)), " ", "_"),
+	}
+
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(resp.Header.Get("Retry-After")); err == nil {
+		e.RetryAfter = time.Duration(math.Max(0, float64(time.Until(t))))
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<16))
+	if err != nil {
+		e.Message = err.Error()
+		return e
+	}
+
+	var we web.Error
+	if err := json.Unmarshal(body, &we); err != nil || we.Code == "" {
+		e.Message = strings.TrimSpace(string(body))
+		return e
+	}
+
+	e.Code, e.Message = we.Code, we.Message
+	for _, f := range we.Fields {
+		e.Fields = append(e.Fields, FieldError{Field: f.Field, Code: f.Code, Message: f.Message})
+	}
+
+	return e
+}
@@ -0,0 +1,280 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// NewLink is a link to create. Only URL is required.
+type NewLink struct {
+	URL      string `json:"link"`
+	Password string `json:"password,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+	Title    string `json:"title,omitempty"`
+	// Interstitial shows a page with the destination before every visit.
+	Interstitial bool `json:"interstitial,omitempty"`
+	// FallbackURL receives the visitors while the destination is failing its health checks.
+	FallbackURL string    `json:"fallback_url,omitempty"`
+	Schedule    *Schedule `json:"schedule,omitempty"`
+	// MaxVisits makes the link secret: it can only be visited this many times. It requires a Password or Codes.
+	MaxVisits int             `json:"max_visits,omitempty"`
+	Codes     []NewAccessCode `json:"codes,omitempty"`
+}
+
+// NewAccessCode is a named password of a link, whose visits are counted apart.
+type NewAccessCode struct {
+	Name      string     `json:"name"`
+	Password  string     `json:"password"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// Schedule is when a link can be visited.
+type Schedule struct {
+	ActivateAt   *time.Time `json:"activate_at,omitempty"`
+	DeactivateAt *time.Time `json:"deactivate_at,omitempty"`
+	Windows      []Window   `json:"windows,omitempty"`
+	// TimeZone is the IANA time zone of the windows, UTC if empty.
+	TimeZone      string `json:"time_zone,omitempty"`
+	ClosedMessage string `json:"closed_message,omitempty"`
+	// ClosedURL receives the visitors while the link is closed.
+	ClosedURL string `json:"closed_url,omitempty"`
+}
+
+// Window is a recurring time of the week when a link is open.
+type Window struct {
+	// Days are names of days of the week, like "monday" or "mon". Every day if empty.
+	Days []string `json:"days,omitempty"`
+	// Start and End are times of the day in the format 15:04. End may be 24:00.
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// Link is a link as listed.
+type Link struct {
+	ID int `json:"id"`
+	// URL is empty for secret links.
+	URL      string    `json:"url"`
+	Owner    string    `json:"owner"`
+	Title    string    `json:"title"`
+	Count    int       `json:"count"`
+	Inactive bool      `json:"inactive"`
+	Metadata *Metadata `json:"metadata"`
+}
+
+// Metadata is what the destination of a link says about itself. It is nil until it is fetched.
+type Metadata struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Image       string    `json:"image"`
+	FetchedAt   time.Time `json:"fetched_at"`
+	Error       string    `json:"error"`
+}
+
+// Metrics are the details and the visits of a link.
+type Metrics struct {
+	ID                  int               `json:"id"`
+	URL                 string            `json:"url"`
+	Owner               string            `json:"owner"`
+	Title               string            `json:"title"`
+	Interstitial        bool              `json:"interstitial"`
+	Count               int               `json:"count"`
+	PreviewCount        int               `json:"preview_count"`
+	BotCount            int               `json:"bot_count"`
+	UniqueVisitors      uint64            `json:"unique_visitors"`
+	DailyUniqueVisitors map[string]uint64 `json:"daily_unique_visitors"`
+	Inactive            bool              `json:"inactive"`
+	Metadata            *Metadata         `json:"metadata"`
+	FallbackURL         string            `json:"fallback_url"`
+	Health              Health            `json:"health"`
+	Schedule            *ScheduleStatus   `json:"schedule"`
+	Public              bool              `json:"public"`
+	Codes               []AccessCode      `json:"codes"`
+	MaxVisits           int               `json:"max_visits"`
+	// RemainingVisits is only set for secret links.
+	RemainingVisits *int       `json:"remaining_visits"`
+	ConsumedAt      *time.Time `json:"consumed_at"`
+}
+
+// Health is the result of the checks of the destination of a link.
+type Health struct {
+	Failing             bool       `json:"failing"`
+	FailingSince        *time.Time `json:"failing_since"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	History             []Check    `json:"history"`
+}
+
+// Check is a check of the destination of a link.
+type Check struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code"`
+	DurationMS int64     `json:"duration_ms"`
+	Error      string    `json:"error"`
+}
+
+// ScheduleStatus is the Schedule of a link and whether it is open now.
+type ScheduleStatus struct {
+	Schedule
+	Open bool `json:"open"`
+	// Transitions are the next times the link opens or closes.
+	Transitions []Transition `json:"transitions"`
+}
+
+// Transition is a time when a link opens or closes.
+type Transition struct {
+	Time   time.Time `json:"time"`
+	Closed bool      `json:"closed"`
+}
+
+// AccessCode is an access code of a link and its visits.
+type AccessCode struct {
+	Name       string     `json:"name"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	Count      int        `json:"count"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// NewAccess is a signed URL to grant.
+type NewAccess struct {
+	// Password is the password of the link, if it has one.
+	Password string
+	// TTL is how long the URL is valid.
+	TTL time.Duration
+	// IPRange restricts the URL to the clients in the CIDR, if not empty.
+	IPRange string
+}
+
+// Access is a signed URL to visit a link without its password.
+type Access struct {
+	URL       string    `json:"url"`
+	KeyID     string    `json:"key_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// QROptions are the options of the image of a QR code. The server picks the ones left empty.
+type QROptions struct {
+	// Format is png or svg.
+	Format string
+	Size   int
+	Margin *int
+	// Level is the error correction level: L, M, Q or H.
+	Level string
+}
+
+// ErrNotRedirected is returned by Resolve when the server answers a page instead of redirecting,
+// such as the preview of the link it shows to bots.
+var ErrNotRedirected = errors.New("client: the link did not redirect")
+
+// Create creates a link and returns its ID.
+func (c *Client) Create(ctx context.Context, nl NewLink) (int, error) {
+	var resp struct {
+		ID int `json:"id"`
+	}
+
+	err := c.call(ctx, request{method: http.MethodPost, path: apiPrefix + "/link", in: nl}, &resp)
+	return resp.ID, err
+}
+
+// List returns the links of owner, or every link if it is empty.
+func (c *Client) List(ctx context.Context, owner string) ([]Link, error) {
+	query := url.Values{}
+	if owner != "" {
+		query.Set("owner", owner)
+	}
+
+	var links []Link
+	err := c.call(ctx, request{method: http.MethodGet, path: apiPrefix + "/link", query: query}, &links)
+	return links, err
+}
+
+// Metrics returns the metrics of the link.
+func (c *Client) Metrics(ctx context.Context, id int) (Metrics, error) {
+	var m Metrics
+	err := c.call(ctx, request{method: http.MethodGet, path: linkPath(id, "/metrics")}, &m)
+	return m, err
+}
+
+// Inactivate inactivates the link, which can't be visited anymore.
+func (c *Client) Inactivate(ctx context.Context, id int) error {
+	return c.call(ctx, request{method: http.MethodPost, path: linkPath(id, "/inactivate")}, nil)
+}
+
+// GrantAccess returns a signed URL to visit the link without its password.
+func (c *Client) GrantAccess(ctx context.Context, id int, na NewAccess) (Access, error) {
+	in := struct {
+		Password string `json:"password,omitempty"`
+		TTL      string `json:"ttl"`
+		IPRange  string `json:"ip_range,omitempty"`
+	}{
+		Password: na.Password,
+		TTL:      na.TTL.String(),
+		IPRange:  na.IPRange,
+	}
+
+	var a Access
+	err := c.call(ctx, request{method: http.MethodPost, path: linkPath(id, "/access"), in: in}, &a)
+	return a, err
+}
+
+// Resolve visits the link with password, which may be empty, and returns where it redirects to
+// without following it. The visit is counted like any other, skipping the interstitial page, so it is
+// only retried when it is rate limited: a retry could count it twice or consume a secret link.
+func (c *Client) Resolve(ctx context.Context, id int, password string) (string, error) {
+	query := url.Values{"confirm": {"1"}}
+	if password != "" {
+		query.Set("password", password)
+	}
+
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: "/link/" + strconv.Itoa(id), query: query, noRedirect: true, unsafe: true})
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	drain(resp.Body)
+
+	location := resp.Header.Get("Location")
+	if resp.StatusCode < http.StatusMultipleChoices || location == "" {
+		return "", ErrNotRedirected
+	}
+
+	return location, nil
+}
+
+// QR returns the image of the QR code of the link and its media type.
+func (c *Client) QR(ctx context.Context, id int, opts QROptions) ([]byte, string, error) {
+	query := url.Values{}
+	if opts.Format != "" {
+		query.Set("format", opts.Format)
+	}
+	if opts.Size != 0 {
+		query.Set("size", strconv.Itoa(opts.Size))
+	}
+	if opts.Margin != nil {
+		query.Set("margin", strconv.Itoa(*opts.Margin))
+	}
+	if opts.Level != "" {
+		query.Set("level", opts.Level)
+	}
+
+	resp, err := c.send(ctx, request{method: http.MethodGet, path: linkPath(id, "/qr"), query: query})
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	img, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("client: reading the QR code: %w", err)
+	}
+
+	return img, resp.Header.Get("Content-Type"), nil
+}
+
+func linkPath(id int, suffix string) string {
+	return apiPrefix + "/link/" + strconv.Itoa(id) + suffix
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Events that webhooks can subscribe to.
+const (
+	EventCreated     = "link.created"
+	EventVisited     = "link.visited"
+	EventInactivated = "link.inactivated"
+	EventReactivated = "link.reactivated"
+)
+
+// NewSubscription is a webhook to subscribe.
+type NewSubscription struct {
+	URL string `json:"url"`
+	// Secret signs the deliveries. The server generates one if it is empty.
+	Secret string `json:"secret,omitempty"`
+	// Events are the events to deliver, every one if empty.
+	Events []string `json:"events,omitempty"`
+	// URLFilter is a glob that the URL of the links of the events must match.
+	URLFilter string `json:"url_filter,omitempty"`
+}
+
+// Subscription is a subscribed webhook.
+type Subscription struct {
+	ID        int      `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	URLFilter string   `json:"url_filter"`
+	// Secret is only returned when subscribing.
+	Secret string `json:"secret"`
+}
+
+// Delivery is an attempt to deliver an event to a webhook.
+type Delivery struct {
+	ID             string    `json:"id"`
+	SubscriptionID int       `json:"subscription_id"`
+	Event          string    `json:"event"`
+	Attempt        int       `json:"attempt"`
+	StatusCode     int       `json:"status_code"`
+	Error          string    `json:"error"`
+	Succeeded      bool      `json:"succeeded"`
+	Time           time.Time `json:"time"`
+	DurationMS     int64     `json:"duration_ms"`
+}
+
+// DeadLetter is an event that could not be delivered after every attempt.
+type DeadLetter struct {
+	Delivery Delivery `json:"delivery"`
+	URL      string   `json:"url"`
+	Payload  string   `json:"payload"`
+	Reason   string   `json:"reason"`
+}
+
+// Subscribe subscribes a webhook.
+func (c *Client) Subscribe(ctx context.Context, ns NewSubscription) (Subscription, error) {
+	var s Subscription
+	err := c.call(ctx, request{method: http.MethodPost, path: apiPrefix + "/webhooks", in: ns}, &s)
+	return s, err
+}
+
+// Subscriptions returns the subscribed webhooks.
+func (c *Client) Subscriptions(ctx context.Context) ([]Subscription, error) {
+	var s []Subscription
+	err := c.call(ctx, request{method: http.MethodGet, path: apiPrefix + "/webhooks"}, &s)
+	return s, err
+}
+
+// Unsubscribe unsubscribes the webhook.
+func (c *Client) Unsubscribe(ctx context.Context, id int) error {
+	return c.call(ctx, request{method: http.MethodDelete, path: webhookPath(id, "")}, nil)
+}
+
+// Deliveries returns the latest deliveries to the webhook.
+func (c *Client) Deliveries(ctx context.Context, id int) ([]Delivery, error) {
+	var d []Delivery
+	err := c.call(ctx, request{method: http.MethodGet, path: webhookPath(id, "/deliveries")}, &d)
+	return d, err
+}
+
+// DeadLetters returns the events that could not be delivered.
+func (c *Client) DeadLetters(ctx context.Context) ([]DeadLetter, error) {
+	var dl []DeadLetter
+	err := c.call(ctx, request{method: http.MethodGet, path: apiPrefix + "/webhooks/dead-letters"}, &dl)
+	return dl, err
+}
+
+func webhookPath(id int, suffix string) string {
+	return apiPrefix + "/webhooks/" + strconv.Itoa(id) + suffix
+}